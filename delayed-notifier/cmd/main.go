@@ -11,17 +11,23 @@ import (
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/handler"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitConsumer"
 	rabbitpublisher "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitProducer"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/repository"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/service"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/postgres"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/server"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/postgres"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/server"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
 	"github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/redis"
 	"github.com/wb-go/wbf/retry"
 	"github.com/wb-go/wbf/zlog"
 )
 
+const healthCheckTimeout = 2 * time.Second
+
 func main() {
 
 	// make context
@@ -44,6 +50,36 @@ func main() {
 		Str("env", cfg.Env).
 		Msg("Start app...")
 
+	// каналы sender plugins воркера: без них API отклонил бы такие уведомления
+	for _, channel := range cfg.PluginChannels {
+		if _, known := domain.LookupChannel(channel); known {
+			continue
+		}
+		if err := domain.RegisterChannel(domain.ChannelSpec{Name: channel, Content: domain.AllContent}); err != nil {
+			zlog.Logger.Fatal().Err(err).Str("channel", channel).Msg("couldn't register plugin channel")
+		}
+	}
+
+	// init tracing
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "delayed-notifier",
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("couldn't set up tracing")
+	}
+	defer func() {
+		// контекст приложения к этому моменту уже отменен, даем время дослать спаны
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			zlog.Logger.Error().Err(err).Msg("couldn't flush traces")
+		}
+	}()
+
 	// stratefies
 	rabbitmqRetryStrategy := config.MakeStrategy(cfg.RabbitMQRetry)
 	postgresRetryStrategy := config.MakeStrategy(cfg.PostgresRetry)
@@ -99,27 +135,130 @@ func main() {
 	}
 	defer publisher.Close()
 
-	// init rabbitRepository and senderService
+	// init redis
+	addr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
+	redisClient := redis.New(addr, cfg.Redis.Password, cfg.Redis.DB)
+	redisRepository := repository.NewRedisRepository(redisClient, redisRepoRetryStrategy, time.Hour)
+
+	// init event bus (redis stream + pub/sub, shared by all replicas)
+	eventRepository := repository.NewRedisEventRepository(
+		redisClient,
+		redisRepoRetryStrategy,
+		cfg.Events.Stream,
+		cfg.Events.Channel,
+		cfg.Events.StreamMaxLen,
+	)
+	eventService := service.NewEventService(eventRepository, cfg.Events.SubscriberBuffer, cfg.Events.BacklogLimit)
+
+	// init callbacks and lifecycle hooks (event bus first: callbacks need the event id)
+	callbackRepository := repository.NewCallbackRepository(postgresDB, storeRepoRetryStrategy)
+	callbackService := service.NewCallbackService(callbackRepository, cfg.Callbacks)
+	auditRepository := repository.NewAuditRepository(postgresDB, storeRepoRetryStrategy)
+	auditService := service.NewAuditService(auditRepository)
+	lifecycle := service.NewLifecycle().
+		On("events", service.EventHook(eventRepository)).
+		On("callbacks", callbackService.OnTransition)
+
+	// init rabbitRepository and the channels workers accept
 	rabbitRepository := repository.NewRabbitRepository(publisher, rabbitRepoRetryStrategy)
-	senderService := service.NewSendService(StoreRepository, rabbitRepository, 5*time.Second, time.Hour)
+	lifecycle.On("cancellations", service.CancelHook(rabbitRepository))
+	channelRegistry := repository.NewRabbitChannelRegistry(publisher, cfg.RabbitMQ)
+
+	// init recipient directory (users addressed by user_id) and unreachable recipients
+	recipientRepository := repository.NewRecipientRepository(postgresDB, storeRepoRetryStrategy)
+	recipientService := service.NewRecipientService(recipientRepository, recipientRepository, channelRegistry, StoreRepository, rabbitRepository, lifecycle)
+
+	// init senderService
+	senderService := service.NewSendService(StoreRepository, rabbitRepository, recipientService, lifecycle, 5*time.Second, time.Hour, cfg.Scheduler)
+
+	// init delivery reports from worker
+	statusConsumer, err := rabbitconsumer.NewStatusConsumer(ctx, cfg.RabbitMQ, rabbitmqRetryStrategy)
+	if err != nil {
+		zlog.Logger.Fatal().
+			Err(err).
+			Msg("failed to create status consumer")
+	}
+	defer statusConsumer.Close()
+	statusReceiver := repository.NewRabbitStatusReceiver(statusConsumer, rabbitmqRetryStrategy)
+	statusService := service.NewStatusService(statusReceiver, StoreRepository, redisRepository, lifecycle, recipientRepository)
+
+	// init retention (archiving of old finished notifications)
+	retentionRepository := repository.NewRetentionRepository(postgresDB, storeRepoRetryStrategy)
+	retentionService := service.NewRetentionService(retentionRepository, cfg.Retention)
+
+	// init broker-side scheduling: created notifications go straight to the delayed exchange
+	var brokerScheduler *service.BrokerScheduler
+	var dueConsumer *rabbitconsumer.Consumer
+	if cfg.Scheduler.Strategy == config.SchedulerBroker {
+		dueConsumer, err = rabbitconsumer.NewDueConsumer(ctx, cfg.RabbitMQ, rabbitmqRetryStrategy)
+		if err != nil {
+			zlog.Logger.Fatal().
+				Err(err).
+				Msg("failed to create due consumer")
+		}
+		defer dueConsumer.Close()
+		dueReceiver := repository.NewRabbitDueReceiver(dueConsumer, rabbitmqRetryStrategy)
+		brokerScheduler = service.NewBrokerScheduler(StoreRepository, rabbitRepository, dueReceiver, senderService, cfg.Scheduler)
+		lifecycle.On("scheduler", brokerScheduler.OnTransition)
+	}
+	zlog.Logger.Info().Str("strategy", cfg.Scheduler.Strategy).Msg("scheduling strategy")
 
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(5)
 
 	go func() {
 		defer wg.Done()
 		senderService.Run(ctx)
 	}()
 
-	// init redis
-	addr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
-	redisClient := redis.New(addr, cfg.Redis.Password, cfg.Redis.DB)
-	redisRepository := repository.NewRedisRepository(redisClient, redisRepoRetryStrategy, time.Hour)
+	go func() {
+		defer wg.Done()
+		eventService.Run(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		callbackService.Run(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		statusService.Run(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		retentionService.Run(ctx)
+	}()
+
+	if brokerScheduler != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			brokerScheduler.Run(ctx)
+		}()
+	}
 
 	// inint crud service
-	crudService := service.NewCrudService(StoreRepository, redisRepository, nil)
-	handl := handler.NewNotifyHandler(crudService)
-	router := handler.NewRouter(handl)
+	crudService := service.NewCrudService(StoreRepository, redisRepository, lifecycle, channelRegistry, recipientRepository, recipientRepository)
+	handl := handler.NewNotifyHandler(crudService, callbackService, auditService)
+	recipientHandl := handler.NewRecipientHandler(recipientService)
+	streamHandl := handler.NewStreamHandler(eventService)
+	// health checks: liveness — only our own loops, readiness — dependencies too
+	liveness := health.NewRegistry(healthCheckTimeout).
+		Add("send_loop", senderService.CheckLoop)
+	readiness := health.NewRegistry(healthCheckTimeout).
+		Add("postgres", postgresDB.Master.PingContext).
+		Add("redis", func(ctx context.Context) error { return redisClient.Ping(ctx).Err() }).
+		Add("rabbitmq_publisher", publisher.Check).
+		Add("rabbitmq_status_consumer", statusConsumer.Check).
+		Add("send_loop", senderService.CheckLoop)
+	if dueConsumer != nil {
+		readiness.Add("rabbitmq_due_consumer", dueConsumer.Check)
+	}
+	healthHandl := handler.NewHealthHandler(liveness, readiness)
+
+	router := handler.NewRouter(handl, recipientHandl, streamHandl, healthHandl)
 
 	// running server
 	zlog.Logger.Info().Msg("server start")
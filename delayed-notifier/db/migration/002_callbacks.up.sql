@@ -0,0 +1,32 @@
+ALTER TABLE notifications ADD COLUMN callback_url TEXT; -- куда отправлять события о смене статуса
+
+-- исходящие callback'и (outbox): одна запись на событие
+CREATE TABLE callbacks (
+    id UUID PRIMARY KEY,
+    notification_id UUID NOT NULL,
+    event_id TEXT NOT NULL,                         -- id события в потоке событий
+    event_type TEXT NOT NULL,                       -- dispatched / delivered / failed / cancelled
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,                         -- тело запроса
+    status TEXT NOT NULL DEFAULT 'pending',         -- pending / delivered / failed
+    attempts INT NOT NULL DEFAULT 0,                -- количество попыток
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX callbacks_due_idx ON callbacks (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX callbacks_notification_idx ON callbacks (notification_id);
+
+-- история попыток доставки callback'ов
+CREATE TABLE callback_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    callback_id UUID NOT NULL REFERENCES callbacks (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT,                                -- HTTP код ответа (NULL, если запрос не дошел)
+    error TEXT,                                     -- текст ошибки
+    duration_ms INT NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX callback_attempts_callback_idx ON callback_attempts (callback_id);
@@ -0,0 +1,25 @@
+ALTER TABLE notifications ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE; -- мягкое удаление, NULL — не удалено
+
+-- журнал переходов уведомлений, только добавление
+CREATE TABLE notification_events (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id UUID NOT NULL,                   -- без FK: журнал переживает удаление уведомления
+    event_type TEXT NOT NULL,                        -- created / rescheduled / dispatched / delivered / failed / cancelled / deleted
+    status TEXT NOT NULL,                            -- статус уведомления после события
+    actor TEXT NOT NULL,                             -- кто вызвал переход: X-Actor, ip:<адрес> или system:<компонент>
+    details TEXT,                                    -- текст ошибки и т.п.
+    stream_event_id TEXT,                            -- id события в потоке событий (если опубликовано)
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notification_events_notification_idx ON notification_events (notification_id, id);
+
+CREATE FUNCTION notification_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'notification_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notification_events_no_update
+    BEFORE UPDATE OR DELETE ON notification_events
+    FOR EACH ROW EXECUTE FUNCTION notification_events_append_only();
@@ -0,0 +1,23 @@
+-- архив уведомлений, вынесенных из основной таблицы по сроку хранения;
+-- партиции по месяцам создает RetentionService, default ловит все, для чего партиции еще нет
+CREATE TABLE notifications_archive (
+    id UUID NOT NULL,
+    recipient TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    message TEXT NOT NULL,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status TEXT NOT NULL,
+    tries INT NOT NULL,
+    last_error TEXT,
+    callback_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, archived_at)
+) PARTITION BY RANGE (archived_at);
+
+CREATE TABLE notifications_archive_default PARTITION OF notifications_archive DEFAULT;
+
+-- выборка кандидатов на архивацию
+CREATE INDEX notifications_status_updated_idx ON notifications (status, updated_at);
@@ -0,0 +1,3 @@
+-- W3C traceparent запроса на создание: публикация происходит позже и в другом контексте,
+-- по нему SendService продолжает ту же трассу
+ALTER TABLE notifications ADD COLUMN trace_parent TEXT;
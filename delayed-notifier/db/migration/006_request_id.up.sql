@@ -0,0 +1,2 @@
+-- X-Request-ID запроса на создание: передается воркеру, чтобы его логи можно было связать с вызывающим
+ALTER TABLE notifications ADD COLUMN request_id TEXT;
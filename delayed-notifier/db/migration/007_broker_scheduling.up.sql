@@ -0,0 +1,2 @@
+-- когда уведомление передано в отложенный exchange RabbitMQ (стратегия broker); NULL — его забирает поллер
+ALTER TABLE notifications ADD COLUMN enqueued_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+-- получатели, которые канал больше не доставит (удаленный push-токен и т.п.), по отчетам воркера
+CREATE TABLE unreachable_recipients (
+    channel TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    reason TEXT NOT NULL,                            -- ответ провайдера
+    notification_id UUID NOT NULL,                   -- уведомление, на котором это выяснилось
+    marked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (channel, recipient)
+);
@@ -0,0 +1,14 @@
+-- необязательное содержимое сверх message; какие поля допустимы, зависит от канала (shared/domain)
+ALTER TABLE notifications
+    ADD COLUMN subject TEXT,
+    ADD COLUMN body_html TEXT,
+    ADD COLUMN attachments JSONB,    -- [{url, filename, content_type, size}], сами файлы хранятся вне базы
+    ADD COLUMN metadata JSONB;       -- {"метка": "значение"}
+
+ALTER TABLE notifications_archive
+    ADD COLUMN subject TEXT,
+    ADD COLUMN body_html TEXT,
+    ADD COLUMN attachments JSONB,
+    ADD COLUMN metadata JSONB;
+
+CREATE INDEX notifications_metadata_idx ON notifications USING GIN (metadata);
@@ -0,0 +1,21 @@
+-- справочник получателей: контакты пользователя по каналам и его подписки
+CREATE TABLE recipients (
+    user_id TEXT PRIMARY KEY,
+    contacts JSONB NOT NULL,                         -- [{channel, address}] в порядке предпочтения
+    preferences JSONB NOT NULL DEFAULT '[]',         -- [{channel, category, opted_in}], пустое поле — любое значение
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- уведомление пользователю справочника: channel и recipient пустые, пока их не выберет отправка
+ALTER TABLE notifications
+    ADD COLUMN user_id TEXT,
+    ADD COLUMN category TEXT,
+    ADD COLUMN channel_preferences JSONB;   -- ["push", "email"], NULL — порядок контактов пользователя
+
+ALTER TABLE notifications_archive
+    ADD COLUMN user_id TEXT,
+    ADD COLUMN category TEXT,
+    ADD COLUMN channel_preferences JSONB;
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id) WHERE user_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- цепочка целей: после окончательной ошибки доставки уведомление уходит на следующую
+ALTER TABLE notifications
+    ADD COLUMN targets JSONB,                        -- [{channel, recipient}], NULL — единственная цель channel/recipient
+    ADD COLUMN target_index INT NOT NULL DEFAULT 0;  -- номер текущей цели, channel/recipient — ее копия
+
+ALTER TABLE notifications_archive
+    ADD COLUMN targets JSONB,
+    ADD COLUMN target_index INT NOT NULL DEFAULT 0;
+
+-- попытки доставки по отчетам воркера: на какую цель и с каким результатом
+CREATE TABLE delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id UUID NOT NULL,
+    target_index INT NOT NULL,
+    channel TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    status TEXT NOT NULL,                            -- delivered / failed
+    error TEXT,                                      -- текст ошибки для failed
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL   -- время попытки по отчету воркера
+);
+
+CREATE INDEX delivery_attempts_notification_idx ON delivery_attempts (notification_id);
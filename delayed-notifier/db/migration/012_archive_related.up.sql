@@ -0,0 +1,30 @@
+-- архивация уносит вместе с уведомлением его журнал, попытки доставки и callback'и,
+-- иначе они остаются в горячих таблицах без уведомления
+ALTER TABLE notifications_archive
+    ADD COLUMN events JSONB,                         -- строки notification_events, по возрастанию id
+    ADD COLUMN delivery_attempts JSONB,              -- строки delivery_attempts
+    ADD COLUMN callbacks JSONB;                      -- строки callbacks, у каждой attempts — ее callback_attempts
+
+-- незавершенный callback держит уведомление в горячей таблице до окончания повторов
+CREATE INDEX callbacks_pending_notification_idx ON callbacks (notification_id) WHERE status = 'pending';
+
+-- журнал по-прежнему нельзя менять; удалять его строки можно только тем же запросом,
+-- что удаляет само уведомление (перенос в архив). AFTER-триггер видит изменения всего запроса
+DROP TRIGGER notification_events_no_update ON notification_events;
+
+CREATE TRIGGER notification_events_no_update
+    BEFORE UPDATE ON notification_events
+    FOR EACH ROW EXECUTE FUNCTION notification_events_append_only();
+
+CREATE FUNCTION notification_events_archive_only() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM notifications WHERE id = OLD.notification_id) THEN
+        RAISE EXCEPTION 'notification_events is append-only';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notification_events_no_delete
+    AFTER DELETE ON notification_events
+    FOR EACH ROW EXECUTE FUNCTION notification_events_archive_only();
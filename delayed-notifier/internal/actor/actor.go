@@ -0,0 +1,25 @@
+// Package actor carries who triggered an operation through the context,
+// so that audit records can name it without threading it through every call.
+package actor
+
+import "context"
+
+// Actors for transitions not caused by an API call.
+const (
+	Scheduler = "system:scheduler" // SendService публикует уведомление
+	Worker    = "system:worker"    // отчет воркера о доставке
+	Unknown   = "unknown"
+)
+
+type ctxKey struct{}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+func FromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(ctxKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return Unknown
+}
@@ -0,0 +1,92 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind sentinels. Every *Error wraps exactly one of them, so callers can use
+// errors.Is(err, apperrors.ErrNotFound) without knowing the concrete code.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// Stable machine-readable error codes returned to API clients.
+const (
+	CodeInvalidBody          = "invalid_body"
+	CodeInvalidID            = "invalid_id"
+	CodeValidationFailed     = "validation_failed"
+	CodeChannelUnavailable   = "channel_unavailable"
+	CodeRecipientUnreachable = "recipient_unreachable"
+	CodeUnknownUser          = "unknown_user"
+	CodeRecipientNotFound    = "recipient_not_found"
+	CodeNotFound             = "notification_not_found"
+	CodeAlreadyExists        = "notification_already_exists"
+	CodeStorageUnavailable   = "storage_unavailable"
+	CodeCacheUnavailable     = "cache_unavailable"
+	CodeInternal             = "internal_error"
+)
+
+// Error is a domain error with a kind, a stable code and a human-readable message.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func NotFound(code, message string, err error) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message, Err: err}
+}
+
+func Conflict(code, message string, err error) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message, Err: err}
+}
+
+func Validation(code, message string, err error) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message, Err: err}
+}
+
+func Unavailable(code, message string, err error) *Error {
+	return &Error{Kind: ErrUnavailable, Code: code, Message: message, Err: err}
+}
+
+// CodeOf returns the code of the first *Error in the chain or CodeInternal.
+func CodeOf(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// PublicMessage returns the message of the first *Error in the chain, which is safe to
+// show to API clients; wrapped driver and network errors stay out of it. The cause of a
+// validation error describes the request itself and is kept.
+func PublicMessage(err error) string {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		return "internal server error"
+	}
+	if appErr.Kind == ErrValidation && appErr.Err != nil {
+		return appErr.Error()
+	}
+	return appErr.Message
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/wb-go/wbf/config"
@@ -9,16 +10,23 @@ import (
 )
 
 type Config struct {
-	Env             string         `yaml:"env" env:"ENV"`
-	Database        PostgresConfig `env-prefix:"POSTGRES_"`
-	Redis           RedisConfig    `env-prefix:"REDIS_"`
-	RabbitMQ        RabbitMQConfig `env-prefix:"RABBITMQ_"`
-	Server          ServerConfig   `env-prefix:"SERVER_"`
-	RabbitMQRetry   RetryConfig    `env-prefix:"RETRY_RABBITMQ_"`
-	PostgresRetry   RetryConfig    `env-prefix:"RETRY_POSTGRES_"`
-	StoreRepoRetry  RetryConfig    `env-prefix:"RETRY_STORE_REPO_"`
-	RabbitRepoRetry RetryConfig    `env-prefix:"RETRY_RABBIT_REPO_"`
-	RedisRepoRetry  RetryConfig    `env-prefix:"RETRY_REDIS_REPO_"`
+	Env             string          `yaml:"env" env:"ENV"`
+	Database        PostgresConfig  `env-prefix:"POSTGRES_"`
+	Redis           RedisConfig     `env-prefix:"REDIS_"`
+	Events          EventsConfig    `env-prefix:"EVENTS_"`
+	Callbacks       CallbacksConfig `env-prefix:"CALLBACKS_"`
+	Retention       RetentionConfig `env-prefix:"RETENTION_"`
+	Tracing         TracingConfig   `env-prefix:"TRACING_"`
+	Scheduler       SchedulerConfig `env-prefix:"SCHEDULER_"`
+	RabbitMQ        RabbitMQConfig  `env-prefix:"RABBITMQ_"`
+	Server          ServerConfig    `env-prefix:"SERVER_"`
+	RabbitMQRetry   RetryConfig     `env-prefix:"RETRY_RABBITMQ_"`
+	PostgresRetry   RetryConfig     `env-prefix:"RETRY_POSTGRES_"`
+	StoreRepoRetry  RetryConfig     `env-prefix:"RETRY_STORE_REPO_"`
+	RabbitRepoRetry RetryConfig     `env-prefix:"RETRY_RABBIT_REPO_"`
+	RedisRepoRetry  RetryConfig     `env-prefix:"RETRY_REDIS_REPO_"`
+
+	PluginChannels []string `env:"PLUGIN_CHANNELS"` // каналы sender plugins воркера, которых нет в shared/domain
 }
 
 func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
@@ -49,6 +57,72 @@ func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
 	myConfig.RabbitMQ.VHost = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_VHOST")
 	myConfig.RabbitMQ.Exchange = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_EXCHANGE")
 	myConfig.RabbitMQ.Queue = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_QUEUE")
+	myConfig.RabbitMQ.StatusQueue = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_STATUS_QUEUE")
+	myConfig.RabbitMQ.StatusRoutingKey = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_STATUS_ROUTING_KEY")
+	if myConfig.RabbitMQ.StatusQueue == "" {
+		myConfig.RabbitMQ.StatusQueue = "notification-status"
+	}
+	if myConfig.RabbitMQ.StatusRoutingKey == "" {
+		myConfig.RabbitMQ.StatusRoutingKey = "status"
+	}
+	myConfig.RabbitMQ.DelayedExchange = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_DELAYED_EXCHANGE")
+	myConfig.RabbitMQ.DueQueue = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_DUE_QUEUE")
+	if myConfig.RabbitMQ.DelayedExchange == "" {
+		myConfig.RabbitMQ.DelayedExchange = myConfig.RabbitMQ.Exchange + ".delayed"
+	}
+	if myConfig.RabbitMQ.DueQueue == "" {
+		myConfig.RabbitMQ.DueQueue = "notification-due"
+	}
+	myConfig.RabbitMQ.CancelExchange = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_CANCEL_EXCHANGE")
+	if myConfig.RabbitMQ.CancelExchange == "" {
+		myConfig.RabbitMQ.CancelExchange = myConfig.RabbitMQ.Exchange + ".cancel"
+	}
+
+	channelsRefresh := cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_CHANNELS_REFRESH")
+	if channelsRefresh == "" {
+		channelsRefresh = "30s"
+	}
+	if refresh, err := time.ParseDuration(channelsRefresh); err != nil || refresh <= 0 {
+		return nil, fmt.Errorf("invalid DELAYED_NOTIFIER_RABBITMQ_CHANNELS_REFRESH '%s': expected a positive duration like '30s'", channelsRefresh)
+	} else {
+		myConfig.RabbitMQ.ChannelsRefresh = refresh
+	}
+
+	// Scheduler
+	myConfig.Scheduler.Strategy = cfg.GetString("DELAYED_NOTIFIER_SCHEDULER_STRATEGY")
+	switch myConfig.Scheduler.Strategy {
+	case "":
+		myConfig.Scheduler.Strategy = SchedulerPoll
+	case SchedulerPoll, SchedulerBroker:
+	default:
+		return nil, fmt.Errorf("invalid DELAYED_NOTIFIER_SCHEDULER_STRATEGY '%s': expected poll or broker", myConfig.Scheduler.Strategy)
+	}
+	schedulerDurations := []struct {
+		key          string
+		target       *time.Duration
+		defaultValue time.Duration
+	}{
+		{"DELAYED_NOTIFIER_SCHEDULER_MAX_DELAY", &myConfig.Scheduler.MaxDelay, 30 * 24 * time.Hour},
+		{"DELAYED_NOTIFIER_SCHEDULER_ENQUEUED_GRACE", &myConfig.Scheduler.EnqueuedGrace, time.Minute},
+	}
+	for _, d := range schedulerDurations {
+		raw := cfg.GetString(d.key)
+		if raw == "" {
+			*d.target = d.defaultValue
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid %s '%s': expected a positive duration like '1m'", d.key, raw)
+		}
+		*d.target = value
+	}
+	if myConfig.Scheduler.MaxDelay > MaxBrokerDelay {
+		return nil, fmt.Errorf("invalid DELAYED_NOTIFIER_SCHEDULER_MAX_DELAY '%s': the delayed exchange accepts at most %s", myConfig.Scheduler.MaxDelay, MaxBrokerDelay)
+	}
+
+	// Каналы sender plugins: принимаются без проверки получателя, ее делает плагин
+	myConfig.PluginChannels = splitList(cfg.GetString("DELAYED_NOTIFIER_PLUGIN_CHANNELS"))
 
 	// Postgres
 	myConfig.Database.MasterDSN = cfg.GetString("DELAYED_NOTIFIER_POSTGRES_MASTER_DSN")
@@ -64,6 +138,113 @@ func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
 	myConfig.Redis.DB = cfg.GetInt("DELAYED_NOTIFIER_REDIS_DB")
 	myConfig.Redis.Expiration = cfg.GetInt("DELAYED_NOTIFIER_REDIS_EXPIRATION")
 
+	// Events
+	myConfig.Events.Stream = cfg.GetString("DELAYED_NOTIFIER_EVENTS_STREAM")
+	myConfig.Events.Channel = cfg.GetString("DELAYED_NOTIFIER_EVENTS_CHANNEL")
+	myConfig.Events.StreamMaxLen = cfg.GetInt64("DELAYED_NOTIFIER_EVENTS_STREAM_MAX_LEN")
+	myConfig.Events.SubscriberBuffer = cfg.GetInt("DELAYED_NOTIFIER_EVENTS_SUBSCRIBER_BUFFER")
+	myConfig.Events.BacklogLimit = cfg.GetInt64("DELAYED_NOTIFIER_EVENTS_BACKLOG_LIMIT")
+	if myConfig.Events.Stream == "" {
+		myConfig.Events.Stream = "notification-events"
+	}
+	if myConfig.Events.Channel == "" {
+		myConfig.Events.Channel = "notification-events"
+	}
+	if myConfig.Events.StreamMaxLen <= 0 {
+		myConfig.Events.StreamMaxLen = 10000
+	}
+	if myConfig.Events.SubscriberBuffer <= 0 {
+		myConfig.Events.SubscriberBuffer = 256
+	}
+	if myConfig.Events.BacklogLimit <= 0 {
+		myConfig.Events.BacklogLimit = 1000
+	}
+
+	// Callbacks
+	myConfig.Callbacks.Secret = cfg.GetString("DELAYED_NOTIFIER_CALLBACKS_SECRET")
+	myConfig.Callbacks.MaxAttempts = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_MAX_ATTEMPTS")
+	myConfig.Callbacks.TimeoutMs = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_TIMEOUT_MS")
+	myConfig.Callbacks.BaseDelayMs = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_BASE_DELAY_MS")
+	myConfig.Callbacks.MaxDelayMs = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_MAX_DELAY_MS")
+	myConfig.Callbacks.PollPeriodMs = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_POLL_PERIOD_MS")
+	myConfig.Callbacks.BatchSize = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_BATCH_SIZE")
+	myConfig.Callbacks.Workers = cfg.GetInt("DELAYED_NOTIFIER_CALLBACKS_WORKERS")
+	myConfig.Callbacks.AllowedHosts = splitList(cfg.GetString("DELAYED_NOTIFIER_CALLBACKS_ALLOWED_HOSTS"))
+	myConfig.Callbacks.AllowPrivate = cfg.GetBool("DELAYED_NOTIFIER_CALLBACKS_ALLOW_PRIVATE")
+	if myConfig.Callbacks.MaxAttempts <= 0 {
+		myConfig.Callbacks.MaxAttempts = 8
+	}
+	if myConfig.Callbacks.TimeoutMs <= 0 {
+		myConfig.Callbacks.TimeoutMs = 5000
+	}
+	if myConfig.Callbacks.BaseDelayMs <= 0 {
+		myConfig.Callbacks.BaseDelayMs = 1000
+	}
+	if myConfig.Callbacks.MaxDelayMs <= 0 {
+		myConfig.Callbacks.MaxDelayMs = 3600000
+	}
+	if myConfig.Callbacks.PollPeriodMs <= 0 {
+		myConfig.Callbacks.PollPeriodMs = 1000
+	}
+	if myConfig.Callbacks.BatchSize <= 0 {
+		myConfig.Callbacks.BatchSize = 100
+	}
+	if myConfig.Callbacks.Workers <= 0 {
+		myConfig.Callbacks.Workers = 8
+	}
+
+	// Retention
+	retentionDurations := []struct {
+		key          string
+		target       *time.Duration
+		defaultValue time.Duration
+	}{
+		{"DELAYED_NOTIFIER_RETENTION_INTERVAL", &myConfig.Retention.Interval, time.Hour},
+		{"DELAYED_NOTIFIER_RETENTION_BATCH_PAUSE", &myConfig.Retention.BatchPause, 100 * time.Millisecond},
+		{"DELAYED_NOTIFIER_RETENTION_SENT", &myConfig.Retention.Sent, 30 * 24 * time.Hour},
+		{"DELAYED_NOTIFIER_RETENTION_DELIVERED", &myConfig.Retention.Delivered, 30 * 24 * time.Hour},
+		{"DELAYED_NOTIFIER_RETENTION_FAILED", &myConfig.Retention.Failed, 90 * 24 * time.Hour},
+		{"DELAYED_NOTIFIER_RETENTION_CANCELLED", &myConfig.Retention.Cancelled, 7 * 24 * time.Hour},
+	}
+	for _, d := range retentionDurations {
+		raw := cfg.GetString(d.key)
+		if raw == "" {
+			*d.target = d.defaultValue
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid %s '%s': expected a non-negative duration like '720h'", d.key, raw)
+		}
+		*d.target = value
+	}
+	myConfig.Retention.BatchSize = cfg.GetInt("DELAYED_NOTIFIER_RETENTION_BATCH_SIZE")
+	myConfig.Retention.MaxBatchesPerRun = cfg.GetInt("DELAYED_NOTIFIER_RETENTION_MAX_BATCHES_PER_RUN")
+	if myConfig.Retention.Interval <= 0 {
+		return nil, fmt.Errorf("invalid DELAYED_NOTIFIER_RETENTION_INTERVAL '%s': expected a positive duration like '1h'", myConfig.Retention.Interval)
+	}
+	if myConfig.Retention.BatchSize <= 0 {
+		myConfig.Retention.BatchSize = 1000
+	}
+	if myConfig.Retention.MaxBatchesPerRun <= 0 {
+		myConfig.Retention.MaxBatchesPerRun = 100
+	}
+
+	// Tracing
+	myConfig.Tracing.Exporter = cfg.GetString("DELAYED_NOTIFIER_TRACING_EXPORTER")
+	myConfig.Tracing.Endpoint = cfg.GetString("DELAYED_NOTIFIER_TRACING_ENDPOINT")
+	myConfig.Tracing.Insecure = cfg.GetBool("DELAYED_NOTIFIER_TRACING_INSECURE")
+	myConfig.Tracing.SampleRatio = cfg.GetFloat64("DELAYED_NOTIFIER_TRACING_SAMPLE_RATIO")
+	if myConfig.Tracing.Exporter == "" {
+		myConfig.Tracing.Exporter = "none"
+	}
+	if myConfig.Tracing.Endpoint == "" {
+		myConfig.Tracing.Endpoint = "localhost:4318"
+	}
+	if myConfig.Tracing.SampleRatio <= 0 || myConfig.Tracing.SampleRatio > 1 {
+		myConfig.Tracing.SampleRatio = 1
+	}
+
 	myConfig.Server.Host = cfg.GetString("DELAYED_NOTIFIER_SERVER_HOST")
 	myConfig.Server.Port = cfg.GetInt("DELAYED_NOTIFIER_SERVER_PORT")
 
@@ -103,3 +284,14 @@ func MakeStrategy(c RetryConfig) retry.Strategy {
 		Backoff:  c.Backoff,
 	}
 }
+
+// splitList parses a comma-separated list, skipping empty items.
+func splitList(raw string) []string {
+	var result []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
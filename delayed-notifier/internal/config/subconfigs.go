@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type PostgresConfig struct {
 	MasterDSN                    string   `env:"MASTER_DSN"`
 	SlaveDSNs                    []string `env:"SLAVE_DSNS" envSeparator:","`
@@ -15,8 +17,22 @@ type RabbitMQConfig struct {
 	Port     int    `yaml:"port" env:"PORT"`         // Порт RabbitMQ (обычно 5672)
 	VHost    string `yaml:"vhost" env:"VHOST"`       // Виртуальный хост в RabbitMQ, для логической сегментации очередей
 	Exchange string `yaml:"exchange" env:"EXCHANGE"` // Название exchange для публикации сообщений
-	Queue    string `yaml:"queue" env:"QUEUE"`       // Название очереди, в которую будут публиковаться сообщения
+	Queue    string `yaml:"queue" env:"QUEUE"`       // префикс очередей каналов, которые объявляет воркер: <queue>.<channel>
+
+	StatusQueue      string `yaml:"status_queue" env:"STATUS_QUEUE"`             // очередь с отчетами воркера о доставке
+	StatusRoutingKey string `yaml:"status_routing_key" env:"STATUS_ROUTING_KEY"` // routing key отчетов о доставке
+
+	DelayedExchange string `yaml:"delayed_exchange" env:"DELAYED_EXCHANGE"` // exchange типа x-delayed-message (стратегия broker)
+	DueQueue        string `yaml:"due_queue" env:"DUE_QUEUE"`               // очередь, куда он возвращает наступившие уведомления
+
+	CancelExchange string `yaml:"cancel_exchange" env:"CANCEL_EXCHANGE"` // fanout exchange отмен уже опубликованных уведомлений
+
+	ChannelsRefresh time.Duration `yaml:"channels_refresh" env:"CHANNELS_REFRESH"` // как долго помнить, объявил ли воркер очередь канала
+}
 
+// QueueFor names the queue a worker declares for a notification channel.
+func (c RabbitMQConfig) QueueFor(channel string) string {
+	return c.Queue + "." + channel
 }
 
 type RedisConfig struct {
@@ -27,6 +43,63 @@ type RedisConfig struct {
 	Expiration int    `yaml:"expiration" env:"EXPIRATION"` // Время жизни ключей (TTL)
 }
 
+type EventsConfig struct {
+	Stream           string `yaml:"stream" env:"STREAM"`                       // redis stream с историей событий (для Last-Event-ID)
+	Channel          string `yaml:"channel" env:"CHANNEL"`                     // redis pub/sub канал для живых событий
+	StreamMaxLen     int64  `yaml:"stream_max_len" env:"STREAM_MAX_LEN"`       // сколько событий хранить в истории
+	SubscriberBuffer int    `yaml:"subscriber_buffer" env:"SUBSCRIBER_BUFFER"` // буфер клиента, при переполнении клиент отключается
+	BacklogLimit     int64  `yaml:"backlog_limit" env:"BACKLOG_LIMIT"`         // максимум событий, отдаваемых при возобновлении
+}
+
+type CallbacksConfig struct {
+	Secret       string `yaml:"secret" env:"SECRET"`                 // ключ HMAC-подписи (заголовок X-Notifier-Signature)
+	MaxAttempts  int    `yaml:"max_attempts" env:"MAX_ATTEMPTS"`     // после стольких неудачных попыток callback помечается failed
+	TimeoutMs    int    `yaml:"timeout_ms" env:"TIMEOUT_MS"`         // таймаут одного запроса
+	BaseDelayMs  int    `yaml:"base_delay_ms" env:"BASE_DELAY_MS"`   // задержка перед первой повторной попыткой, далее удваивается
+	MaxDelayMs   int    `yaml:"max_delay_ms" env:"MAX_DELAY_MS"`     // максимальная задержка между попытками
+	PollPeriodMs int    `yaml:"poll_period_ms" env:"POLL_PERIOD_MS"` // как часто искать callback'и к отправке
+	BatchSize    int    `yaml:"batch_size" env:"BATCH_SIZE"`         // сколько callback'ов забирать за раз
+	Workers      int    `yaml:"workers" env:"WORKERS"`               // сколько callback'ов отправлять параллельно
+
+	AllowedHosts []string `yaml:"allowed_hosts" env:"ALLOWED_HOSTS"` // если задан — callback'и только на эти хосты, ".example.com" — с поддоменами
+	AllowPrivate bool     `yaml:"allow_private" env:"ALLOW_PRIVATE"` // разрешить loopback и внутренние адреса (локальная разработка)
+}
+
+type RetentionConfig struct {
+	Interval         time.Duration `yaml:"interval" env:"INTERVAL"`                       // как часто запускать архивацию
+	BatchSize        int           `yaml:"batch_size" env:"BATCH_SIZE"`                   // сколько строк переносить за один запрос
+	MaxBatchesPerRun int           `yaml:"max_batches_per_run" env:"MAX_BATCHES_PER_RUN"` // ограничение работы за один запуск на каждый статус
+	BatchPause       time.Duration `yaml:"batch_pause" env:"BATCH_PAUSE"`                 // пауза между пачками, чтобы не нагружать базу
+
+	// сколько хранить уведомление в основной таблице после последнего изменения, 0 — не архивировать
+	Sent      time.Duration `yaml:"sent" env:"SENT"`
+	Delivered time.Duration `yaml:"delivered" env:"DELIVERED"`
+	Failed    time.Duration `yaml:"failed" env:"FAILED"`
+	Cancelled time.Duration `yaml:"cancelled" env:"CANCELLED"`
+}
+
+// Scheduling strategies.
+const (
+	SchedulerPoll   = "poll"   // поллер раз в несколько секунд выбирает наступающие уведомления из Postgres
+	SchedulerBroker = "broker" // уведомление сразу публикуется с задержкой в x-delayed-message exchange
+)
+
+// MaxBrokerDelay is the longest delay the delayed message exchange accepts (x-delay is a 32-bit count of milliseconds).
+const MaxBrokerDelay = time.Duration(1<<32-1) * time.Millisecond
+
+type SchedulerConfig struct {
+	Strategy      string        `yaml:"strategy" env:"STRATEGY"`             // poll / broker
+	MaxDelay      time.Duration `yaml:"max_delay" env:"MAX_DELAY"`           // более далекие уведомления остаются поллеру
+	EnqueuedGrace time.Duration `yaml:"enqueued_grace" env:"ENQUEUED_GRACE"` // через сколько после scheduled_at поллер забирает уведомление, не вернувшееся из брокера
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"EXPORTER"`         // none / stdout / otlp
+	Endpoint    string  `yaml:"endpoint" env:"ENDPOINT"`         // host:port OTLP/HTTP коллектора
+	Insecure    bool    `yaml:"insecure" env:"INSECURE"`         // отправлять трассы без TLS
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"` // доля новых трасс, которые записываются (0..1]
+}
+
 type ServerConfig struct {
 	Host string `yaml:"host"` // например, "localhost"
 	Port int    `yaml:"port"` // например, 8080
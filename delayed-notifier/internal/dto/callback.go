@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+// CallbackEvent is the body POSTed to the notification's callback_url.
+type CallbackEvent struct {
+	EventID        string `json:"event_id"`
+	Type           string `json:"type"`
+	NotificationID string `json:"notification_id"`
+	Channel        string `json:"channel"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	Actor          string `json:"actor"`
+	At             string `json:"at"`
+}
+
+func ToCallbackEventFromModel(event *model.NotificationEvent) *CallbackEvent {
+	return &CallbackEvent{
+		EventID:        event.ID,
+		Type:           event.Type,
+		NotificationID: event.NotificationID,
+		Channel:        event.Channel,
+		Status:         event.Status,
+		Error:          event.Error,
+		Actor:          event.Actor,
+		At:             event.At.Format(time.RFC3339Nano),
+	}
+}
+
+type CallbackAttemptFull struct {
+	Attempt     int    `json:"attempt"`
+	StatusCode  *int   `json:"status_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+	AttemptedAt string `json:"attempted_at"`
+}
+
+type CallbackFull struct {
+	ID            string                 `json:"id"`
+	EventID       string                 `json:"event_id"`
+	EventType     string                 `json:"event_type"`
+	URL           string                 `json:"url"`
+	Status        string                 `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	NextAttemptAt string                 `json:"next_attempt_at,omitempty"`
+	CreatedAt     string                 `json:"created_at"`
+	History       []*CallbackAttemptFull `json:"history"`
+}
+
+func ToCallbackFullFromModel(cb *model.Callback) *CallbackFull {
+	full := &CallbackFull{
+		ID:        cb.ID.String(),
+		EventID:   cb.EventID,
+		EventType: cb.EventType,
+		URL:       cb.URL,
+		Status:    cb.Status,
+		Attempts:  cb.Attempts,
+		CreatedAt: cb.CreatedAt.Format(time.RFC3339Nano),
+		History:   make([]*CallbackAttemptFull, len(cb.History)),
+	}
+	if cb.Status == model.CallbackPending {
+		full.NextAttemptAt = cb.NextAttemptAt.Format(time.RFC3339Nano)
+	}
+	for i, attempt := range cb.History {
+		item := &CallbackAttemptFull{
+			Attempt:     attempt.Attempt,
+			StatusCode:  attempt.StatusCode,
+			DurationMs:  attempt.Duration.Milliseconds(),
+			AttemptedAt: attempt.AttemptedAt.Format(time.RFC3339Nano),
+		}
+		if attempt.Error != nil {
+			item.Error = *attempt.Error
+		}
+		full.History[i] = item
+	}
+	return full
+}
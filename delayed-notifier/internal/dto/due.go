@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// DueMessage is published to the delayed exchange. It carries only the id: the
+// notification is read from Postgres again when the delay expires, so cancellations
+// made in between are respected.
+type DueMessage struct {
+	ID          string `json:"id"`
+	ScheduledAt string `json:"scheduled_at"` // RFC3339Nano
+}
+
+func ToDueFromModel(notification *model.Notification) ([]byte, error) {
+	return json.Marshal(DueMessage{
+		ID:          notification.ID.String(),
+		ScheduledAt: notification.ScheduledAt.Format(time.RFC3339Nano),
+	})
+}
+
+func ToModelFromDue(data []byte) (*model.DueNotification, error) {
+	var msg DueMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid due message json: %w", err)
+	}
+
+	id, err := types.NewUUID(msg.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id in due message: %w", err)
+	}
+	scheduledAt, err := time.Parse(time.RFC3339Nano, msg.ScheduledAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'scheduled_at' in due message: %w", err)
+	}
+
+	return &model.DueNotification{
+		ID:          &id,
+		ScheduledAt: scheduledAt,
+	}, nil
+}
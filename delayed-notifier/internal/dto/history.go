@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+type HistoryEntryFull struct {
+	ID            int64   `json:"id"`
+	Type          string  `json:"type"`
+	Status        string  `json:"status"`
+	Actor         string  `json:"actor"`
+	Details       *string `json:"details,omitempty"`
+	StreamEventID *string `json:"stream_event_id,omitempty"`
+	At            string  `json:"at"`
+}
+
+func ToHistoryEntryFullFromModel(entry *model.HistoryEntry) *HistoryEntryFull {
+	return &HistoryEntryFull{
+		ID:            entry.ID,
+		Type:          entry.Type,
+		Status:        entry.Status,
+		Actor:         entry.Actor,
+		Details:       entry.Details,
+		StreamEventID: entry.StreamEventID,
+		At:            entry.At.Format(time.RFC3339Nano),
+	}
+}
@@ -1,12 +1,14 @@
 package dto
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/internaltypes"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 )
 
 type NotificationCreate struct {
@@ -14,31 +16,176 @@ type NotificationCreate struct {
 	Channel     string `json:"channel" db:"channel"`           // email, telegram
 	Message     string `json:"message" db:"message"`           // текст уведомления
 	ScheduledAt string `json:"scheduled_at" db:"scheduled_at"` // время отправки
+	CallbackURL string `json:"callback_url" db:"callback_url"` // необязательный URL для событий о смене статуса
+
+	Subject     string              `json:"subject"`     // тема письма, заголовок push
+	BodyHTML    string              `json:"body_html"`   // HTML-версия message
+	Attachments []domain.Attachment `json:"attachments"` // ссылки на файлы, воркер скачивает их при отправке
+	Metadata    map[string]string   `json:"metadata"`    // произвольные метки
+
+	UserID   string   `json:"user_id"`  // пользователь из справочника получателей вместо recipient и channel
+	Category string   `json:"category"` // категория для проверки отписок пользователя
+	Channels []string `json:"channels"` // порядок выбора канала пользователя
+
+	Targets []model.Target `json:"targets"` // цепочка целей вместо recipient и channel: следующая — после окончательной ошибки
 }
 
+// maxTargets bounds a fallback chain.
+const maxTargets = 10
+
 func (b NotificationCreate) ToEnity() (*model.Notification, error) {
 	var err error
-	
+	if b.UserID != "" {
+		return b.toUserEntity()
+	}
+	if b.Category != "" || len(b.Channels) > 0 {
+		return nil, errors.New("'category' and 'channels' require 'user_id'")
+	}
+	if len(b.Targets) > 0 {
+		if b.Recipient != "" || b.Channel != "" {
+			return nil, errors.New("'recipient' and 'channel' can't be combined with 'targets'")
+		}
+		// первая цель цепочки становится текущей
+		b.Channel, b.Recipient = b.Targets[0].Channel, b.Targets[0].Recipient
+	}
 
-	var channel internaltypes.NotificationChannel
-	channel, err = internaltypes.NotificationChannelFromString(b.Channel)
+	var channel domain.NotificationChannel
+	channel, err = domain.NotificationChannelFromString(b.Channel)
 	if err != nil {
 		return nil, fmt.Errorf("incorrect 'channel' '%s': %w", b.Channel, err)
 	}
-	rec, err := internaltypes.NewSendTo(types.NewAnyText(b.Recipient), channel)
+	rec, err := domain.NewSendTo(types.NewAnyText(b.Recipient), channel)
 	if err != nil {
-		return nil, fmt.Errorf("incorrect 'recipment' '%s': %w", b.ScheduledAt, err)
+		return nil, fmt.Errorf("incorrect 'recipient' '%s': %w", b.Recipient, err)
 	}
 	shedAt, err := time.Parse(time.RFC3339, b.ScheduledAt)
 	if err != nil {
 		return nil, fmt.Errorf("incorrect 'scheduled_at' '%s': %w", b.ScheduledAt, err)
 	}
 
+	content := domain.Content{
+		Subject:     b.Subject,
+		BodyHTML:    b.BodyHTML,
+		Attachments: b.Attachments,
+		Metadata:    b.Metadata,
+	}
+	if err = domain.ValidateContent(channel, content); err != nil {
+		return nil, fmt.Errorf("incorrect content: %w", err)
+	}
+	if err = validateTargets(b.Targets, content); err != nil {
+		return nil, err
+	}
+
+	if b.CallbackURL != "" {
+		if err = validateCallbackURL(b.CallbackURL); err != nil {
+			return nil, fmt.Errorf("incorrect 'callback_url' '%s': %w", b.CallbackURL, err)
+		}
+	}
+
 	return &model.Notification{
 		Recipient:   rec,
 		Channel:     channel,
 		Message:     b.Message,
 		ScheduledAt: shedAt,
+		CallbackURL: b.CallbackURL,
+		Targets:     b.Targets,
+		Content:     content,
 	}, nil
 
 }
+
+// toUserEntity builds a notification addressed to a user of the recipient directory;
+// the channel is picked when it is sent, so content is checked against the limits only.
+func (b NotificationCreate) toUserEntity() (*model.Notification, error) {
+	if b.Recipient != "" || b.Channel != "" || len(b.Targets) > 0 {
+		return nil, errors.New("'recipient', 'channel' and 'targets' can't be combined with 'user_id'")
+	}
+	if err := validateUserID(b.UserID); err != nil {
+		return nil, fmt.Errorf("incorrect 'user_id' '%s': %w", b.UserID, err)
+	}
+	if b.Category != "" && !categoryPattern.MatchString(b.Category) {
+		return nil, fmt.Errorf("incorrect 'category' '%s': expected lowercase letters, digits, '_', '.', '-'", b.Category)
+	}
+	seen := make(map[string]struct{}, len(b.Channels))
+	for _, name := range b.Channels {
+		if _, err := domain.NotificationChannelFromString(name); err != nil {
+			return nil, fmt.Errorf("incorrect 'channels' item '%s': %w", name, err)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate 'channels' item '%s'", name)
+		}
+		seen[name] = struct{}{}
+	}
+	shedAt, err := time.Parse(time.RFC3339, b.ScheduledAt)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect 'scheduled_at' '%s': %w", b.ScheduledAt, err)
+	}
+
+	content := domain.Content{
+		Subject:     b.Subject,
+		BodyHTML:    b.BodyHTML,
+		Attachments: b.Attachments,
+		Metadata:    b.Metadata,
+	}
+	if err = domain.ValidateContentLimits(content); err != nil {
+		return nil, fmt.Errorf("incorrect content: %w", err)
+	}
+
+	if b.CallbackURL != "" {
+		if err = validateCallbackURL(b.CallbackURL); err != nil {
+			return nil, fmt.Errorf("incorrect 'callback_url' '%s': %w", b.CallbackURL, err)
+		}
+	}
+
+	return &model.Notification{
+		Message:     b.Message,
+		ScheduledAt: shedAt,
+		CallbackURL: b.CallbackURL,
+		Content:     content,
+		Audience: model.Audience{
+			UserID:   b.UserID,
+			Category: b.Category,
+			Channels: b.Channels,
+		},
+	}, nil
+}
+
+// validateTargets checks a fallback chain: every target must be able to deliver the
+// content, since any of them may end up delivering it.
+func validateTargets(targets []model.Target, content domain.Content) error {
+	if len(targets) > maxTargets {
+		return fmt.Errorf("more than %d 'targets'", maxTargets)
+	}
+	seen := make(map[model.Target]struct{}, len(targets))
+	for i, t := range targets {
+		channel, err := domain.NotificationChannelFromString(t.Channel)
+		if err != nil {
+			return fmt.Errorf("target %d: incorrect 'channel' '%s': %w", i, t.Channel, err)
+		}
+		if _, err = domain.NewSendTo(types.NewAnyText(t.Recipient), channel); err != nil {
+			return fmt.Errorf("target %d: incorrect 'recipient' '%s': %w", i, t.Recipient, err)
+		}
+		if _, ok := seen[t]; ok {
+			return fmt.Errorf("target %d: duplicate of an earlier target", i)
+		}
+		seen[t] = struct{}{}
+		if err = domain.ValidateContent(channel, content); err != nil {
+			return fmt.Errorf("target %d: incorrect content: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("host is required")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+func TestNotificationCreateTargets(t *testing.T) {
+	var tooMany []model.Target
+	for i := 0; i <= maxTargets; i++ {
+		tooMany = append(tooMany, model.Target{Channel: "email", Recipient: fmt.Sprintf("user%d@example.com", i)})
+	}
+
+	cases := []struct {
+		name    string
+		body    NotificationCreate
+		wantErr string
+	}{
+		{
+			name: "chain",
+			body: NotificationCreate{Targets: []model.Target{
+				{Channel: "telegram", Recipient: "123456"},
+				{Channel: "email", Recipient: "a@example.com"},
+			}},
+		},
+		{
+			name:    "too many targets",
+			body:    NotificationCreate{Targets: tooMany},
+			wantErr: "more than 10 'targets'",
+		},
+		{
+			name: "duplicate target",
+			body: NotificationCreate{Targets: []model.Target{
+				{Channel: "email", Recipient: "a@example.com"},
+				{Channel: "telegram", Recipient: "123456"},
+				{Channel: "email", Recipient: "a@example.com"},
+			}},
+			wantErr: "target 2: duplicate",
+		},
+		{
+			name: "invalid recipient of a fallback target",
+			body: NotificationCreate{Targets: []model.Target{
+				{Channel: "email", Recipient: "a@example.com"},
+				{Channel: "email", Recipient: "not an address"},
+			}},
+			wantErr: "target 1: incorrect 'recipient'",
+		},
+		{
+			// тему доставит почта, но не запасной telegram
+			name: "content unsupported by a fallback target",
+			body: NotificationCreate{Subject: "Order shipped", Targets: []model.Target{
+				{Channel: "email", Recipient: "a@example.com"},
+				{Channel: "telegram", Recipient: "123456"},
+			}},
+			wantErr: "target 1: incorrect content",
+		},
+		{
+			name: "targets with channel",
+			body: NotificationCreate{Channel: "email", Targets: []model.Target{
+				{Channel: "email", Recipient: "a@example.com"},
+			}},
+			wantErr: "can't be combined with 'targets'",
+		},
+	}
+	for _, tc := range cases {
+		tc.body.Message = "your order has shipped"
+		tc.body.ScheduledAt = "2025-01-01T12:00:00Z"
+		notify, err := tc.body.ToEnity()
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+				continue
+			}
+			// первая цель становится текущей
+			if notify.Channel.String() != "telegram" || notify.Recipient.String() != "123456" || len(notify.Targets) != 2 {
+				t.Errorf("%s: current target %s:%s, chain %v", tc.name, notify.Channel, notify.Recipient, notify.Targets)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
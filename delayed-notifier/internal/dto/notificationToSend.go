@@ -1,35 +1,42 @@
 package dto
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
 )
 
-type NotificationToSend struct {
-	ID          string `json:"id"`
-	Recipient   string `json:"recipient" db:"recipient"`       // email, telegram id и т.д.
-	Channel     string `json:"channel" db:"channel"`           // email, telegram
-	Message     string `json:"message" db:"message"`           // текст уведомления
-	ScheduledAt string `json:"scheduled_at" db:"scheduled_at"` // время отправки
-}
-
-func ToDTOFromModel(obj *model.Notification) *NotificationToSend {
-	return &NotificationToSend{
+// ToWireFromModel builds the message the worker receives, see the wire package for the schema.
+func ToWireFromModel(obj *model.Notification) wire.Notification {
+	return wire.Notification{
 		ID:          obj.ID.String(),
 		Recipient:   obj.Recipient.String(),
 		Channel:     obj.Channel.String(),
 		Message:     obj.Message,
-		ScheduledAt: obj.ScheduledAt.Format("2006-01-02T15:04:05Z07:00"), // ISO8601
+		ScheduledAt: obj.ScheduledAt,
+		Subject:     obj.Subject,
+		BodyHTML:    obj.BodyHTML,
+		Attachments: toWireAttachments(obj.Attachments),
+		Metadata:    obj.Metadata,
 	}
 }
 
-
-func ToSendFromDTO(obj *model.Notification) ([]byte, error) {
-	data, err := json.Marshal(ToDTOFromModel(obj))
-	if err != nil {
-		return nil, fmt.Errorf("could not marshal NotificationToSend: %w", err)
+func toWireAttachments(attachments []domain.Attachment) []wire.Attachment {
+	if len(attachments) == 0 {
+		return nil
 	}
-	return data, nil
+	result := make([]wire.Attachment, len(attachments))
+	for i, a := range attachments {
+		result[i] = wire.Attachment{
+			URL:         a.URL,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
+	}
+	return result
 }
 
+func ToSendFromDTO(obj *model.Notification) ([]byte, error) {
+	return wire.EncodeNotification(ToWireFromModel(obj))
+}
@@ -3,6 +3,7 @@ package dto
 import (
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
 )
 
 type NotificationFull struct {
@@ -14,6 +15,12 @@ type NotificationFull struct {
 	Status      string `json:"status"`
 	Tries       string `json:"tries"`
 	LastError   string `json:"last_error"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	Targets     []model.Target `json:"targets,omitempty"`
+	TargetIndex int            `json:"target_index,omitempty"`
+
+	domain.Content
+	model.Audience
 }
 
 func ToFullFromModelNotification(notify *model.Notification) *NotificationFull {
@@ -24,6 +31,11 @@ func ToFullFromModelNotification(notify *model.Notification) *NotificationFull {
 		ScheduledAt: notify.ScheduledAt.String(),
 		Status: notify.Status,
 		Message: notify.Message,
+		CallbackURL: notify.CallbackURL,
+		Targets:     notify.Targets,
+		TargetIndex: notify.TargetIndex,
+		Content:     notify.Content,
+		Audience:    notify.Audience,
 		// Tries: strconv.Itoa(notify.Tries),
 		// LastError: *notify.LastError,
 	}
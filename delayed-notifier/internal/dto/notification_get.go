@@ -1,7 +1,7 @@
 package dto
 
 import (
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 	"github.com/wb-go/wbf/ginext"
 )
 
@@ -0,0 +1,11 @@
+package dto
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type"`               // URI reference that identifies the problem type
+	Title    string `json:"title"`              // short summary of the problem type
+	Status   int    `json:"status"`             // HTTP status code
+	Detail   string `json:"detail,omitempty"`   // explanation specific to this occurrence
+	Instance string `json:"instance,omitempty"` // URI reference of the request
+	Code     string `json:"code"`               // stable machine-readable error code
+}
@@ -0,0 +1,115 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/ginext"
+)
+
+const (
+	maxUserIDLength = 128 // символов
+	maxPreferences  = 100
+)
+
+// categoryPattern matches a notification category: "marketing", "billing.invoice".
+var categoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+type RecipientSave struct {
+	UserID      string           `json:"user_id"`
+	Contacts    []model.Contact  `json:"contacts"`    // в порядке предпочтения, по одному на канал
+	Preferences []PreferenceSave `json:"preferences"` // необязательные подписки и отписки
+}
+
+type PreferenceSave struct {
+	Channel  string `json:"channel"`  // пусто — любой канал
+	Category string `json:"category"` // пусто — любая категория
+	OptedIn  *bool  `json:"opted_in"`
+}
+
+func (b RecipientSave) ToModel() (*model.RecipientProfile, error) {
+	if err := validateUserID(b.UserID); err != nil {
+		return nil, fmt.Errorf("incorrect 'user_id' '%s': %w", b.UserID, err)
+	}
+	if len(b.Contacts) == 0 {
+		return nil, errors.New("'contacts' must have at least one item")
+	}
+
+	contacts := make([]model.Contact, 0, len(b.Contacts))
+	seen := make(map[string]struct{}, len(b.Contacts))
+	for _, c := range b.Contacts {
+		channel, err := domain.NotificationChannelFromString(c.Channel)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect contact 'channel' '%s': %w", c.Channel, err)
+		}
+		if _, ok := seen[c.Channel]; ok {
+			return nil, fmt.Errorf("more than one contact for channel '%s'", c.Channel)
+		}
+		seen[c.Channel] = struct{}{}
+		if _, err = domain.NewSendTo(types.NewAnyText(c.Address), channel); err != nil {
+			return nil, fmt.Errorf("incorrect contact 'address' for channel '%s': %w", c.Channel, err)
+		}
+		contacts = append(contacts, model.Contact{Channel: c.Channel, Address: c.Address})
+	}
+
+	if len(b.Preferences) > maxPreferences {
+		return nil, fmt.Errorf("more than %d 'preferences'", maxPreferences)
+	}
+	preferences := make([]model.Preference, 0, len(b.Preferences))
+	for i, p := range b.Preferences {
+		if p.Channel != "" {
+			if _, err := domain.NotificationChannelFromString(p.Channel); err != nil {
+				return nil, fmt.Errorf("preference %d: incorrect 'channel' '%s': %w", i, p.Channel, err)
+			}
+		}
+		if p.Category != "" && !categoryPattern.MatchString(p.Category) {
+			return nil, fmt.Errorf("preference %d: incorrect 'category' '%s': expected lowercase letters, digits, '_', '.', '-'", i, p.Category)
+		}
+		if p.OptedIn == nil {
+			return nil, fmt.Errorf("preference %d: 'opted_in' is required", i)
+		}
+		preferences = append(preferences, model.Preference{Channel: p.Channel, Category: p.Category, OptedIn: *p.OptedIn})
+	}
+
+	return &model.RecipientProfile{
+		UserID:      b.UserID,
+		Contacts:    contacts,
+		Preferences: preferences,
+	}, nil
+}
+
+func validateUserID(userID string) error {
+	if strings.TrimSpace(userID) == "" {
+		return errors.New("must not be blank")
+	}
+	if utf8.RuneCountInString(userID) > maxUserIDLength {
+		return fmt.Errorf("longer than %d characters", maxUserIDLength)
+	}
+	if strings.ContainsFunc(userID, unicode.IsControl) {
+		return errors.New("contains control characters")
+	}
+	return nil
+}
+
+// RecipientRequest is the path of /recipients/:user_id.
+type RecipientRequest struct {
+	UserID string `uri:"user_id" binding:"required"`
+}
+
+func BindRecipientRequest(c *ginext.Context) (*RecipientRequest, error) {
+	var req RecipientRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		return nil, err
+	}
+	if err := validateUserID(req.UserID); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
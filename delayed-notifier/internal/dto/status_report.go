@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// StatusReportMessage is published by the worker after a delivery attempt.
+type StatusReportMessage struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`          // delivered / failed
+	Error  string `json:"error,omitempty"` // текст ошибки для failed
+	At     string `json:"at"`              // время попытки, RFC3339
+
+	Unreachable bool `json:"unreachable,omitempty"` // получатель больше не может получать уведомления канала
+
+	Channel   string `json:"channel,omitempty"` // цель попытки, старые воркеры ее не присылают
+	Recipient string `json:"recipient,omitempty"`
+}
+
+func ToModelFromStatusReport(data []byte) (*model.StatusReport, error) {
+	var msg StatusReportMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid status report json: %w", err)
+	}
+
+	id, err := types.NewUUID(msg.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id in status report: %w", err)
+	}
+	if msg.Status != model.StatusDelivered && msg.Status != model.StatusFailed {
+		return nil, fmt.Errorf("invalid status in status report: '%s'", msg.Status)
+	}
+	at, err := time.Parse(time.RFC3339Nano, msg.At)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'at' in status report: %w", err)
+	}
+
+	report := &model.StatusReport{
+		ID:     &id,
+		Status: msg.Status,
+		At:     at,
+
+		Unreachable: msg.Unreachable,
+
+		Channel:   msg.Channel,
+		Recipient: msg.Recipient,
+	}
+	if msg.Error != "" {
+		report.Error = &msg.Error
+	}
+	return report, nil
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// HealthHandler serves k8s probes. Liveness only looks at the process' own loops,
+// so a dependency outage takes replicas out of the Service instead of restarting them.
+type HealthHandler struct {
+	liveness  *health.Registry
+	readiness *health.Registry
+}
+
+func NewHealthHandler(liveness *health.Registry, readiness *health.Registry) *HealthHandler {
+	return &HealthHandler{liveness: liveness, readiness: readiness}
+}
+
+func (h *HealthHandler) Healthz(c *ginext.Context) {
+	writeReport(c, h.liveness.Run(c.Request.Context()))
+}
+
+func (h *HealthHandler) Readyz(c *ginext.Context) {
+	writeReport(c, h.readiness.Run(c.Request.Context()))
+}
+
+func writeReport(c *ginext.Context, report health.Report) {
+	status := http.StatusOK
+	if !report.Up() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
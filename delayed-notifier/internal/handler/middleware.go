@@ -2,8 +2,13 @@ package handler
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/requestid"
+	"github.com/google/uuid"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/wb-go/wbf/ginext"
 )
@@ -31,6 +36,44 @@ func init() {
 }
 
 
+// actorHeader names the caller. It is trusted as is: the service has no auth of its
+// own and expects the gateway in front of it to set or strip the header.
+const actorHeader = "X-Actor"
+
+const maxActorLength = 128
+
+// ActorMiddleware puts the caller into the request context for the audit log.
+// Without X-Actor the caller is identified by its IP.
+func ActorMiddleware(c *ginext.Context) {
+	name := strings.TrimSpace(c.GetHeader(actorHeader))
+	if len(name) > maxActorLength {
+		name = name[:maxActorLength]
+	}
+	if name == "" {
+		name = "ip:" + c.ClientIP()
+	}
+	c.Request = c.Request.WithContext(actor.WithActor(c.Request.Context(), name))
+	c.Next()
+}
+
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware takes the caller's X-Request-ID or generates one and returns it
+// in the response. It is stored with created notifications and passed on to the worker.
+func RequestIDMiddleware(c *ginext.Context) {
+	id := strings.TrimSpace(c.GetHeader(requestid.Header))
+	if id == "" || len(id) > maxRequestIDLength || strings.ContainsFunc(id, isNotPrintableASCII) {
+		id = uuid.NewString()
+	}
+	c.Header(requestid.Header, id)
+	c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+	c.Next()
+}
+
+func isNotPrintableASCII(r rune) bool {
+	return r < 0x21 || r > 0x7e
+}
+
 func MetricsMiddleware(c *ginext.Context) {
 	start := time.Now()
 	c.Next() // выполняем хендлер
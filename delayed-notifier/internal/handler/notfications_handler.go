@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
@@ -13,38 +14,47 @@ import (
 )
 
 type NotifyHandler struct {
-	crudService ports.CRUDServiceInterface
+	crudService     ports.CRUDServiceInterface
+	callbackService ports.CallbackServiceInterface
+	historyService  ports.HistoryServiceInterface
 }
 
-func NewNotifyHandler(crudService ports.CRUDServiceInterface) *NotifyHandler {
-	return &NotifyHandler{crudService: crudService}
+func NewNotifyHandler(
+	crudService ports.CRUDServiceInterface,
+	callbackService ports.CallbackServiceInterface,
+	historyService ports.HistoryServiceInterface,
+) *NotifyHandler {
+	return &NotifyHandler{crudService: crudService, callbackService: callbackService, historyService: historyService}
+}
+
+// detachedContext keeps request values (actor) but is not cancelled when the client
+// goes away, so a started create/delete finishes and gets into the audit log.
+func detachedContext(c *ginext.Context) context.Context {
+	return context.WithoutCancel(c.Request.Context())
 }
 
 func (h *NotifyHandler) CreateNotification(c *ginext.Context) {
 	var body dto.NotificationCreate
 
-	err := c.BindJSON(&body)
+	err := c.ShouldBindJSON(&body)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, ginext.H{"error": fmt.Sprintf("invalid body (parsing): %s", err.Error())})
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidBody, "invalid body (parsing)", err))
 		return
 	}
 
 	var createModel *model.Notification
 	createModel, err = body.ToEnity()
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, ginext.H{"error": fmt.Sprintf("invalid body (validating): %s", err.Error())})
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeValidationFailed, "invalid body (validating)", err))
 		return
 	}
 
-	notif, err := h.crudService.CreateNotification(context.Background(), createModel)
+	notif, err := h.crudService.CreateNotification(detachedContext(c), createModel)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusConflict,
-			ginext.H{"error": fmt.Sprintf("couldn't perform operation: %s", err.Error())},
-		)
+		abortWithProblem(c, fmt.Errorf("couldn't create notification: %w", err))
 		return
 	}
-	
+
 
 	c.JSON(http.StatusCreated, dto.ToFullFromModelNotification(notif))
 }
@@ -52,27 +62,18 @@ func (h *NotifyHandler) CreateNotification(c *ginext.Context) {
 func (h *NotifyHandler) GetNotification(c *ginext.Context) {
 	req, err := dto.BindNotificationRequest(c)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			ginext.H{"error": fmt.Sprintf("invalid ID parameter: %s", err.Error())},
-		)
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid ID parameter", err))
 		return
 	}
 
 	id, err := req.ToUUID()
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			ginext.H{"error": fmt.Sprintf("invalid UUID format: %s", err.Error())},
-		)
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid UUID format", err))
 		return
 	}
-	notification, err := h.crudService.GetNotification(context.Background(), id)
+	notification, err := h.crudService.GetNotification(detachedContext(c), id)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError,
-			ginext.H{"error": fmt.Sprintf("couldn't get notification: %s", err.Error())},
-		)
+		abortWithProblem(c, fmt.Errorf("couldn't get notification: %w", err))
 		return
 	}
 
@@ -81,10 +82,7 @@ func (h *NotifyHandler) GetNotification(c *ginext.Context) {
 func (h *NotifyHandler) GetAllNotifications(c *ginext.Context) {
 	notifications, err := h.crudService.GetAllNotifications(c.Request.Context())
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError,
-			ginext.H{"error": fmt.Sprintf("couldn't get notifications: %s", err.Error())},
-		)
+		abortWithProblem(c, fmt.Errorf("couldn't get notifications: %w", err))
 		return
 	}
 
@@ -99,34 +97,113 @@ func (h *NotifyHandler) GetAllNotifications(c *ginext.Context) {
 func (h *NotifyHandler) DeleteNotification(c *ginext.Context) {
 	req, err := dto.BindNotificationRequest(c)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			ginext.H{"error": fmt.Sprintf("invalid ID parameter: %s", err.Error())},
-		)
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid ID parameter", err))
 		return
 	}
 
 	id, err := req.ToUUID()
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			ginext.H{"error": fmt.Sprintf("invalid UUID format: %s", err.Error())},
-		)
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid UUID format", err))
 		return
 	}
 
-	err = h.crudService.DeleteNotification(context.Background(), id)
+	err = h.crudService.DeleteNotification(detachedContext(c), id)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError,
-			ginext.H{"error": fmt.Sprintf("couldn't delete notification: %s", err.Error())},
-		)
+		abortWithProblem(c, fmt.Errorf("couldn't delete notification: %w", err))
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+func (h *NotifyHandler) GetCallbacks(c *ginext.Context) {
+	req, err := dto.BindNotificationRequest(c)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid ID parameter", err))
+		return
+	}
+
+	id, err := req.ToUUID()
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid UUID format", err))
+		return
+	}
+
+	// 404 для несуществующего уведомления, а не пустой список
+	if _, err = h.crudService.GetNotification(c.Request.Context(), id); err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get notification: %w", err))
+		return
+	}
+
+	callbacks, err := h.callbackService.GetCallbacks(c.Request.Context(), id)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get callbacks: %w", err))
+		return
+	}
+
+	dtoCallbacks := make([]*dto.CallbackFull, len(callbacks))
+	for i, cb := range callbacks {
+		dtoCallbacks[i] = dto.ToCallbackFullFromModel(cb)
+	}
+
+	c.JSON(http.StatusOK, dtoCallbacks)
+}
+
+func (h *NotifyHandler) GetAttempts(c *ginext.Context) {
+	req, err := dto.BindNotificationRequest(c)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid ID parameter", err))
+		return
+	}
+
+	id, err := req.ToUUID()
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid UUID format", err))
+		return
+	}
+
+	// 404 для несуществующего уведомления, а не пустой список
+	if _, err = h.crudService.GetNotification(c.Request.Context(), id); err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get notification: %w", err))
+		return
+	}
+
+	attempts, err := h.crudService.GetAttempts(c.Request.Context(), id)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get delivery attempts: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+func (h *NotifyHandler) GetHistory(c *ginext.Context) {
+	req, err := dto.BindNotificationRequest(c)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid ID parameter", err))
+		return
+	}
+
+	id, err := req.ToUUID()
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid UUID format", err))
+		return
+	}
+
+	// история доступна и для удаленных уведомлений
+	history, err := h.historyService.GetHistory(c.Request.Context(), id)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get history: %w", err))
+		return
+	}
+
+	dtoHistory := make([]*dto.HistoryEntryFull, len(history))
+	for i, entry := range history {
+		dtoHistory[i] = dto.ToHistoryEntryFullFromModel(entry)
+	}
+
+	c.JSON(http.StatusOK, dtoHistory)
+}
 
 func (h *NotifyHandler) Metrics(c *ginext.Context) {
 	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// attemptsCRUDService knows one notification and its delivery attempts; other
+// methods aren't used by the tests.
+type attemptsCRUDService struct {
+	ports.CRUDServiceInterface
+	id       types.UUID
+	attempts []*model.DeliveryAttempt
+}
+
+func (s *attemptsCRUDService) GetNotification(ctx context.Context, id types.UUID) (*model.Notification, error) {
+	if id.String() != s.id.String() {
+		return nil, apperrors.NotFound(apperrors.CodeNotFound, "notification not found", nil)
+	}
+	return &model.Notification{ID: &s.id}, nil
+}
+
+func (s *attemptsCRUDService) GetAttempts(ctx context.Context, id types.UUID) ([]*model.DeliveryAttempt, error) {
+	return s.attempts, nil
+}
+
+func TestGetAttempts(t *testing.T) {
+	failure := "mailbox is full"
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := &attemptsCRUDService{
+		id: types.GenerateUUID(),
+		attempts: []*model.DeliveryAttempt{
+			{TargetIndex: 0, Channel: "email", Recipient: "a@example.com", Status: model.StatusFailed, Error: &failure, AttemptedAt: at},
+			{TargetIndex: 1, Channel: "telegram", Recipient: "123456", Status: model.StatusDelivered, AttemptedAt: at.Add(time.Minute)},
+		},
+	}
+	router := ginext.New("release")
+	router.GET("/notify/:id/attempts", NewNotifyHandler(service, nil, nil).GetAttempts)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	recorder := get("/notify/" + service.id.String() + "/attempts")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", recorder.Code, recorder.Body)
+	}
+	var attempts []model.DeliveryAttempt
+	if err := json.Unmarshal(recorder.Body.Bytes(), &attempts); err != nil {
+		t.Fatalf("body %s: %v", recorder.Body, err)
+	}
+	if len(attempts) != 2 || attempts[0].TargetIndex != 0 || attempts[0].Error == nil || *attempts[0].Error != failure ||
+		attempts[1].TargetIndex != 1 || attempts[1].Channel != "telegram" || attempts[1].Status != model.StatusDelivered {
+		t.Fatalf("attempts %s", recorder.Body)
+	}
+
+	// несуществующее уведомление — 404, а не пустой список
+	unknown := types.GenerateUUID()
+	if recorder := get("/notify/" + unknown.String() + "/attempts"); recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown notification: status %d, body %s", recorder.Code, recorder.Body)
+	}
+	if recorder := get("/notify/42/attempts"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: status %d, body %s", recorder.Code, recorder.Body)
+	}
+}
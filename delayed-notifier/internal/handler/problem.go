@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypeBase    = "https://delayed-notifier/problems/"
+)
+
+// statusOf maps a domain error kind to an HTTP status code.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// abortWithProblem writes err as an RFC 7807 response and aborts the chain.
+func abortWithProblem(c *ginext.Context, err error) {
+	status := statusOf(err)
+	code := apperrors.CodeOf(err)
+
+	// клиенту — только публичное сообщение, цепочка ошибок с текстом драйверов остается в логе
+	detail := apperrors.PublicMessage(err)
+	switch status {
+	case http.StatusInternalServerError:
+		zlog.Logger.Error().Err(err).Str("path", c.FullPath()).Msg("internal error while handling request")
+		detail = "internal server error"
+	case http.StatusServiceUnavailable:
+		zlog.Logger.Warn().Err(err).Str("path", c.FullPath()).Str("code", code).Msg("dependency unavailable while handling request")
+	default:
+		zlog.Logger.Debug().Err(err).Str("path", c.FullPath()).Str("code", code).Msg("request rejected")
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(status, dto.Problem{
+		Type:     problemTypeBase + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// RecipientHandler serves the recipient directory.
+type RecipientHandler struct {
+	recipientService ports.RecipientServiceInterface
+}
+
+func NewRecipientHandler(recipientService ports.RecipientServiceInterface) *RecipientHandler {
+	return &RecipientHandler{recipientService: recipientService}
+}
+
+// SaveRecipient creates the user or replaces their contacts and preferences.
+func (h *RecipientHandler) SaveRecipient(c *ginext.Context) {
+	var body dto.RecipientSave
+
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidBody, "invalid body (parsing)", err))
+		return
+	}
+
+	var profile *model.RecipientProfile
+	profile, err = body.ToModel()
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeValidationFailed, "invalid body (validating)", err))
+		return
+	}
+
+	profile, err = h.recipientService.SaveRecipient(detachedContext(c), profile)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't save recipient: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *RecipientHandler) GetRecipient(c *ginext.Context) {
+	req, err := dto.BindRecipientRequest(c)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid user_id parameter", err))
+		return
+	}
+
+	profile, err := h.recipientService.GetRecipient(c.Request.Context(), req.UserID)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't get recipient: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *RecipientHandler) DeleteRecipient(c *ginext.Context) {
+	req, err := dto.BindRecipientRequest(c)
+	if err != nil {
+		abortWithProblem(c, apperrors.Validation(apperrors.CodeInvalidID, "invalid user_id parameter", err))
+		return
+	}
+
+	err = h.recipientService.DeleteRecipient(detachedContext(c), req.UserID)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("couldn't delete recipient: %w", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
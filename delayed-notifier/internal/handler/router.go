@@ -1,19 +1,42 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/wb-go/wbf/ginext"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func NewRouter(notifyHandler *NotifyHandler) *ginext.Engine {
+func NewRouter(notifyHandler *NotifyHandler, recipientHandler *RecipientHandler, streamHandler *StreamHandler, healthHandler *HealthHandler) *ginext.Engine {
 	router := ginext.New("release")
 	router.Use(MetricsMiddleware)
 	router.Use(ginext.Logger())
 	router.Use(ginext.Recovery())
+	// спан на каждый запрос, кроме служебных эндпоинтов, которые дергают по расписанию
+	router.Use(otelgin.Middleware("delayed-notifier", otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	})))
+	router.Use(RequestIDMiddleware)
+	router.Use(ActorMiddleware)
 	router.StaticFile("/", "/app/internal/static/index.html")
 	router.POST("/notify", notifyHandler.CreateNotification)
 	router.GET("/notify", notifyHandler.GetAllNotifications)
+	router.GET("/notify/stream", streamHandler.StreamSSE)
+	router.GET("/notify/ws", streamHandler.StreamWebSocket)
 	router.GET("/notify/:id", notifyHandler.GetNotification)
 	router.DELETE("/notify/:id", notifyHandler.DeleteNotification)
+	router.GET("/notify/:id/callbacks", notifyHandler.GetCallbacks)
+	router.GET("/notify/:id/history", notifyHandler.GetHistory)
+	router.GET("/notify/:id/attempts", notifyHandler.GetAttempts)
+	router.POST("/recipients", recipientHandler.SaveRecipient)
+	router.GET("/recipients/:user_id", recipientHandler.GetRecipient)
+	router.DELETE("/recipients/:user_id", recipientHandler.DeleteRecipient)
 	router.GET("/metrics", notifyHandler.Metrics)
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
 	return router
 }
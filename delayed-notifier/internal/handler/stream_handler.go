@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/gorilla/websocket"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	streamHeartbeatPeriod = 15 * time.Second
+	wsWriteTimeout        = 10 * time.Second
+)
+
+// streamIDPattern matches a Redis stream entry ID: "<ms>-<seq>" or "<ms>".
+var streamIDPattern = regexp.MustCompile(`^[0-9]{1,20}(-[0-9]{1,20})?$`)
+
+type StreamHandler struct {
+	eventService ports.EventStreamService
+	upgrader     websocket.Upgrader
+}
+
+func NewStreamHandler(eventService ports.EventStreamService) *StreamHandler {
+	return &StreamHandler{
+		eventService: eventService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+// StreamSSE streams notification events as Server-Sent Events.
+// Filters: ?channel=email,telegram&status=sent. Resumes from the Last-Event-ID header
+// (sent by EventSource on reconnect) or ?last_event_id=.
+func (h *StreamHandler) StreamSSE(c *ginext.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	if err := validateLastEventID(lastEventID); err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	filter, err := eventFilterFromQuery(c)
+	if err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	events, err := h.eventService.Subscribe(ctx, filter, lastEventID)
+	if err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx must not buffer the stream
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				zlog.Logger.Error().Err(err).Msg("failed to marshal event for SSE")
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// StreamWebSocket streams notification events as JSON text frames.
+// Accepts the same filters as StreamSSE, resumes from ?last_event_id=.
+func (h *StreamHandler) StreamWebSocket(c *ginext.Context) {
+	lastEventID := c.Query("last_event_id")
+	if err := validateLastEventID(lastEventID); err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	filter, err := eventFilterFromQuery(c)
+	if err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	events, err := h.eventService.Subscribe(ctx, filter, lastEventID)
+	if err != nil {
+		abortWithProblem(c, err)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// upgrader has already written the error response
+		zlog.Logger.Warn().Err(err).Msg("websocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	// reader: we don't expect messages, but need to process control frames and notice disconnects
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				_ = conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "resume with last_event_id"),
+					time.Now().Add(wsWriteTimeout),
+				)
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func eventFilterFromQuery(c *ginext.Context) (model.EventFilter, error) {
+	channels := splitQueryList(c.QueryArray("channel"))
+	for _, channel := range channels {
+		if _, err := domain.NotificationChannelFromString(channel); err != nil {
+			return model.EventFilter{}, apperrors.Validation(apperrors.CodeValidationFailed, fmt.Sprintf("invalid channel filter '%s'", channel), err)
+		}
+	}
+
+	statuses := splitQueryList(c.QueryArray("status"))
+	for _, status := range statuses {
+		if !model.IsKnownStatus(status) {
+			return model.EventFilter{}, apperrors.Validation(apperrors.CodeValidationFailed, fmt.Sprintf("invalid status filter '%s'", status), nil)
+		}
+	}
+
+	return model.NewEventFilter(channels, statuses), nil
+}
+
+// validateLastEventID rejects a resume ID that is not a stream entry ID, otherwise Redis
+// would fail the read and the client would get 503 instead of 400.
+func validateLastEventID(id string) error {
+	if id != "" && !streamIDPattern.MatchString(id) {
+		return apperrors.Validation(apperrors.CodeValidationFailed, fmt.Sprintf("invalid last event id '%s'", id), nil)
+	}
+	return nil
+}
+
+// splitQueryList supports both ?a=x&a=y and ?a=x,y.
+func splitQueryList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
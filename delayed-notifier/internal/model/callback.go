@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// Callback statuses.
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// Callback is one outgoing status-change event for the notification creator.
+type Callback struct {
+	ID             *types.UUID        `json:"id" db:"id"`
+	NotificationID *types.UUID        `json:"notification_id" db:"notification_id"`
+	EventID        string             `json:"event_id" db:"event_id"`
+	EventType      string             `json:"event_type" db:"event_type"`
+	URL            string             `json:"url" db:"url"`
+	Payload        []byte             `json:"-" db:"payload"`
+	Status         string             `json:"status" db:"status"`     // pending / delivered / failed
+	Attempts       int                `json:"attempts" db:"attempts"` // количество попыток
+	NextAttemptAt  time.Time          `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+	History        []*CallbackAttempt `json:"history" db:"-"`
+}
+
+type CallbackAttempt struct {
+	Attempt     int           `json:"attempt" db:"attempt"`
+	StatusCode  *int          `json:"status_code,omitempty" db:"status_code"` // NULL, если запрос не дошел
+	Error       *string       `json:"error,omitempty" db:"error"`
+	Duration    time.Duration `json:"duration" db:"duration_ms"`
+	AttemptedAt time.Time     `json:"attempted_at" db:"attempted_at"`
+}
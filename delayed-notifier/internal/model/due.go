@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// DueNotification comes back from the broker's delayed exchange when the delay of a
+// notification published with the broker strategy expires.
+type DueNotification struct {
+	ID          *types.UUID
+	ScheduledAt time.Time // время, на которое уведомление было поставлено в брокер
+}
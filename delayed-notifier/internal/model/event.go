@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// Notification lifecycle event types.
+const (
+	EventCreated     = "created"
+	EventRescheduled = "rescheduled" // публикация не удалась, уведомление будет отправлено повторно
+	EventDispatched  = "dispatched"
+	EventRerouted    = "rerouted" // доставка на текущую цель не удалась, уведомление уходит на следующую цель цепочки
+	EventDelivered   = "delivered"
+	EventFailed      = "failed"
+	EventCancelled   = "cancelled"
+	EventDeleted     = "deleted"
+)
+
+// EventReset is sent to a stream client instead of the missed events when more than the
+// backlog limit of them were missed: the client must reload the notifications it shows.
+// It has no id, so a reconnecting client doesn't ask for the same gap again.
+const EventReset = "reset"
+
+type NotificationEvent struct {
+	ID             string    `json:"id"`              // id события в потоке (используется как Last-Event-ID)
+	Type           string    `json:"type"`            // created / rescheduled / dispatched / rerouted / delivered / failed / cancelled / deleted
+	NotificationID string    `json:"notification_id"` // id уведомления
+	Channel        string    `json:"channel"`         // email, telegram
+	Status         string    `json:"status"`          // статус уведомления после события
+	Error          string    `json:"error,omitempty"` // текст ошибки для failed и rerouted
+	Actor          string    `json:"actor"`           // кто вызвал переход
+	At             time.Time `json:"at"`              // время события
+}
+
+// NewNotificationEvent builds an event of the given type for notify.
+func NewNotificationEvent(eventType string, notify *Notification, status string) *NotificationEvent {
+	return &NotificationEvent{
+		Type:           eventType,
+		NotificationID: notify.ID.String(),
+		Channel:        notify.Channel.String(),
+		Status:         status,
+		At:             time.Now().UTC(),
+	}
+}
+
+// EventFilter selects events by channel and status. Empty sets match everything.
+type EventFilter struct {
+	Channels map[string]struct{}
+	Statuses map[string]struct{}
+}
+
+func NewEventFilter(channels []string, statuses []string) EventFilter {
+	return EventFilter{
+		Channels: toSet(channels),
+		Statuses: toSet(statuses),
+	}
+}
+
+func (f EventFilter) Match(event *NotificationEvent) bool {
+	if len(f.Channels) > 0 {
+		if _, ok := f.Channels[event.Channel]; !ok {
+			return false
+		}
+	}
+	if len(f.Statuses) > 0 {
+		if _, ok := f.Statuses[event.Status]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if v != "" {
+			set[v] = struct{}{}
+		}
+	}
+	return set
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// HistoryEntry is one row of the notification audit log.
+type HistoryEntry struct {
+	ID             int64
+	NotificationID *types.UUID
+	Type           string // created / rescheduled / dispatched / rerouted / delivered / failed / cancelled / deleted
+	Status         string // статус уведомления после события
+	Actor          string
+	Details        *string
+	StreamEventID  *string // только у старых записей, журнал теперь пишется до публикации события
+	At             time.Time
+}
@@ -3,17 +3,43 @@ package model
 import (
 	"time"
 
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/internaltypes"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 )
 
 type Notification struct {
 	ID          *types.UUID                            `json:"id" db:"id"`                           // PRIMARY KEY,
-	Recipient   internaltypes.Recipient              `json:"recipient" db:"recipient"`             // email, telegram id и т.д.
-	Channel     internaltypes.NotificationChannel `json:"channel" db:"channel"`                 // email, telegram
+	Recipient   domain.Recipient              `json:"recipient" db:"recipient"`             // email, telegram id и т.д.
+	Channel     domain.NotificationChannel `json:"channel" db:"channel"`                 // email, telegram
 	Message     string                            `json:"message" db:"message"`                 // текст уведомления
 	ScheduledAt time.Time                         `json:"scheduled_at" db:"scheduled_at"`       // время отправки
-	Status      string                            `json:"status" db:"status"`                   // pending / sent / cancelled / failed
+	Status      string                            `json:"status" db:"status"`                   // pending / sent / delivered / cancelled / failed
 	Tries       int                               `json:"tries" db:"tries"`                     // количество попыток отправки
 	LastError   *string                           `json:"last_error,omitempty" db:"last_error"` // текст последней ошибки (может быть NULL)
-}
\ No newline at end of file
+	CallbackURL string                            `json:"callback_url,omitempty" db:"callback_url"` // куда отправлять события о смене статуса (может быть пустым)
+	DeletedAt   *time.Time                        `json:"deleted_at,omitempty" db:"deleted_at"`     // время мягкого удаления (NULL — не удалено)
+	TraceParent string                            `json:"-" db:"trace_parent"`                      // W3C traceparent запроса на создание, продолжает трассу при публикации
+	RequestID   string                            `json:"-" db:"request_id"`                        // X-Request-ID запроса на создание, воркер пишет его в логи
+	Targets     []Target                          `json:"targets,omitempty" db:"targets"`           // цепочка целей (может быть пустой), channel/recipient — текущая из них
+	TargetIndex int                               `json:"target_index,omitempty" db:"target_index"` // номер текущей цели в targets
+
+	domain.Content // subject, body_html, attachments, metadata: необязательные, набор зависит от канала
+	Audience       // user_id, category, channels: адресат из справочника получателей вместо recipient
+}
+
+// Notification statuses.
+const (
+	StatusPending   = "pending"
+	StatusSent      = "sent" // опубликовано в RabbitMQ
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+func IsKnownStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusSent, StatusDelivered, StatusCancelled, StatusFailed:
+		return true
+	}
+	return false
+}
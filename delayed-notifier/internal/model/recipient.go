@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// RecipientProfile is a user of the recipient directory: where they can be reached and
+// what they agreed to receive.
+type RecipientProfile struct {
+	UserID      string       `json:"user_id"`
+	Contacts    []Contact    `json:"contacts"`    // в порядке предпочтения, по одному на канал
+	Preferences []Preference `json:"preferences"` // подписки и отписки
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Contact is the address of the user on one channel.
+type Contact struct {
+	Channel string `json:"channel"`
+	Address string `json:"address"` // email, telegram id, телефон и т.д.
+}
+
+// Preference opts the user in or out of a channel, a category or a category on a
+// channel; an empty field matches any value.
+type Preference struct {
+	Channel  string `json:"channel,omitempty"`
+	Category string `json:"category,omitempty"`
+	OptedIn  bool   `json:"opted_in"`
+}
+
+// Contact returns the address of the user on channel.
+func (p *RecipientProfile) Contact(channel string) (string, bool) {
+	for _, c := range p.Contacts {
+		if c.Channel == channel {
+			return c.Address, true
+		}
+	}
+	return "", false
+}
+
+// OptedIn tells whether the user receives notifications of category on channel. The
+// most specific preference wins: channel and category, then category, then channel;
+// without one the user is opted in.
+func (p *RecipientProfile) OptedIn(channel, category string) bool {
+	optedIn, best := true, 0
+	for _, pref := range p.Preferences {
+		if (pref.Channel != "" && pref.Channel != channel) || (pref.Category != "" && pref.Category != category) {
+			continue
+		}
+		rank := 1 // любой канал, любая категория
+		switch {
+		case pref.Channel != "" && pref.Category != "":
+			rank = 4
+		case pref.Category != "":
+			rank = 3
+		case pref.Channel != "":
+			rank = 2
+		}
+		if rank >= best {
+			optedIn, best = pref.OptedIn, rank
+		}
+	}
+	return optedIn
+}
+
+// Audience addresses a notification to a user of the directory instead of a fixed
+// recipient; the channel and the recipient are resolved when it is sent.
+type Audience struct {
+	UserID   string   `json:"user_id,omitempty"`
+	Category string   `json:"category,omitempty"` // marketing, security и т.д., по ней проверяются отписки
+	Channels []string `json:"channels,omitempty"` // порядок выбора канала, пусто — порядок контактов пользователя
+}
@@ -0,0 +1,10 @@
+package model
+
+// ArchivedBatch counts the rows one retention batch moved into the archive. Rows of
+// the other tables go into the archived notification they belong to.
+type ArchivedBatch struct {
+	Notifications    int64
+	Events           int64 // журнал notification_events
+	DeliveryAttempts int64
+	Callbacks        int64 // вместе с callback_attempts
+}
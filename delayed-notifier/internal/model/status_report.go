@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// StatusReport is sent back by the worker after it tried to deliver a notification.
+type StatusReport struct {
+	ID     *types.UUID
+	Status string // delivered / failed
+	Error  *string
+	At     time.Time
+
+	Unreachable bool // получатель больше не может получать уведомления канала
+
+	Channel   string // цель, на которую воркер доставлял; пусто у воркеров, которые ее не сообщают
+	Recipient string
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Target is one (channel, recipient) of a fallback chain.
+type Target struct {
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+}
+
+// DeliveryAttempt is the outcome of delivering a notification to one target, as
+// reported by the worker.
+type DeliveryAttempt struct {
+	TargetIndex int       `json:"target_index"` // номер цели в цепочке, 0 — без цепочки
+	Channel     string    `json:"channel"`
+	Recipient   string    `json:"recipient"`
+	Status      string    `json:"status"` // delivered / failed
+	Error       *string   `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+type AuditRepository interface {
+	GetHistory(ctx context.Context, notificationID types.UUID) ([]*model.HistoryEntry, error)
+}
+
+type HistoryServiceInterface interface {
+	GetHistory(ctx context.Context, notificationID types.UUID) ([]*model.HistoryEntry, error)
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+type CallbackRepository interface {
+	CreateCallback(ctx context.Context, cb *model.Callback) error
+	FetchDueCallbacks(ctx context.Context, limit int, lease time.Duration) ([]*model.Callback, error)
+	RecordAttempt(ctx context.Context, cb *model.Callback, attempt *model.CallbackAttempt) error
+	GetCallbacks(ctx context.Context, notificationID types.UUID) ([]*model.Callback, error)
+}
+
+type CallbackServiceInterface interface {
+	GetCallbacks(ctx context.Context, notificationID types.UUID) ([]*model.Callback, error)
+}
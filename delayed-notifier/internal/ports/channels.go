@@ -0,0 +1,8 @@
+package ports
+
+import "context"
+
+// ChannelRegistry knows which notification channels have a worker consuming them.
+type ChannelRegistry interface {
+	Available(ctx context.Context, channel string) (bool, error)
+}
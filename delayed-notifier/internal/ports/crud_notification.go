@@ -5,15 +5,16 @@ import (
 	"time"
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 )
 
 type CRUDStoreRepositoryInterface interface {
 	CreateNotify(ctx context.Context, notify *model.Notification) error
 	GetNotify(ctx context.Context, id types.UUID) (*model.Notification, error)
-	FetchFromDb(ctx context.Context, needToSendTime time.Time) ([]*model.Notification, error)
-	DeleteNotification(ctx context.Context, id types.UUID) error
+	FetchFromDb(ctx context.Context, needToSendTime time.Time, enqueuedDueBefore time.Time) ([]*model.Notification, error)
+	DeleteNotification(ctx context.Context, id types.UUID) (string, error)
 	GetAllNotifies(ctx context.Context) ([]*model.Notification, error)
+	GetAttempts(ctx context.Context, id types.UUID) ([]*model.DeliveryAttempt, error)
 }
 
 type CRUDRedisRepositoryInterface interface {
@@ -27,4 +28,5 @@ type CRUDServiceInterface interface {
 	GetNotification(ctx context.Context, id types.UUID) (*model.Notification, error) 
 	DeleteNotification(ctx context.Context, id types.UUID) error
 	GetAllNotifications(ctx context.Context) ([]*model.Notification, error)
+	GetAttempts(ctx context.Context, id types.UUID) ([]*model.DeliveryAttempt, error)
 }
@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *model.NotificationEvent) error
+}
+
+type EventBusRepository interface {
+	EventPublisher
+	// Subscribe streams live events published by any replica until ctx is done.
+	Subscribe(ctx context.Context) (<-chan *model.NotificationEvent, error)
+	// ReadSince returns up to limit stored events that came after lastID.
+	ReadSince(ctx context.Context, lastID string, limit int64) ([]*model.NotificationEvent, error)
+}
+
+type EventStreamService interface {
+	Subscribe(ctx context.Context, filter model.EventFilter, lastEventID string) (<-chan *model.NotificationEvent, error)
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+// LifecycleHooks is notified about every notification status transition.
+type LifecycleHooks interface {
+	Fire(ctx context.Context, event *model.NotificationEvent, notify *model.Notification)
+}
+
+type StatusStoreRepository interface {
+	ApplyReport(ctx context.Context, report *model.StatusReport) (*model.Notification, *model.DeliveryAttempt, error)
+}
+
+// StatusReceiver hands worker delivery reports to handle. A report is acknowledged
+// only when handle returns nil.
+type StatusReceiver interface {
+	Receive(ctx context.Context, handle func(ctx context.Context, report *model.StatusReport) error) error
+}
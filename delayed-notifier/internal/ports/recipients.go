@@ -0,0 +1,39 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+)
+
+// RecipientRegistry remembers recipients a channel can no longer deliver to.
+type RecipientRegistry interface {
+	MarkUnreachable(ctx context.Context, notify *model.Notification, reason string) error
+	IsUnreachable(ctx context.Context, channel, recipient string) (bool, error)
+}
+
+// RecipientDirectory stores the users notifications can be addressed to by user_id.
+type RecipientDirectory interface {
+	SaveProfile(ctx context.Context, profile *model.RecipientProfile) error
+	GetProfile(ctx context.Context, userID string) (*model.RecipientProfile, error)
+	DeleteProfile(ctx context.Context, userID string) error
+}
+
+// InFlightRepository finds notifications addressed to a user that were published to the
+// workers but not delivered yet, and takes them back when the user's directory entry changes.
+type InFlightRepository interface {
+	FetchInFlight(ctx context.Context, userID string) ([]*model.Notification, error)
+	Retract(ctx context.Context, notify *model.Notification, status string, reason string) (bool, error)
+}
+
+// RecipientResolver picks the channel and the recipient of a notification addressed to a user.
+type RecipientResolver interface {
+	Resolve(ctx context.Context, notify *model.Notification) (domain.NotificationChannel, domain.Recipient, error)
+}
+
+type RecipientServiceInterface interface {
+	SaveRecipient(ctx context.Context, profile *model.RecipientProfile) (*model.RecipientProfile, error)
+	GetRecipient(ctx context.Context, userID string) (*model.RecipientProfile, error)
+	DeleteRecipient(ctx context.Context, userID string) error
+}
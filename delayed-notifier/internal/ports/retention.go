@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+type RetentionRepository interface {
+	// EnsurePartition creates the archive partition for the month containing month.
+	EnsurePartition(ctx context.Context, month time.Time) error
+	// ArchiveBatch moves up to limit rows with the status last changed before olderThan
+	// into the archive, together with their audit log, delivery attempts and callbacks,
+	// and returns how many rows of each were moved.
+	ArchiveBatch(ctx context.Context, status string, olderThan time.Time, limit int) (*model.ArchivedBatch, error)
+}
@@ -5,16 +5,43 @@ import (
 	"time"
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/dlq"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/dlq"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 )
 
 type FetcherRepository interface {
-	FetchFromDb(ctx context.Context, needToSendTime time.Time) ([]*model.Notification, error)
+	FetchFromDb(ctx context.Context, needToSendTime time.Time, enqueuedDueBefore time.Time) ([]*model.Notification, error)
 	MarkAsSent(ctx context.Context, ids []*types.UUID) error
+	MarkAsFailed(ctx context.Context, id *types.UUID, reason string) (string, error)
+	AssignRecipient(ctx context.Context, id *types.UUID, channel string, recipient string) error
+	CloseUnresolved(ctx context.Context, id *types.UUID, status string, reason string) (bool, error)
 }
 
 type PublisherRepository interface {
 	SendMany(ctx context.Context, notifications []*model.Notification) *dlq.DLQ[*model.Notification]
 	SendOne(ctx context.Context, notification *model.Notification) error
 }
+
+// DelayedStoreRepository tracks notifications scheduled with the broker strategy.
+type DelayedStoreRepository interface {
+	FetchDue(ctx context.Context, id *types.UUID, scheduledAt time.Time) (*model.Notification, error)
+	MarkAsEnqueued(ctx context.Context, id *types.UUID) error
+	UnmarkEnqueued(ctx context.Context, id *types.UUID) error
+}
+
+// DelayedPublisherRepository publishes a notification so that the broker returns it at scheduled_at.
+type DelayedPublisherRepository interface {
+	SendDelayed(ctx context.Context, notification *model.Notification) error
+}
+
+// CancelPublisherRepository tells the workers to drop a published notification.
+type CancelPublisherRepository interface {
+	SendCancellation(ctx context.Context, notification *model.Notification) error
+	SendRetraction(ctx context.Context, notification *model.Notification) error
+}
+
+// DueReceiver hands notifications whose broker-side delay expired to handle. A message
+// is acknowledged only when handle returns nil.
+type DueReceiver interface {
+	Receive(ctx context.Context, handle func(ctx context.Context, due *model.DueNotification) error) error
+}
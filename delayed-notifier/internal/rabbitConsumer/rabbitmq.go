@@ -0,0 +1,181 @@
+package rabbitconsumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/retry"
+)
+
+// Consumer читает одну очередь: отчеты воркера о доставке или наступившие отложенные уведомления
+type Consumer struct {
+	conn  *amqp091.Connection
+	Chan  *amqp091.Channel
+	Queue string
+}
+
+func NewStatusConsumer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rabbitmqRetryStrategy retry.Strategy) (*Consumer, error) {
+	return newConsumer(ctx, rabbitCfg, rabbitmqRetryStrategy, rabbitCfg.StatusQueue, func(ch *amqp091.Channel) error {
+		// объявляем exchange
+		if err := ch.ExchangeDeclare(
+			rabbitCfg.Exchange,
+			"direct",
+			true,
+			false,
+			false,
+			false,
+			nil,
+		); err != nil {
+			return fmt.Errorf("error declaring exchange: %w", err)
+		}
+		return bindQueue(ctx, ch, rabbitmqRetryStrategy, rabbitCfg.StatusQueue, rabbitCfg.StatusRoutingKey, rabbitCfg.Exchange)
+	})
+}
+
+// DueRoutingKey routes messages of the delayed exchange to the due queue.
+const DueRoutingKey = "due"
+
+// NewDueConsumer declares the delayed exchange of the broker scheduling strategy and
+// the queue it routes notifications to once their delay expires. The exchange type
+// comes from the rabbitmq_delayed_message_exchange plugin, which must be enabled.
+func NewDueConsumer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rabbitmqRetryStrategy retry.Strategy) (*Consumer, error) {
+	return newConsumer(ctx, rabbitCfg, rabbitmqRetryStrategy, rabbitCfg.DueQueue, func(ch *amqp091.Channel) error {
+		if err := ch.ExchangeDeclare(
+			rabbitCfg.DelayedExchange,
+			"x-delayed-message",
+			true,
+			false,
+			false,
+			false,
+			amqp091.Table{"x-delayed-type": "direct"},
+		); err != nil {
+			return fmt.Errorf("error declaring delayed exchange '%s' (is rabbitmq_delayed_message_exchange enabled?): %w", rabbitCfg.DelayedExchange, err)
+		}
+		return bindQueue(ctx, ch, rabbitmqRetryStrategy, rabbitCfg.DueQueue, DueRoutingKey, rabbitCfg.DelayedExchange)
+	})
+}
+
+func newConsumer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rabbitmqRetryStrategy retry.Strategy, queue string, declare func(ch *amqp091.Channel) error) (*Consumer, error) {
+	var conn *amqp091.Connection
+	var err error
+
+	// подключаемся с ретраями
+	err = retry.DoContext(ctx, rabbitmqRetryStrategy, func() error {
+		conn, err = amqp091.Dial(fmt.Sprintf(
+			"amqp://%s:%s@%s:%d/%s",
+			rabbitCfg.User,
+			rabbitCfg.Password,
+			rabbitCfg.Host,
+			rabbitCfg.Port,
+			rabbitCfg.VHost,
+		))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to rabbitmq: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error creating channel: %w", err)
+	}
+
+	if err := declare(ch); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// не берем больше, чем успеваем обработать, остальное достанется другим репликам
+	if err := ch.Qos(64, 0, false); err != nil {
+		return nil, fmt.Errorf("error setting qos: %w", err)
+	}
+
+	return &Consumer{
+		conn:  conn,
+		Chan:  ch,
+		Queue: queue,
+	}, nil
+}
+
+func bindQueue(ctx context.Context, ch *amqp091.Channel, rabbitmqRetryStrategy retry.Strategy, queue, routingKey, exchange string) error {
+	err := retry.DoContext(ctx, rabbitmqRetryStrategy, func() error {
+		_, errQ := ch.QueueDeclare(queue, // имя очереди
+			true,  // durable
+			false, // autoDelete
+			false, // exclusive
+			false, // noWait
+			nil,   // args
+		)
+		if errQ == nil {
+			errQ = ch.QueueBind(queue, routingKey, exchange, false, nil)
+		}
+		return errQ
+	})
+	if err != nil {
+		return fmt.Errorf("error declaring queue '%s': %w", queue, err)
+	}
+	return nil
+}
+
+func (c *Consumer) ConsumeWithRetry(ctx context.Context, out chan<- amqp091.Delivery, retryStrategy retry.Strategy) error {
+	var deliveries <-chan amqp091.Delivery
+
+	err := retry.DoContext(ctx, retryStrategy, func() error {
+		var err error
+		deliveries, err = c.Chan.Consume(
+			c.Queue, // имя очереди
+			"",      // consumer — RabbitMQ сгенерирует уникальный тег
+			false,   // autoAck
+			false,   // exclusive
+			false,   // noLocal
+			false,   // noWait
+			nil,     // args
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("deliveries channel closed")
+			}
+			select {
+			case out <- m:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Check reports whether the consumer is still connected, used by /readyz.
+func (c *Consumer) Check(ctx context.Context) error {
+	if c.conn == nil || c.conn.IsClosed() {
+		return fmt.Errorf("rabbitmq connection is closed")
+	}
+	if c.Chan == nil || c.Chan.IsClosed() {
+		return fmt.Errorf("rabbitmq channel is closed")
+	}
+	return nil
+}
+
+// Close закрывает канал и соединение
+func (c *Consumer) Close() error {
+	if c.Chan != nil {
+		if err := c.Chan.Close(); err != nil {
+			return err
+		}
+	}
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
@@ -2,17 +2,37 @@ package rabbitpublisher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/requestid"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/retry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitProducer")
+
+// amqpChannel is the part of *amqp091.Channel the publisher uses.
+type amqpChannel interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error
+	IsClosed() bool
+	Close() error
+}
+
 type Publisher struct {
 	conn      *amqp091.Connection
-	channel   *amqp091.Channel
+	channel   amqpChannel
 	exchange  string
+	delayedExchange string // x-delayed-message exchange стратегии broker
+	cancelExchange  string // fanout exchange отмен, его слушает каждый воркер
 	contentType string
 	retryStrategy retry.Strategy
 }
@@ -56,6 +76,19 @@ func NewRabbitProducer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rab
 		return nil, fmt.Errorf("error declaring exchange: %w", err)
 	}
 
+	// отмены получает каждый воркер: уведомление может ждать в куче любого из них
+	if err := ch.ExchangeDeclare(
+		rabbitCfg.CancelExchange,
+		"fanout",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return nil, fmt.Errorf("error declaring cancel exchange: %w", err)
+	}
+
 	// // биндим очередь к exchange (routing key можно оставить пустым для direct)
 	// if err := ch.QueueBind(
 	// 	rabbitCfg.Queue,
@@ -71,18 +104,96 @@ func NewRabbitProducer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rab
 		conn:       conn,
 		channel:    ch,
 		exchange:   rabbitCfg.Exchange,
+		delayedExchange: rabbitCfg.DelayedExchange,
+		cancelExchange: rabbitCfg.CancelExchange,
 		contentType: "application/json",
 		retryStrategy: rabbitmqRetryStrategy,
 	}, nil
 }
-// PublishWithRetry публикует сообщение с ретраями
-func (p *Publisher) PublishWithRetry(ctx context.Context, body []byte, routingKey string) error {
-	return retry.DoContext(ctx, p.retryStrategy, func() error {
-		return p.channel.PublishWithContext(ctx, p.exchange, routingKey, false, false, amqp091.Publishing{
-			ContentType: p.contentType,
-			Body:        body,
+// PublishWithRetry публикует сообщение с ретраями. messageID и request id из контекста
+// уходят в свойства сообщения (MessageId, CorrelationId), воркер пишет их в логи.
+// Тело — уведомление текущей версии схемы wire, версия дублируется в заголовке.
+func (p *Publisher) PublishWithRetry(ctx context.Context, body []byte, routingKey string, messageID string) error {
+	headers := amqp091.Table{wire.HeaderSchemaVersion: int32(wire.CurrentSchemaVersion)}
+	return p.publish(ctx, p.exchange, routingKey, body, messageID, wire.ContentType, headers)
+}
+
+// PublishDelayedWithRetry публикует сообщение в отложенный exchange: брокер направит его
+// по routingKey только через delay.
+func (p *Publisher) PublishDelayedWithRetry(ctx context.Context, body []byte, routingKey string, delay time.Duration, messageID string) error {
+	return p.publish(ctx, p.delayedExchange, routingKey, body, messageID, p.contentType, amqp091.Table{"x-delay": delay.Milliseconds()})
+}
+
+// PublishCancelWithRetry публикует отмену уже опубликованного уведомления в fanout exchange отмен.
+func (p *Publisher) PublishCancelWithRetry(ctx context.Context, body []byte, messageID string) error {
+	return p.publish(ctx, p.cancelExchange, "", body, messageID, wire.CancelContentType, nil)
+}
+
+func (p *Publisher) publish(ctx context.Context, exchange string, routingKey string, body []byte, messageID string, contentType string, headers amqp091.Table) error {
+	ctx, span := tracer.Start(ctx, "publish "+routingKey,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+			semconv.MessagingMessageBodySize(len(body)),
+			semconv.MessagingMessageID(messageID),
+		),
+	)
+	defer span.End()
+
+	// воркер продолжит трассу из заголовков сообщения
+	headers = tracing.InjectAMQP(ctx, headers)
+
+	err := retry.DoContext(ctx, p.retryStrategy, func() error {
+		return p.channel.PublishWithContext(ctx, exchange, routingKey, false, false, amqp091.Publishing{
+			ContentType:   contentType,
+			Headers:       headers,
+			MessageId:     messageID,
+			CorrelationId: requestid.FromContext(ctx),
+			Body:          body,
 		})
 	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// QueueExists reports whether queue has been declared on the broker. A passive declare
+// closes the channel it fails on, so every check gets a throwaway channel.
+func (p *Publisher) QueueExists(queue string) (bool, error) {
+	if p == nil || p.conn == nil {
+		return false, fmt.Errorf("rabbitmq connection is not established")
+	}
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return false, fmt.Errorf("error creating channel: %w", err)
+	}
+	defer ch.Close()
+
+	_, err = ch.QueueDeclarePassive(queue, true, false, false, false, nil)
+	var amqpErr *amqp091.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking queue '%s': %w", queue, err)
+	}
+	return true, nil
+}
+
+// Check reports whether the publisher can still publish, used by /readyz.
+func (p *Publisher) Check(ctx context.Context) error {
+	if p == nil || p.conn == nil || p.conn.IsClosed() {
+		return fmt.Errorf("rabbitmq connection is closed")
+	}
+	if p.channel == nil || p.channel.IsClosed() {
+		return fmt.Errorf("rabbitmq channel is closed")
+	}
+	return nil
 }
 
 // Close закрывает канал и соединение
@@ -0,0 +1,79 @@
+package rabbitpublisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/retry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingChannel keeps published messages instead of sending them to a broker.
+type recordingChannel struct {
+	published []amqp091.Publishing
+}
+
+func (c *recordingChannel) PublishWithContext(_ context.Context, _, _ string, _, _ bool, msg amqp091.Publishing) error {
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func (c *recordingChannel) IsClosed() bool { return false }
+
+func (c *recordingChannel) Close() error { return nil }
+
+func TestPublishInjectsTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	channel := &recordingChannel{}
+	publisher := &Publisher{
+		channel:       channel,
+		exchange:      "notifications",
+		retryStrategy: retry.Strategy{Attempts: 1},
+	}
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "POST /notify")
+	if err := publisher.PublishWithRetry(ctx, []byte("{}"), "email", "7f1c"); err != nil {
+		t.Fatalf("PublishWithRetry: %v", err)
+	}
+	request.End()
+
+	if len(channel.published) != 1 {
+		t.Fatalf("published %d messages, want 1", len(channel.published))
+	}
+	headers := channel.published[0].Headers
+	if headers[wire.HeaderSchemaVersion] != int32(wire.CurrentSchemaVersion) {
+		t.Errorf("schema version header lost: %v", headers)
+	}
+
+	// спан публикации — ребенок спана запроса, а заголовки несут именно его
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	publish, parent := spans[0], spans[1]
+	if publish.SpanKind != trace.SpanKindProducer {
+		t.Errorf("publish span kind %s, want producer", publish.SpanKind)
+	}
+	if publish.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("publish parent %s, want request span %s", publish.Parent.SpanID(), parent.SpanContext.SpanID())
+	}
+
+	// так воркер восстановит родителя из заголовков
+	remote := trace.SpanContextFromContext(tracing.ExtractAMQP(context.Background(), headers))
+	if remote.TraceID() != publish.SpanContext.TraceID() || remote.SpanID() != publish.SpanContext.SpanID() {
+		t.Errorf("headers carry %s/%s, want publish span %s/%s",
+			remote.TraceID(), remote.SpanID(), publish.SpanContext.TraceID(), publish.SpanContext.SpanID())
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+)
+
+// audienceColumns holds model.Audience as the user_id, category and
+// channel_preferences columns; empty fields are NULL.
+type audienceColumns struct {
+	userID   *string
+	category *string
+	channels *string
+}
+
+func newAudienceColumns(audience model.Audience) (audienceColumns, error) {
+	columns := audienceColumns{
+		userID:   nullString(audience.UserID),
+		category: nullString(audience.Category),
+	}
+	if len(audience.Channels) > 0 {
+		data, err := json.Marshal(audience.Channels)
+		if err != nil {
+			return audienceColumns{}, fmt.Errorf("couldn't marshal channel preferences: %w", err)
+		}
+		columns.channels = nullString(string(data))
+	}
+	return columns, nil
+}
+
+func (c *audienceColumns) audience() (model.Audience, error) {
+	audience := model.Audience{
+		UserID:   stringOrEmpty(c.userID),
+		Category: stringOrEmpty(c.category),
+	}
+	if c.channels != nil {
+		if err := json.Unmarshal([]byte(*c.channels), &audience.Channels); err != nil {
+			return model.Audience{}, fmt.Errorf("invalid channel preferences in postgres: %w", err)
+		}
+	}
+	return audience, nil
+}
+
+// parseChannel reads the channel column, which stays empty until the recipient of a
+// notification addressed to a user is resolved.
+func parseChannel(channel string) (domain.NotificationChannel, error) {
+	if channel == "" {
+		return domain.NotificationChannel{}, nil
+	}
+	return domain.NotificationChannelFromString(channel)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+type AuditRepository struct {
+	db       *dbpg.DB
+	strategy retry.Strategy
+}
+
+func NewAuditRepository(db *dbpg.DB, strategy retry.Strategy) *AuditRepository {
+	return &AuditRepository{
+		db:       db,
+		strategy: strategy,
+	}
+}
+
+// GetHistory returns the notification timeline, oldest first.
+func (r *AuditRepository) GetHistory(ctx context.Context, notificationID types.UUID) ([]*model.HistoryEntry, error) {
+	query := `SELECT id, event_type, status, actor, details, stream_event_id, created_at
+		FROM notifier_db.public.notification_events
+		WHERE notification_id = $1
+		ORDER BY id`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, notificationID.String())
+	if err != nil {
+		return nil, postgresError(err, "get history")
+	}
+	defer rows.Close()
+
+	result := []*model.HistoryEntry{}
+	for rows.Next() {
+		entry := &model.HistoryEntry{NotificationID: &notificationID}
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Type,
+			&entry.Status,
+			&entry.Actor,
+			&entry.Details,
+			&entry.StreamEventID,
+			&entry.At,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+		result = append(result, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error in GetHistory: %w", err)
+	}
+	return result, nil
+}
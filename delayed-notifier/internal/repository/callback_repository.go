@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+type CallbackRepository struct {
+	db       *dbpg.DB
+	strategy retry.Strategy
+}
+
+func NewCallbackRepository(db *dbpg.DB, strategy retry.Strategy) *CallbackRepository {
+	return &CallbackRepository{
+		db:       db,
+		strategy: strategy,
+	}
+}
+
+func (r *CallbackRepository) CreateCallback(ctx context.Context, cb *model.Callback) error {
+	query := `INSERT INTO notifier_db.public.callbacks (id, notification_id, event_id, event_type, url, payload, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecWithRetry(
+		ctx,
+		r.strategy,
+		query,
+		cb.ID.String(),
+		cb.NotificationID.String(),
+		cb.EventID,
+		cb.EventType,
+		cb.URL,
+		cb.Payload,
+		cb.NextAttemptAt,
+	)
+	if err != nil {
+		return postgresError(err, "create callback")
+	}
+	return nil
+}
+
+// FetchDueCallbacks claims up to limit pending callbacks whose time has come.
+// Claimed rows are pushed forward by lease so other replicas skip them while
+// this one is delivering; RecordAttempt sets the real next attempt time.
+func (r *CallbackRepository) FetchDueCallbacks(ctx context.Context, limit int, lease time.Duration) ([]*model.Callback, error) {
+	query := `
+	UPDATE notifier_db.public.callbacks
+	SET next_attempt_at = now() + $2 * interval '1 millisecond'
+	WHERE id IN (
+		SELECT id FROM notifier_db.public.callbacks
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, notification_id, event_id, event_type, url, payload, status, attempts, next_attempt_at, created_at`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, postgresError(err, "fetch callbacks")
+	}
+	defer rows.Close()
+
+	result := []*model.Callback{}
+	for rows.Next() {
+		var (
+			id             string
+			notificationID string
+			cb             model.Callback
+		)
+		if err := rows.Scan(
+			&id,
+			&notificationID,
+			&cb.EventID,
+			&cb.EventType,
+			&cb.URL,
+			&cb.Payload,
+			&cb.Status,
+			&cb.Attempts,
+			&cb.NextAttemptAt,
+			&cb.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan callback: %w", err)
+		}
+		if cb.ID, cb.NotificationID, err = parseCallbackIDs(id, notificationID); err != nil {
+			return nil, err
+		}
+		result = append(result, &cb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after scanning callbacks: %w", err)
+	}
+	return result, nil
+}
+
+// RecordAttempt stores the attempt and moves the callback to its new state in one statement.
+func (r *CallbackRepository) RecordAttempt(ctx context.Context, cb *model.Callback, attempt *model.CallbackAttempt) error {
+	query := `
+	WITH attempt AS (
+		INSERT INTO notifier_db.public.callback_attempts (callback_id, attempt, status_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	)
+	UPDATE notifier_db.public.callbacks
+	SET status = $7,
+		attempts = $2,
+		next_attempt_at = $8,
+		updated_at = now()
+	WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(
+		ctx,
+		r.strategy,
+		query,
+		cb.ID.String(),
+		attempt.Attempt,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Duration.Milliseconds(),
+		attempt.AttemptedAt,
+		cb.Status,
+		cb.NextAttemptAt,
+	)
+	if err != nil {
+		return postgresError(err, "record callback attempt")
+	}
+	return nil
+}
+
+func (r *CallbackRepository) GetCallbacks(ctx context.Context, notificationID types.UUID) ([]*model.Callback, error) {
+	query := `
+	SELECT c.id, c.notification_id, c.event_id, c.event_type, c.url, c.status, c.attempts, c.next_attempt_at, c.created_at,
+		a.attempt, a.status_code, a.error, a.duration_ms, a.attempted_at
+	FROM notifier_db.public.callbacks c
+	LEFT JOIN notifier_db.public.callback_attempts a ON a.callback_id = c.id
+	WHERE c.notification_id = $1
+	ORDER BY c.created_at, a.attempt`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, notificationID.String())
+	if err != nil {
+		return nil, postgresError(err, "get callbacks")
+	}
+	defer rows.Close()
+
+	result := []*model.Callback{}
+	byID := map[string]*model.Callback{}
+	for rows.Next() {
+		var (
+			id          string
+			notifyID    string
+			cb          model.Callback
+			attemptNum  *int
+			statusCode  *int
+			attemptErr  *string
+			durationMs  *int64
+			attemptedAt *time.Time
+		)
+		if err := rows.Scan(
+			&id,
+			&notifyID,
+			&cb.EventID,
+			&cb.EventType,
+			&cb.URL,
+			&cb.Status,
+			&cb.Attempts,
+			&cb.NextAttemptAt,
+			&cb.CreatedAt,
+			&attemptNum,
+			&statusCode,
+			&attemptErr,
+			&durationMs,
+			&attemptedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan callback: %w", err)
+		}
+
+		current, ok := byID[id]
+		if !ok {
+			if cb.ID, cb.NotificationID, err = parseCallbackIDs(id, notifyID); err != nil {
+				return nil, err
+			}
+			cb.History = []*model.CallbackAttempt{}
+			current = &cb
+			byID[id] = current
+			result = append(result, current)
+		}
+
+		if attemptNum != nil {
+			current.History = append(current.History, &model.CallbackAttempt{
+				Attempt:     *attemptNum,
+				StatusCode:  statusCode,
+				Error:       attemptErr,
+				Duration:    time.Duration(*durationMs) * time.Millisecond,
+				AttemptedAt: *attemptedAt,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after scanning callbacks: %w", err)
+	}
+	return result, nil
+}
+
+func parseCallbackIDs(id string, notificationID string) (*types.UUID, *types.UUID, error) {
+	cbID, err := types.NewUUID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid callback uuid in postgres: %w", err)
+	}
+	notifyID, err := types.NewUUID(notificationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid notification uuid in postgres: %w", err)
+	}
+	return &cbID, &notifyID, nil
+}
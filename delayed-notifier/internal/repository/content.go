@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+)
+
+// contentColumns holds domain.Content as the subject, body_html, attachments and
+// metadata columns; empty fields are NULL, the JSONB ones travel as text.
+type contentColumns struct {
+	subject     *string
+	bodyHTML    *string
+	attachments *string
+	metadata    *string
+}
+
+func newContentColumns(content domain.Content) (contentColumns, error) {
+	columns := contentColumns{
+		subject:  nullString(content.Subject),
+		bodyHTML: nullString(content.BodyHTML),
+	}
+	if len(content.Attachments) > 0 {
+		data, err := json.Marshal(content.Attachments)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("couldn't marshal attachments: %w", err)
+		}
+		columns.attachments = nullString(string(data))
+	}
+	if len(content.Metadata) > 0 {
+		data, err := json.Marshal(content.Metadata)
+		if err != nil {
+			return contentColumns{}, fmt.Errorf("couldn't marshal metadata: %w", err)
+		}
+		columns.metadata = nullString(string(data))
+	}
+	return columns, nil
+}
+
+func (c *contentColumns) content() (domain.Content, error) {
+	content := domain.Content{
+		Subject:  stringOrEmpty(c.subject),
+		BodyHTML: stringOrEmpty(c.bodyHTML),
+	}
+	if c.attachments != nil {
+		if err := json.Unmarshal([]byte(*c.attachments), &content.Attachments); err != nil {
+			return domain.Content{}, fmt.Errorf("invalid attachments in postgres: %w", err)
+		}
+	}
+	if c.metadata != nil {
+		if err := json.Unmarshal([]byte(*c.metadata), &content.Metadata); err != nil {
+			return domain.Content{}, fmt.Errorf("invalid metadata in postgres: %w", err)
+		}
+	}
+	return content, nil
+}
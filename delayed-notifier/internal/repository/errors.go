@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/redis"
+)
+
+const pqUniqueViolation = "23505"
+
+// postgresError maps a postgres driver error to a domain error.
+// Errors that can't be classified are returned wrapped as is.
+func postgresError(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.NotFound(apperrors.CodeNotFound, "notification not found", err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == pqUniqueViolation:
+			return apperrors.Conflict(apperrors.CodeAlreadyExists, "notification already exists", err)
+		// class 08 — connection exception, class 57 — operator intervention (shutdown etc.)
+		case strings.HasPrefix(string(pqErr.Code), "08"), strings.HasPrefix(string(pqErr.Code), "57"):
+			return apperrors.Unavailable(apperrors.CodeStorageUnavailable, fmt.Sprintf("postgres unavailable on %s", op), err)
+		}
+		return fmt.Errorf("postgres error on %s: %w", op, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return apperrors.Unavailable(apperrors.CodeStorageUnavailable, fmt.Sprintf("postgres unavailable on %s", op), err)
+	}
+
+	return fmt.Errorf("postgres error on %s: %w", op, err)
+}
+
+// redisError maps a redis client error to a domain error.
+func redisError(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, redis.NoMatches) {
+		return apperrors.NotFound(apperrors.CodeNotFound, "notification not found in cache", err)
+	}
+	return apperrors.Unavailable(apperrors.CodeCacheUnavailable, fmt.Sprintf("redis unavailable on %s", op), err)
+}
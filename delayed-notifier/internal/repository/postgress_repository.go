@@ -8,12 +8,17 @@ import (
 	"strings"
 	"time"
 
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/internaltypes"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/requestid"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 	"github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/retry"
 	"github.com/wb-go/wbf/zlog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type StoreRepository struct {
@@ -28,10 +33,34 @@ func NewRepository(db *dbpg.DB, strategy retry.Strategy) *StoreRepository {
 	}
 }
 
+// CreateNotify inserts the notification together with the 'created' entry of its audit log.
 func (r *StoreRepository) CreateNotify(ctx context.Context, notify *model.Notification) error {
-	query := `INSERT INTO notifier_db.public.notifications (id, recipient, channel, message, scheduled_at)
-		VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecWithRetry(
+	query := `WITH created AS (
+			INSERT INTO notifier_db.public.notifications (id, recipient, channel, message, scheduled_at, callback_url, trace_parent, request_id,
+				subject, body_html, attachments, metadata, user_id, category, channel_preferences, targets)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			RETURNING id, status
+		)
+		INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor)
+		SELECT id, 'created', status, $17 FROM created`
+	// контекст запроса на создание, чтобы публикация позже попала в ту же трассу
+	traceParent := tracing.TraceParent(ctx)
+	ctx, span := startQuerySpan(ctx, "CreateNotify", "INSERT", query)
+	defer span.End()
+
+	content, err := newContentColumns(notify.Content)
+	if err != nil {
+		return failSpan(span, err)
+	}
+	audience, err := newAudienceColumns(notify.Audience)
+	if err != nil {
+		return failSpan(span, err)
+	}
+	targets, err := newTargetsColumn(notify.Targets)
+	if err != nil {
+		return failSpan(span, err)
+	}
+	_, err = r.db.ExecWithRetry(
 		ctx,
 		r.strategy,
 		query,
@@ -40,17 +69,37 @@ func (r *StoreRepository) CreateNotify(ctx context.Context, notify *model.Notifi
 		notify.Channel.String(),
 		notify.Message,
 		notify.ScheduledAt.Format(time.RFC3339),
+		nullString(notify.CallbackURL),
+		nullString(traceParent),
+		nullString(requestid.FromContext(ctx)),
+		content.subject,
+		content.bodyHTML,
+		content.attachments,
+		content.metadata,
+		audience.userID,
+		audience.category,
+		audience.channels,
+		targets,
+		actor.FromContext(ctx),
 	)
 	if err != nil {
-		return err
+		return failSpan(span, postgresError(err, "create"))
 	}
 
+	// уведомление должно совпадать с записью: по нему его сразу могут опубликовать (стратегия broker)
+	notify.ScheduledAt = notify.ScheduledAt.Truncate(time.Second)
+	notify.TraceParent = traceParent
+	notify.RequestID = requestid.FromContext(ctx)
 	return nil
 }
 
 func (r *StoreRepository) GetNotify(ctx context.Context, id types.UUID) (*model.Notification, error) {
-	query := `SELECT * FROM notifier_db.public.notifications
-			  WHERE id = :id`
+	query := `SELECT recipient, channel, message, scheduled_at, status, tries, last_error, callback_url,
+				subject, body_html, attachments, metadata, user_id, category, channel_preferences, targets, target_index
+			  FROM notifier_db.public.notifications
+			  WHERE id = $1 AND deleted_at IS NULL`
+	ctx, span := startQuerySpan(ctx, "GetNotify", "SELECT", query)
+	defer span.End()
 
 	var (
 		recipient   string
@@ -60,11 +109,16 @@ func (r *StoreRepository) GetNotify(ctx context.Context, id types.UUID) (*model.
 		status      string
 		tries       int
 		lastError   *string
+		callbackURL *string
+		content     contentColumns
+		audience    audienceColumns
+		targets     *string
+		targetIndex int
 	)
 
 	rows, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, id.String())
 	if err != nil {
-		return nil, fmt.Errorf("error select by id in postgres: %w", err)
+		return nil, failSpan(span, postgresError(err, "get"))
 	}
 
 	err = rows.Scan(
@@ -75,24 +129,48 @@ func (r *StoreRepository) GetNotify(ctx context.Context, id types.UUID) (*model.
 		&status,
 		&tries,
 		&lastError,
+		&callbackURL,
+		&content.subject,
+		&content.bodyHTML,
+		&content.attachments,
+		&content.metadata,
+		&audience.userID,
+		&audience.category,
+		&audience.channels,
+		&targets,
+		&targetIndex,
 	)
 	if err != nil {
-		return nil,fmt.Errorf("error scan in get: %w", err)
+		return nil, failSpan(span, postgresError(err, "get"))
 	}
 
-	var channelValid internaltypes.NotificationChannel
-	channelValid, err = internaltypes.NotificationChannelFromString(channel)
+	var channelValid domain.NotificationChannel
+	channelValid, err = parseChannel(channel)
 	if err != nil {
 		return nil, fmt.Errorf("invalid channel in postgres: %w", err)
 	}
 
-	var recipientToValid internaltypes.Recipient
-	recipientToValid, err = internaltypes.NewSendTo(types.NewAnyText(recipient), channelValid)
+	var recipientToValid domain.Recipient
+	recipientToValid, err = domain.NewSendTo(types.NewAnyText(recipient), channelValid)
 	if err != nil {
 		return nil, fmt.Errorf("invalid send_to in postgres: %w", err)
 	}
 
+	rich, err := content.content()
+	if err != nil {
+		return nil, err
+	}
+	addressee, err := audience.audience()
+	if err != nil {
+		return nil, err
+	}
+	chain, err := parseTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
 	return &model.Notification{
+		ID:          &id,
 		Recipient:   recipientToValid,
 		Channel:     channelValid,
 		Message:     message,
@@ -100,6 +178,11 @@ func (r *StoreRepository) GetNotify(ctx context.Context, id types.UUID) (*model.
 		Status:      status,
 		Tries:       tries,
 		LastError:   lastError,
+		CallbackURL: stringOrEmpty(callbackURL),
+		Targets:     chain,
+		TargetIndex: targetIndex,
+		Content:     rich,
+		Audience:    addressee,
 	}, nil
 }
 
@@ -112,13 +195,26 @@ func (r *StoreRepository) GetAllNotifies(ctx context.Context) ([]*model.Notifica
                 scheduled_at,
                 status,
                 tries,
-                last_error
+                last_error,
+                callback_url,
+                subject,
+                body_html,
+                attachments,
+                metadata,
+                user_id,
+                category,
+                channel_preferences,
+                targets,
+                target_index
               FROM notifier_db.public.notifications
+              WHERE deleted_at IS NULL
               ORDER BY scheduled_at DESC`
+	ctx, span := startQuerySpan(ctx, "GetAllNotifies", "SELECT", query)
+	defer span.End()
 
 	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query)
 	if err != nil {
-		return nil, fmt.Errorf("error selecting all notifications from postgres: %w", err)
+		return nil, failSpan(span, postgresError(err, "get all"))
 	}
 	defer rows.Close()
 
@@ -134,6 +230,11 @@ func (r *StoreRepository) GetAllNotifies(ctx context.Context) ([]*model.Notifica
 			status      string
 			tries       int
 			lastError   *string
+			callbackURL *string
+			content     contentColumns
+			audience    audienceColumns
+			targets     *string
+			targetIndex int
 		)
 
 		if err := rows.Scan(
@@ -145,17 +246,39 @@ func (r *StoreRepository) GetAllNotifies(ctx context.Context) ([]*model.Notifica
 			&status,
 			&tries,
 			&lastError,
+			&callbackURL,
+			&content.subject,
+			&content.bodyHTML,
+			&content.attachments,
+			&content.metadata,
+			&audience.userID,
+			&audience.category,
+			&audience.channels,
+			&targets,
+			&targetIndex,
 		); err != nil {
-			return nil, fmt.Errorf("error scan in GetAllNotifies: %w", err)
+			return nil, failSpan(span, fmt.Errorf("error scan in GetAllNotifies: %w", err))
+		}
+		rich, err := content.content()
+		if err != nil {
+			return nil, failSpan(span, err)
+		}
+		addressee, err := audience.audience()
+		if err != nil {
+			return nil, failSpan(span, err)
+		}
+		chain, err := parseTargets(targets)
+		if err != nil {
+			return nil, failSpan(span, err)
 		}
 
 		uuid, _ := types.NewUUID(id)
 
 
-		channelValid, _ := internaltypes.NotificationChannelFromString(channel)
+		channelValid, _ := parseChannel(channel)
 		
 
-		recipientValid, _ := internaltypes.NewSendTo(types.NewAnyText(recipient), channelValid)
+		recipientValid, _ := domain.NewSendTo(types.NewAnyText(recipient), channelValid)
 		
 
 		result = append(result, &model.Notification{
@@ -167,28 +290,168 @@ func (r *StoreRepository) GetAllNotifies(ctx context.Context) ([]*model.Notifica
 			Status:      status,
 			Tries:       tries,
 			LastError:   lastError,
+			CallbackURL: stringOrEmpty(callbackURL),
+			Targets:     chain,
+			TargetIndex: targetIndex,
+			Content:     rich,
+			Audience:    addressee,
 		})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error in GetAllNotifies: %w", err)
+		return nil, failSpan(span, fmt.Errorf("rows error in GetAllNotifies: %w", err))
 	}
 
 	return result, nil
 }
 
 
-func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.Time) ([]*model.Notification, error) {
+// FetchFromDb returns pending notifications due by needToSendTime. Notifications handed
+// to the broker's delayed exchange are skipped unless they were due by enqueuedDueBefore:
+// by then the broker should have delivered them, so the poller takes over.
+func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.Time, enqueuedDueBefore time.Time) ([]*model.Notification, error) {
 	query := `
-    SELECT id, recipient, channel, message, scheduled_at, status, tries, last_error
+    SELECT id, recipient, channel, message, scheduled_at, status, tries, last_error, callback_url, trace_parent, request_id,
+      subject, body_html, attachments, metadata, user_id, category, channel_preferences
     FROM notifier_db.public.notifications
-    WHERE scheduled_at <= $1 AND status = 'pending' AND tries <= 3
+    WHERE scheduled_at <= $1 AND status = 'pending' AND tries <= 3 AND deleted_at IS NULL
+      AND (enqueued_at IS NULL OR scheduled_at <= $2)
     ORDER BY scheduled_at
 `
-	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, needToSendTime)
+	return r.fetchToSend(ctx, "FetchFromDb", query, needToSendTime, enqueuedDueBefore)
+}
 
+// FetchDue returns the notification a due message from the delayed exchange points to,
+// or nil if it must not be sent any more: it was cancelled, already sent by the poller,
+// ran out of tries, or the message is stale (scheduled_at no longer matches).
+func (r *StoreRepository) FetchDue(ctx context.Context, id *types.UUID, scheduledAt time.Time) (*model.Notification, error) {
+	query := `
+    SELECT id, recipient, channel, message, scheduled_at, status, tries, last_error, callback_url, trace_parent, request_id,
+      subject, body_html, attachments, metadata, user_id, category, channel_preferences
+    FROM notifier_db.public.notifications
+    WHERE id = $1 AND scheduled_at = $2 AND status = 'pending' AND tries <= 3 AND deleted_at IS NULL
+`
+	result, err := r.fetchToSend(ctx, "FetchDue", query, id.String(), scheduledAt)
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// MarkAsEnqueued records that a pending notification was published to the delayed exchange.
+func (r *StoreRepository) MarkAsEnqueued(ctx context.Context, id *types.UUID) error {
+	query := `UPDATE notifier_db.public.notifications SET enqueued_at = now(), updated_at = now() WHERE id = $1 AND status = 'pending'`
+	ctx, span := startQuerySpan(ctx, "MarkAsEnqueued", "UPDATE", query)
+	defer span.End()
+
+	if _, err := r.db.ExecWithRetry(ctx, r.strategy, query, id.String()); err != nil {
+		return failSpan(span, postgresError(err, "mark as enqueued"))
+	}
+	return nil
+}
+
+// UnmarkEnqueued hands a notification back to the poller when publishing it to the
+// delayed exchange failed.
+func (r *StoreRepository) UnmarkEnqueued(ctx context.Context, id *types.UUID) error {
+	query := `UPDATE notifier_db.public.notifications SET enqueued_at = NULL, updated_at = now() WHERE id = $1`
+	ctx, span := startQuerySpan(ctx, "UnmarkEnqueued", "UPDATE", query)
+	defer span.End()
+
+	if _, err := r.db.ExecWithRetry(ctx, r.strategy, query, id.String()); err != nil {
+		return failSpan(span, postgresError(err, "unmark enqueued"))
+	}
+	return nil
+}
+
+// AssignRecipient stores the channel and the recipient resolved for a notification
+// addressed to a user; status reports and the API see them from then on.
+func (r *StoreRepository) AssignRecipient(ctx context.Context, id *types.UUID, channel string, recipient string) error {
+	query := `UPDATE notifier_db.public.notifications SET channel = $2, recipient = $3, updated_at = now() WHERE id = $1 AND status = 'pending'`
+	ctx, span := startQuerySpan(ctx, "AssignRecipient", "UPDATE", query)
+	defer span.End()
+
+	if _, err := r.db.ExecWithRetry(ctx, r.strategy, query, id.String(), channel, recipient); err != nil {
+		return failSpan(span, postgresError(err, "assign recipient"))
+	}
+	return nil
+}
+
+// CloseUnresolved finishes a pending notification whose recipient can't be resolved
+// with status (cancelled or failed) and logs the event of the same name. Returns false
+// if it wasn't pending any more.
+func (r *StoreRepository) CloseUnresolved(ctx context.Context, id *types.UUID, status string, reason string) (bool, error) {
+	query := `WITH closed AS (
+			UPDATE notifier_db.public.notifications SET status = $2, last_error = $3, updated_at = now()
+			WHERE id = $1 AND status = 'pending'
+			RETURNING id, status
+		)
+		INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor, details)
+		SELECT id, status, status, $4, $3 FROM closed`
+	ctx, span := startQuerySpan(ctx, "CloseUnresolved", "UPDATE", query)
+	defer span.End()
+
+	// по строке журнала на закрытое уведомление
+	res, err := r.db.ExecWithRetry(ctx, r.strategy, query, id.String(), status, reason, actor.FromContext(ctx))
+	if err != nil {
+		return false, failSpan(span, postgresError(err, "close unresolved"))
+	}
+	closed, err := res.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("error fetch %w", err)
+		return false, failSpan(span, fmt.Errorf("couldn't get number of rows affected: %w", err))
+	}
+	return closed > 0, nil
+}
+
+// FetchInFlight returns the notifications addressed to the user that were published to
+// the workers and haven't been delivered yet.
+func (r *StoreRepository) FetchInFlight(ctx context.Context, userID string) ([]*model.Notification, error) {
+	query := `
+    SELECT id, recipient, channel, message, scheduled_at, status, tries, last_error, callback_url, trace_parent, request_id,
+      subject, body_html, attachments, metadata, user_id, category, channel_preferences
+    FROM notifier_db.public.notifications
+    WHERE user_id = $1 AND status = 'sent' AND deleted_at IS NULL
+`
+	return r.fetchToSend(ctx, "FetchInFlight", query, userID)
+}
+
+// Retract takes back a published notification whose resolved recipient no longer holds:
+// it is cancelled, failed or returned to 'pending' to be resolved and published again.
+// The transition is logged as 'rerouted' for 'pending' and under the status name otherwise.
+// Returns false if it was delivered, rerouted or deleted in the meantime.
+func (r *StoreRepository) Retract(ctx context.Context, notify *model.Notification, status string, reason string) (bool, error) {
+	query := `WITH retracted AS (
+			UPDATE notifier_db.public.notifications
+			SET status = $4, last_error = $5, enqueued_at = NULL, updated_at = now()
+			WHERE id = $1 AND channel = $2 AND recipient = $3 AND status = 'sent' AND deleted_at IS NULL
+			RETURNING id, status
+		)
+		INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor, details)
+		SELECT id, CASE WHEN status = 'pending' THEN 'rerouted' ELSE status END, status, $6, $5 FROM retracted`
+	ctx, span := startQuerySpan(ctx, "Retract", "UPDATE", query)
+	defer span.End()
+
+	// по строке журнала на отозванное уведомление
+	res, err := r.db.ExecWithRetry(ctx, r.strategy, query,
+		notify.ID.String(), notify.Channel.String(), notify.Recipient.String(), status, reason, actor.FromContext(ctx))
+	if err != nil {
+		return false, failSpan(span, postgresError(err, "retract"))
+	}
+	retracted, err := res.RowsAffected()
+	if err != nil {
+		return false, failSpan(span, fmt.Errorf("couldn't get number of rows affected: %w", err))
+	}
+	return retracted > 0, nil
+}
+
+// fetchToSend runs a query selecting the columns needed to publish notifications.
+func (r *StoreRepository) fetchToSend(ctx context.Context, method string, query string, args ...any) ([]*model.Notification, error) {
+	ctx, span := startQuerySpan(ctx, method, "SELECT", query)
+	defer span.End()
+
+	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, args...)
+
+	if err != nil {
+		return nil, failSpan(span, postgresError(err, "fetch"))
 	}
 	if rows == nil {
 		zlog.Logger.Warn().Msg("QueryWithRetry returned nil rows, returning empty result")
@@ -215,6 +478,11 @@ func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.T
 			status      string
 			tries       int
 			lastError   *string
+			callbackURL *string
+			traceParent *string
+			requestID   *string
+			content     contentColumns
+			audience    audienceColumns
 		)
 
 		if err := rows.Scan(
@@ -226,19 +494,29 @@ func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.T
 			&status,
 			&tries,
 			&lastError,
+			&callbackURL,
+			&traceParent,
+			&requestID,
+			&content.subject,
+			&content.bodyHTML,
+			&content.attachments,
+			&content.metadata,
+			&audience.userID,
+			&audience.category,
+			&audience.channels,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, failSpan(span, fmt.Errorf("failed to scan row: %w", err))
 		}
 
-		var channelValid internaltypes.NotificationChannel
-		channelValid, err = internaltypes.NotificationChannelFromString(channel)
+		var channelValid domain.NotificationChannel
+		channelValid, err = parseChannel(channel)
 		if err != nil {
 			zlog.Logger.Error().Err(fmt.Errorf("invalid channel in postgres: %w", err))
 			continue
 		}
 
-		var recipientToValid internaltypes.Recipient
-		recipientToValid = internaltypes.RecipientFromString(recipient)
+		var recipientToValid domain.Recipient
+		recipientToValid = domain.RecipientFromString(recipient)
 
 		var UUID types.UUID
 		UUID, err = types.NewUUID(id)
@@ -247,6 +525,17 @@ func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.T
 			continue
 		}
 
+		rich, err := content.content()
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("id", id).Msg("skipping notification with invalid content")
+			continue
+		}
+		addressee, err := audience.audience()
+		if err != nil {
+			zlog.Logger.Error().Err(err).Str("id", id).Msg("skipping notification with invalid audience")
+			continue
+		}
+
 		result = append(result, &model.Notification{
 			ID:          &UUID,
 			Recipient:   recipientToValid,
@@ -256,14 +545,19 @@ func (r *StoreRepository) FetchFromDb(ctx context.Context, needToSendTime time.T
 			Status:      status,
 			Tries:       tries,
 			LastError:   lastError,
+			CallbackURL: stringOrEmpty(callbackURL),
+			TraceParent: stringOrEmpty(traceParent),
+			RequestID:   stringOrEmpty(requestID),
+			Content:     rich,
+			Audience:    addressee,
 		})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error after scanning rows: %w", err)
+		return nil, failSpan(span, fmt.Errorf("error after scanning rows: %w", err))
 	}
 
-
+	span.SetAttributes(attribute.Int("db.response.returned_rows", len(result)))
 	return result, nil
 }
 
@@ -278,9 +572,33 @@ func (r *StoreRepository) UpdateNotification(ctx context.Context, n *model.Notif
             status = $5,
             tries = $6,
             last_error = $7,
+            subject = $9,
+            body_html = $10,
+            attachments = $11,
+            metadata = $12,
+            user_id = $13,
+            category = $14,
+            channel_preferences = $15,
+            targets = $16,
+            target_index = $17,
             updated_at = now()
-        WHERE id = $8
+        WHERE id = $8 AND deleted_at IS NULL
     `
+    ctx, span := startQuerySpan(ctx, "UpdateNotification", "UPDATE", query)
+    defer span.End()
+
+    content, err := newContentColumns(n.Content)
+    if err != nil {
+        return failSpan(span, err)
+    }
+    audience, err := newAudienceColumns(n.Audience)
+    if err != nil {
+        return failSpan(span, err)
+    }
+    targets, err := newTargetsColumn(n.Targets)
+    if err != nil {
+        return failSpan(span, err)
+    }
 
     // Выполняем запрос
     res, err := r.db.ExecWithRetry(
@@ -295,20 +613,29 @@ func (r *StoreRepository) UpdateNotification(ctx context.Context, n *model.Notif
         n.Tries,
         n.LastError,
         n.ID.String(),
+        content.subject,
+        content.bodyHTML,
+        content.attachments,
+        content.metadata,
+        audience.userID,
+        audience.category,
+        audience.channels,
+        targets,
+        n.TargetIndex,
     )
     if err != nil {
-        return fmt.Errorf("failed to update notification: %w", err)
+        return failSpan(span, postgresError(err, "update"))
     }
 
     // Проверяем, сколько строк реально было обновлено
     rowsAffected, err := res.RowsAffected()
     if err != nil {
-        return fmt.Errorf("couldn't get number of rows affected: %w", err)
+        return failSpan(span, fmt.Errorf("couldn't get number of rows affected: %w", err))
     }
 
     // Если запись с таким ID не найдена
     if rowsAffected == 0 {
-        return fmt.Errorf("notification not found")
+        return apperrors.NotFound(apperrors.CodeNotFound, "notification not found", nil)
     }
 
     return nil
@@ -325,41 +652,250 @@ func (r *StoreRepository) MarkAsSent(ctx context.Context, ids []*types.UUID) err
 	for i := range idNumsList {
 		idNumsList[i] = "$" + strconv.Itoa(i+1)
 	}
-	query := fmt.Sprintf(`UPDATE notifier_db.public.notifications SET status = 'sent' where id IN (%s)`, strings.Join(idNumsList, ","))
+	// воркер может успеть прислать отчет о доставке раньше, его статус не перетираем;
+	// 'dispatched' попадает в журнал тем же запросом
+	query := fmt.Sprintf(`WITH sent AS (
+			UPDATE notifier_db.public.notifications SET status = 'sent', updated_at = now() where id IN (%s) AND status = 'pending'
+			RETURNING id, status
+		)
+		INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor)
+		SELECT id, 'dispatched', status, $%d FROM sent`, strings.Join(idNumsList, ","), len(ids)+1)
 
-	idStrsList := make([]any, len(ids))
+	idStrsList := make([]any, len(ids), len(ids)+1)
 	for i, v := range ids {
 		idStrsList[i] = v.String()
 	}
+	idStrsList = append(idStrsList, actor.FromContext(ctx))
+
+	ctx, span := startQuerySpan(ctx, "MarkAsSent", "UPDATE", query)
+	defer span.End()
 
 	_, err := r.db.ExecWithRetry(ctx, r.strategy, query, idStrsList...)
 	if err != nil {
-		return fmt.Errorf("error marking %d notifications as sent: %w", len(ids), err)
+		return failSpan(span, fmt.Errorf("error marking %d notifications as sent: %w", len(ids), postgresError(err, "mark as sent")))
 	}
 
 	return nil
 }
 
-func (r *StoreRepository) DeleteNotification(ctx context.Context, id types.UUID) error {
-	// SQL-запрос на удаление по id
-	query := `DELETE FROM notifier_db.public.notifications WHERE id = $1`
+// MarkAsFailed counts a failed publication attempt. Once the notification runs
+// out of tries (see FetchFromDb) it is moved to 'failed'. The attempt is logged as
+// 'rescheduled' or 'failed'. Returns the resulting status.
+func (r *StoreRepository) MarkAsFailed(ctx context.Context, id *types.UUID, reason string) (string, error) {
+	query := `WITH failed AS (
+			UPDATE notifier_db.public.notifications
+			SET tries = tries + 1,
+				last_error = $2,
+				status = CASE WHEN tries + 1 > 3 THEN 'failed' ELSE status END,
+				updated_at = now()
+			WHERE id = $1
+			RETURNING id, status
+		), audit AS (
+			INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor, details)
+			SELECT id, CASE WHEN status = 'failed' THEN 'failed' ELSE 'rescheduled' END, status, $3, $2 FROM failed
+		)
+		SELECT status FROM failed`
+	ctx, span := startQuerySpan(ctx, "MarkAsFailed", "UPDATE", query)
+	defer span.End()
 
-	// Выполняем запрос через ExecWithRetry
-	res, err := r.db.ExecWithRetry(ctx, r.strategy, query, id.String())
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, id.String(), reason, actor.FromContext(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to delete notification: %w", err)
+		return "", failSpan(span, postgresError(err, "mark as failed"))
+	}
+
+	var status string
+	if err = row.Scan(&status); err != nil {
+		return "", failSpan(span, postgresError(err, "mark as failed"))
 	}
+	return status, nil
+}
+
+// DeleteNotification soft-deletes the notification: the row stays for the audit log,
+// a pending notification is cancelled so it is never published. The audit log gets
+// 'cancelled' (for a pending one) and 'deleted'. Returns the status before deletion.
+func (r *StoreRepository) DeleteNotification(ctx context.Context, id types.UUID) (string, error) {
+	query := `WITH old AS (
+			SELECT id, status FROM notifier_db.public.notifications
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		), deleted AS (
+			UPDATE notifier_db.public.notifications n
+			SET deleted_at = now(),
+				status = CASE WHEN old.status = 'pending' THEN 'cancelled' ELSE old.status END,
+				updated_at = now()
+			FROM old
+			WHERE n.id = old.id
+			RETURNING n.id, n.status, old.status AS previous_status
+		), audit AS (
+			INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor)
+			SELECT deleted.id, e.event_type, e.status, $2
+			FROM deleted, LATERAL (VALUES
+				(1, 'cancelled', 'cancelled', deleted.previous_status = 'pending'),
+				(2, 'deleted', deleted.status, true)
+			) AS e(seq, event_type, status, logged)
+			WHERE e.logged
+			ORDER BY e.seq
+		)
+		SELECT previous_status FROM deleted`
+	ctx, span := startQuerySpan(ctx, "DeleteNotification", "UPDATE", query)
+	defer span.End()
 
-	// Проверяем, сколько строк реально было удалено
-	rowsAffected, err := res.RowsAffected()
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, id.String(), actor.FromContext(ctx))
 	if err != nil {
-		return fmt.Errorf("couldn't get number of rows affected: %w", err)
+		return "", failSpan(span, postgresError(err, "delete"))
 	}
 
-	// Если запись с таким ID не найдена
-	if rowsAffected == 0 {
-		return fmt.Errorf("notification not found")
+	var previousStatus string
+	if err = row.Scan(&previousStatus); err != nil {
+		// sql.ErrNoRows — записи нет или она уже удалена
+		return "", failSpan(span, postgresError(err, "delete"))
 	}
+	return previousStatus, nil
+}
 
-	return nil
-}
\ No newline at end of file
+// ApplyReport records the worker's delivery attempt and moves the notification to its
+// new state in one statement. A failed attempt with targets left in the chain switches
+// the notification to the next target and returns it to 'pending'. The transition goes to
+// the audit log as 'delivered', 'failed' or 'rerouted' in the same statement. A report for
+// a target the notification has already left is stale and yields a NotFound error.
+func (r *StoreRepository) ApplyReport(ctx context.Context, report *model.StatusReport) (*model.Notification, *model.DeliveryAttempt, error) {
+	query := `WITH cur AS (
+			SELECT id, channel, recipient, target_index,
+				($2 = 'failed' AND status <> 'cancelled' AND deleted_at IS NULL
+					AND target_index + 1 < COALESCE(jsonb_array_length(targets), 0)) AS reroute
+			FROM notifier_db.public.notifications
+			WHERE id = $1 AND ($4 = '' OR (channel = $4 AND recipient = $5))
+			FOR UPDATE
+		), attempt AS (
+			INSERT INTO notifier_db.public.delivery_attempts (notification_id, target_index, channel, recipient, status, error, attempted_at)
+			SELECT id, target_index, channel, recipient, $2, $3, $6 FROM cur
+		)
+		), updated AS (
+			UPDATE notifier_db.public.notifications n
+			SET status = CASE WHEN cur.reroute THEN 'pending' ELSE $2 END,
+				target_index = CASE WHEN cur.reroute THEN n.target_index + 1 ELSE n.target_index END,
+				channel = CASE WHEN cur.reroute THEN n.targets -> (n.target_index + 1) ->> 'channel' ELSE n.channel END,
+				recipient = CASE WHEN cur.reroute THEN n.targets -> (n.target_index + 1) ->> 'recipient' ELSE n.recipient END,
+				tries = CASE WHEN cur.reroute THEN 0 ELSE n.tries END,
+				enqueued_at = CASE WHEN cur.reroute THEN NULL ELSE n.enqueued_at END,
+				last_error = COALESCE($3, n.last_error),
+				updated_at = now()
+			FROM cur
+			WHERE n.id = cur.id
+			RETURNING n.id, n.recipient, n.channel, n.message, n.scheduled_at, n.status, n.tries, n.last_error, n.callback_url, n.deleted_at,
+				n.subject, n.body_html, n.attachments, n.metadata, n.user_id, n.category, n.channel_preferences,
+				n.targets, n.target_index, cur.reroute,
+				cur.target_index AS attempt_target_index, cur.channel AS attempt_channel, cur.recipient AS attempt_recipient
+		), audit AS (
+			INSERT INTO notifier_db.public.notification_events (notification_id, event_type, status, actor, details)
+			SELECT id, CASE WHEN reroute THEN 'rerouted' WHEN $2 = 'failed' THEN 'failed' ELSE 'delivered' END, status, $7, $3
+			FROM updated
+		)
+		SELECT recipient, channel, message, scheduled_at, status, tries, last_error, callback_url, deleted_at,
+			subject, body_html, attachments, metadata, user_id, category, channel_preferences,
+			targets, target_index, attempt_target_index, attempt_channel, attempt_recipient
+		FROM updated`
+	ctx, span := startQuerySpan(ctx, "ApplyReport", "UPDATE", query)
+	defer span.End()
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query,
+		report.ID.String(), report.Status, report.Error, report.Channel, report.Recipient, report.At, actor.FromContext(ctx))
+	if err != nil {
+		return nil, nil, failSpan(span, postgresError(err, "apply report"))
+	}
+
+	var (
+		recipient   string
+		channel     string
+		message     string
+		scheduledAt time.Time
+		newStatus   string
+		tries       int
+		newError    *string
+		callbackURL *string
+		deletedAt   *time.Time
+		content     contentColumns
+		audience    audienceColumns
+		targets     *string
+		targetIndex int
+		attempt     = model.DeliveryAttempt{Status: report.Status, Error: report.Error, AttemptedAt: report.At}
+	)
+	err = row.Scan(&recipient, &channel, &message, &scheduledAt, &newStatus, &tries, &newError, &callbackURL, &deletedAt,
+		&content.subject, &content.bodyHTML, &content.attachments, &content.metadata,
+		&audience.userID, &audience.category, &audience.channels,
+		&targets, &targetIndex, &attempt.TargetIndex, &attempt.Channel, &attempt.Recipient)
+	if err != nil {
+		// sql.ErrNoRows — записи нет или отчет о цели, с которой уведомление уже ушло
+		return nil, nil, failSpan(span, postgresError(err, "apply report"))
+	}
+
+	channelValid, err := parseChannel(channel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid channel in postgres: %w", err)
+	}
+	rich, err := content.content()
+	if err != nil {
+		return nil, nil, err
+	}
+	addressee, err := audience.audience()
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := parseTargets(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &model.Notification{
+		ID:          report.ID,
+		Recipient:   domain.RecipientFromString(recipient),
+		Channel:     channelValid,
+		Message:     message,
+		ScheduledAt: scheduledAt,
+		Status:      newStatus,
+		Tries:       tries,
+		LastError:   newError,
+		CallbackURL: stringOrEmpty(callbackURL),
+		DeletedAt:   deletedAt,
+		Targets:     chain,
+		TargetIndex: targetIndex,
+		Content:     rich,
+		Audience:    addressee,
+	}, &attempt, nil
+}
+
+// GetAttempts lists the delivery attempts of the notification, oldest first.
+func (r *StoreRepository) GetAttempts(ctx context.Context, id types.UUID) ([]*model.DeliveryAttempt, error) {
+	query := `SELECT target_index, channel, recipient, status, error, attempted_at
+		FROM notifier_db.public.delivery_attempts
+		WHERE notification_id = $1
+		ORDER BY attempted_at, id`
+	ctx, span := startQuerySpan(ctx, "GetAttempts", "SELECT", query)
+	defer span.End()
+
+	rows, err := r.db.QueryWithRetry(ctx, r.strategy, query, id.String())
+	if err != nil {
+		return nil, failSpan(span, postgresError(err, "get attempts"))
+	}
+	defer rows.Close()
+
+	result := []*model.DeliveryAttempt{}
+	for rows.Next() {
+		var attempt model.DeliveryAttempt
+		if err := rows.Scan(
+			&attempt.TargetIndex,
+			&attempt.Channel,
+			&attempt.Recipient,
+			&attempt.Status,
+			&attempt.Error,
+			&attempt.AttemptedAt,
+		); err != nil {
+			return nil, failSpan(span, fmt.Errorf("failed to scan delivery attempt: %w", err))
+		}
+		result = append(result, &attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, failSpan(span, fmt.Errorf("rows error in GetAttempts: %w", err))
+	}
+	return result, nil
+}
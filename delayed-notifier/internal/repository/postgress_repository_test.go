@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/postgres"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+// testPostgresDSNEnv points the repository tests to a scratch database named notifier_db
+// (queries address notifier_db.public); without it they are skipped.
+const testPostgresDSNEnv = "DELAYED_NOTIFIER_TEST_POSTGRES_DSN"
+
+func newTestDB(t *testing.T) *dbpg.DB {
+	t.Helper()
+	dsn := os.Getenv(testPostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testPostgresDSNEnv)
+	}
+	migrations, err := filepath.Abs("../../db/migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postgres.MigrateUp(dsn, "file://"+migrations); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db, err := dbpg.New(dsn, nil, nil)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Master.Close() })
+	return db
+}
+
+func TestApplyReportReroutesAlongTheChain(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	strategy := retry.Strategy{Attempts: 1}
+	repo := NewRepository(db, strategy)
+
+	id := types.GenerateUUID()
+	chain := []model.Target{
+		{Channel: "email", Recipient: "a@example.com"},
+		{Channel: "telegram", Recipient: "123456"},
+		{Channel: "email", Recipient: "b@example.com"},
+	}
+	notify := &model.Notification{
+		ID:          &id,
+		Channel:     domain.ChannelEmail,
+		Recipient:   domain.RecipientFromString(chain[0].Recipient),
+		Message:     "your order has shipped",
+		ScheduledAt: time.Now().Add(time.Hour),
+		Status:      model.StatusPending,
+		Targets:     chain,
+	}
+	if err := repo.CreateNotify(ctx, notify); err != nil {
+		t.Fatalf("CreateNotify: %v", err)
+	}
+	t.Cleanup(func() {
+		// журнал можно удалить только после самого уведомления
+		for _, query := range []string{
+			`DELETE FROM notifier_db.public.notifications WHERE id = $1`,
+			`DELETE FROM notifier_db.public.delivery_attempts WHERE notification_id = $1`,
+			`DELETE FROM notifier_db.public.notification_events WHERE notification_id = $1`,
+		} {
+			if _, err := db.Master.ExecContext(ctx, query, id.String()); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+
+	if err := repo.MarkAsEnqueued(ctx, &id); err != nil {
+		t.Fatalf("MarkAsEnqueued: %v", err)
+	}
+	if err := repo.MarkAsSent(ctx, []*types.UUID{&id}); err != nil {
+		t.Fatalf("MarkAsSent: %v", err)
+	}
+	// публикация до этого падала: после перехода на другую цель попытки считаются заново
+	if _, err := db.Master.ExecContext(ctx, `UPDATE notifier_db.public.notifications SET tries = 2 WHERE id = $1`, id.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := "mailbox is full"
+	report := func(target model.Target) (*model.Notification, *model.DeliveryAttempt, error) {
+		return repo.ApplyReport(ctx, &model.StatusReport{
+			ID:        &id,
+			Status:    model.StatusFailed,
+			Error:     &failure,
+			At:        time.Now(),
+			Channel:   target.Channel,
+			Recipient: target.Recipient,
+		})
+	}
+
+	rerouted, attempt, err := report(chain[0])
+	if err != nil {
+		t.Fatalf("ApplyReport: %v", err)
+	}
+	if rerouted.Status != model.StatusPending || rerouted.TargetIndex != 1 || rerouted.Tries != 0 ||
+		rerouted.Channel.String() != "telegram" || rerouted.Recipient.String() != "123456" {
+		t.Fatalf("after the first target failed: %+v", rerouted)
+	}
+	if attempt.TargetIndex != 0 || attempt.Channel != "email" || attempt.Recipient != "a@example.com" {
+		t.Fatalf("attempt %+v, want the first target", attempt)
+	}
+	var enqueued bool
+	row := db.Master.QueryRowContext(ctx, `SELECT enqueued_at IS NOT NULL FROM notifier_db.public.notifications WHERE id = $1`, id.String())
+	if err := row.Scan(&enqueued); err != nil {
+		t.Fatal(err)
+	}
+	if enqueued {
+		t.Fatal("enqueued_at kept after rerouting: the poller would skip the notification")
+	}
+
+	// повторный отчет о цели, с которой уведомление уже ушло
+	if _, _, err := report(chain[0]); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("stale report: got %v, want not found", err)
+	}
+
+	if _, _, err := report(chain[1]); err != nil {
+		t.Fatalf("ApplyReport: %v", err)
+	}
+	failed, _, err := report(chain[2])
+	if err != nil {
+		t.Fatalf("ApplyReport: %v", err)
+	}
+	if failed.Status != model.StatusFailed || failed.TargetIndex != 2 {
+		t.Fatalf("after the last target failed: %+v", failed)
+	}
+
+	attempts, err := repo.GetAttempts(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAttempts: %v", err)
+	}
+	if len(attempts) != len(chain) {
+		t.Fatalf("%d attempts recorded, want %d", len(attempts), len(chain))
+	}
+	for i, attempt := range attempts {
+		if attempt.TargetIndex != i || attempt.Channel != chain[i].Channel || attempt.Recipient != chain[i].Recipient ||
+			attempt.Status != model.StatusFailed || attempt.Error == nil || *attempt.Error != failure {
+			t.Errorf("attempt %d: %+v", i, attempt)
+		}
+	}
+
+	// журнал пишется тем же запросом, что и переход
+	history, err := NewAuditRepository(db, strategy).GetHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	want := []string{model.EventCreated, model.EventDispatched, model.EventRerouted, model.EventRerouted, model.EventFailed}
+	if len(history) != len(want) {
+		t.Fatalf("history of %d entries, want %v", len(history), want)
+	}
+	for i, entry := range history {
+		if entry.Type != want[i] {
+			t.Errorf("history entry %d is %q, want %q", i, entry.Type, want[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	rabbitpublisher "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitProducer"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type channelState struct {
+	available bool
+	checkedAt time.Time
+}
+
+// RabbitChannelRegistry считает канал доступным, если воркер объявил его очередь
+// <queue>.<channel>. Ответ брокера кешируется на RabbitMQConfig.ChannelsRefresh.
+type RabbitChannelRegistry struct {
+	publisher *rabbitpublisher.Publisher
+	rabbitCfg config.RabbitMQConfig
+
+	mu     sync.Mutex
+	states map[string]channelState
+}
+
+func NewRabbitChannelRegistry(publisher *rabbitpublisher.Publisher, rabbitCfg config.RabbitMQConfig) *RabbitChannelRegistry {
+	return &RabbitChannelRegistry{
+		publisher: publisher,
+		rabbitCfg: rabbitCfg,
+		states:    make(map[string]channelState),
+	}
+}
+
+// Available reports whether some worker has declared the queue of channel. When the
+// broker can't be asked, the last known answer is used, and without one the channel is
+// accepted: the notification waits in Postgres either way.
+func (r *RabbitChannelRegistry) Available(ctx context.Context, channel string) (bool, error) {
+	r.mu.Lock()
+	state, known := r.states[channel]
+	r.mu.Unlock()
+	if known && time.Since(state.checkedAt) < r.rabbitCfg.ChannelsRefresh {
+		return state.available, nil
+	}
+
+	available, err := r.publisher.QueueExists(r.rabbitCfg.QueueFor(channel))
+	if err != nil {
+		zlog.Logger.Warn().Err(err).Str("channel", channel).Bool("known", known).
+			Msg("failed to check channel queue, using the last known state")
+		if known {
+			return state.available, nil
+		}
+		return true, nil
+	}
+
+	r.mu.Lock()
+	r.states[channel] = channelState{available: available, checkedAt: time.Now()}
+	r.mu.Unlock()
+	return available, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitConsumer"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// пауза перед возвратом сообщения в очередь, чтобы не крутить его в цикле при недоступной БД
+const dueRequeueDelay = time.Second
+
+// RabbitDueReceiver reads notifications whose delay in the delayed exchange expired.
+type RabbitDueReceiver struct {
+	consumer      *rabbitconsumer.Consumer
+	retryStrategy retry.Strategy
+}
+
+func NewRabbitDueReceiver(consumer *rabbitconsumer.Consumer, retryStrategy retry.Strategy) *RabbitDueReceiver {
+	return &RabbitDueReceiver{
+		consumer:      consumer,
+		retryStrategy: retryStrategy,
+	}
+}
+
+func (r *RabbitDueReceiver) Receive(ctx context.Context, handle func(ctx context.Context, due *model.DueNotification) error) error {
+	messages := make(chan amqp091.Delivery)
+	consumeErr := make(chan error, 1)
+	go func() {
+		consumeErr <- r.consumer.ConsumeWithRetry(ctx, messages, r.retryStrategy)
+	}()
+
+	for {
+		select {
+		case err := <-consumeErr:
+			return err
+		case delivery := <-messages:
+			r.process(ctx, delivery, handle)
+		}
+	}
+}
+
+func (r *RabbitDueReceiver) process(ctx context.Context, delivery amqp091.Delivery, handle func(ctx context.Context, due *model.DueNotification) error) {
+	// продолжаем трассу создания уведомления
+	ctx, span := tracer.Start(tracing.ExtractAMQP(ctx, delivery.Headers), "process "+delivery.RoutingKey,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingRabbitMQDestinationRoutingKey(delivery.RoutingKey),
+			semconv.MessagingMessageID(delivery.MessageId),
+		),
+	)
+	defer span.End()
+
+	due, err := dto.ToModelFromDue(delivery.Body)
+	if err != nil {
+		// битое сообщение не станет лучше при повторе, уведомление заберет поллер
+		failSpan(span, err)
+		zlog.Logger.Error().Err(err).Str("message_id", delivery.MessageId).Msg("dropping malformed due message")
+		_ = delivery.Reject(false)
+		return
+	}
+
+	if err := handle(ctx, due); err != nil {
+		failSpan(span, err)
+		zlog.Logger.Warn().Err(err).Stringer("notification_id", due.ID).Msg("due notification not handled, requeueing")
+		select {
+		case <-time.After(dueRequeueDelay):
+		case <-ctx.Done():
+		}
+		_ = delivery.Nack(false, true)
+		return
+	}
+
+	_ = delivery.Ack(false)
+}
@@ -3,15 +3,43 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitConsumer"
 	rabbitpublisher "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitProducer"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/dlq"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/requestid"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/dlq"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/wb-go/wbf/retry"
 	"github.com/wb-go/wbf/zlog"
 )
 
+var (
+	rabbitPublishDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rabbit_publish_duration_seconds",
+			Help:    "Latency of publishing a notification to RabbitMQ, retries included",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"routing_key"},
+	)
+	rabbitPublishTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rabbit_publish_total",
+			Help: "Notifications published to RabbitMQ",
+		},
+		[]string{"routing_key", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(rabbitPublishDuration, rabbitPublishTotal)
+}
+
 type RabbitRepository struct {
 	publisher     *rabbitpublisher.Publisher
 	retryStrategy retry.Strategy
@@ -29,7 +57,7 @@ func (n *RabbitRepository) SendOne(ctx context.Context, notification *model.Noti
 	if err != nil {
 		return fmt.Errorf("couldn't create body to send one: %w", err)
 	}
-	err = n.publisher.PublishWithRetry(ctx, body, n.routingKey(notification))
+	err = n.publish(ctx, notification, body)
 	if err != nil {
 		return fmt.Errorf("couldn't send message to rabbitMQ: %w", err)
 	}
@@ -47,11 +75,10 @@ func (p *RabbitRepository) SendMany(ctx context.Context, notifications []*model.
 
 			if err != nil {
 				zlog.Logger.Err(err).Msg("dont recreate in dto")
-			}
-			if err != nil {
 				DLQ.Put(notification, fmt.Errorf("couldn't send message to rabbitMQ: %w", err))
+				continue
 			}
-			err = p.publisher.PublishWithRetry(ctx, body, p.routingKey(notification))
+			err = p.publish(ctx, notification, body)
 			if err != nil {
 				DLQ.Put(notification, fmt.Errorf("couldn't send message to rabbitMQ: %w", err))
 			} else {
@@ -65,6 +92,77 @@ func (p *RabbitRepository) SendMany(ctx context.Context, notifications []*model.
 
 }
 
+// SendDelayed publishes the notification to the delayed exchange; the broker returns it
+// to the due queue at scheduled_at.
+func (n *RabbitRepository) SendDelayed(ctx context.Context, notification *model.Notification) error {
+	body, err := dto.ToDueFromModel(notification)
+	if err != nil {
+		return fmt.Errorf("couldn't create body to send delayed: %w", err)
+	}
+	ctx = tracing.WithTraceParent(ctx, notification.TraceParent)
+	ctx = requestid.WithID(ctx, notification.RequestID)
+
+	start := time.Now()
+	err = n.publisher.PublishDelayedWithRetry(ctx, body, rabbitconsumer.DueRoutingKey, max(time.Until(notification.ScheduledAt), 0), notification.ID.String())
+	rabbitPublishDuration.WithLabelValues(rabbitconsumer.DueRoutingKey).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	rabbitPublishTotal.WithLabelValues(rabbitconsumer.DueRoutingKey, result).Inc()
+	if err != nil {
+		return fmt.Errorf("couldn't send delayed message to rabbitMQ: %w", err)
+	}
+	return nil
+}
+
+// SendCancellation tells the workers to drop a notification that was published to
+// them but may still be waiting for its scheduled_at.
+func (n *RabbitRepository) SendCancellation(ctx context.Context, notification *model.Notification) error {
+	return n.sendCancellation(ctx, notification, wire.Cancellation{ID: notification.ID.String()})
+}
+
+// SendRetraction tells the workers to drop the copy of a notification addressed to its
+// current channel and recipient; a copy published later to another recipient stays.
+func (n *RabbitRepository) SendRetraction(ctx context.Context, notification *model.Notification) error {
+	return n.sendCancellation(ctx, notification, wire.Cancellation{
+		ID:        notification.ID.String(),
+		Channel:   notification.Channel.String(),
+		Recipient: notification.Recipient.String(),
+	})
+}
+
+func (n *RabbitRepository) sendCancellation(ctx context.Context, notification *model.Notification, cancellation wire.Cancellation) error {
+	body, err := wire.EncodeCancellation(cancellation)
+	if err != nil {
+		return err
+	}
+	ctx = tracing.WithTraceParent(ctx, notification.TraceParent)
+	if err := n.publisher.PublishCancelWithRetry(ctx, body, notification.ID.String()); err != nil {
+		return fmt.Errorf("couldn't send cancellation to rabbitMQ: %w", err)
+	}
+	return nil
+}
+
+func (n *RabbitRepository) publish(ctx context.Context, notification *model.Notification, body []byte) error {
+	routingKey := n.routingKey(notification)
+	// публикация продолжает трассу и request id запроса, которым уведомление было создано
+	ctx = tracing.WithTraceParent(ctx, notification.TraceParent)
+	ctx = requestid.WithID(ctx, notification.RequestID)
+
+	start := time.Now()
+	err := n.publisher.PublishWithRetry(ctx, body, routingKey, notification.ID.String())
+	rabbitPublishDuration.WithLabelValues(routingKey).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	rabbitPublishTotal.WithLabelValues(routingKey, result).Inc()
+	return err
+}
+
 func (n *RabbitRepository) routingKey(notification *model.Notification) string {
 	return notification.Channel.String()
 }
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/rabbitConsumer"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// пауза перед возвратом отчета в очередь, чтобы не крутить его в цикле при недоступной БД
+const statusRequeueDelay = time.Second
+
+type RabbitStatusReceiver struct {
+	consumer      *rabbitconsumer.Consumer
+	retryStrategy retry.Strategy
+}
+
+func NewRabbitStatusReceiver(consumer *rabbitconsumer.Consumer, retryStrategy retry.Strategy) *RabbitStatusReceiver {
+	return &RabbitStatusReceiver{
+		consumer:      consumer,
+		retryStrategy: retryStrategy,
+	}
+}
+
+func (r *RabbitStatusReceiver) Receive(ctx context.Context, handle func(ctx context.Context, report *model.StatusReport) error) error {
+	messages := make(chan amqp091.Delivery)
+	consumeErr := make(chan error, 1)
+	go func() {
+		consumeErr <- r.consumer.ConsumeWithRetry(ctx, messages, r.retryStrategy)
+	}()
+
+	for {
+		select {
+		case err := <-consumeErr:
+			return err
+		case delivery := <-messages:
+			r.process(ctx, delivery, handle)
+		}
+	}
+}
+
+func (r *RabbitStatusReceiver) process(ctx context.Context, delivery amqp091.Delivery, handle func(ctx context.Context, report *model.StatusReport) error) {
+	// отчет продолжает трассу отправки в воркере
+	ctx, span := tracer.Start(tracing.ExtractAMQP(ctx, delivery.Headers), "process "+delivery.RoutingKey,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingRabbitMQDestinationRoutingKey(delivery.RoutingKey),
+		),
+	)
+	defer span.End()
+
+	report, err := dto.ToModelFromStatusReport(delivery.Body)
+	if err != nil {
+		// битое сообщение не станет лучше при повторе
+		failSpan(span, err)
+		zlog.Logger.Error().Err(err).Msg("dropping malformed status report")
+		_ = delivery.Reject(false)
+		return
+	}
+
+	if err := handle(ctx, report); err != nil {
+		failSpan(span, err)
+		zlog.Logger.Warn().Err(err).Stringer("notification_id", report.ID).Msg("status report not applied, requeueing")
+		select {
+		case <-time.After(statusRequeueDelay):
+		case <-ctx.Done():
+		}
+		_ = delivery.Nack(false, true)
+		return
+	}
+
+	_ = delivery.Ack(false)
+}
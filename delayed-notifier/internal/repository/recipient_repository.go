@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+type RecipientRepository struct {
+	db       *dbpg.DB
+	strategy retry.Strategy
+}
+
+func NewRecipientRepository(db *dbpg.DB, strategy retry.Strategy) *RecipientRepository {
+	return &RecipientRepository{
+		db:       db,
+		strategy: strategy,
+	}
+}
+
+// MarkUnreachable records the recipient of notify as unreachable on its channel; a
+// repeated mark keeps the first one.
+func (r *RecipientRepository) MarkUnreachable(ctx context.Context, notify *model.Notification, reason string) error {
+	query := `INSERT INTO notifier_db.public.unreachable_recipients (channel, recipient, reason, notification_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel, recipient) DO NOTHING`
+	_, err := r.db.ExecWithRetry(
+		ctx,
+		r.strategy,
+		query,
+		notify.Channel.String(),
+		notify.Recipient.String(),
+		reason,
+		notify.ID.String(),
+	)
+	if err != nil {
+		return postgresError(err, "mark recipient unreachable")
+	}
+	return nil
+}
+
+func (r *RecipientRepository) IsUnreachable(ctx context.Context, channel, recipient string) (bool, error) {
+	query := `SELECT 1 FROM notifier_db.public.unreachable_recipients WHERE channel = $1 AND recipient = $2`
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, channel, recipient)
+	if err != nil {
+		return false, postgresError(err, "check recipient")
+	}
+	var found int
+	err = row.Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, postgresError(err, "check recipient")
+	}
+	return true, nil
+}
+
+// SaveProfile creates the user or replaces their contacts and preferences.
+func (r *RecipientRepository) SaveProfile(ctx context.Context, profile *model.RecipientProfile) error {
+	query := `INSERT INTO notifier_db.public.recipients (user_id, contacts, preferences)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET contacts = EXCLUDED.contacts, preferences = EXCLUDED.preferences, updated_at = now()
+		RETURNING created_at, updated_at`
+	contacts, err := json.Marshal(profile.Contacts)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal contacts: %w", err)
+	}
+	preferences, err := json.Marshal(profile.Preferences)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal preferences: %w", err)
+	}
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, profile.UserID, string(contacts), string(preferences))
+	if err != nil {
+		return postgresError(err, "save recipient")
+	}
+	if err = row.Scan(&profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		return postgresError(err, "save recipient")
+	}
+	return nil
+}
+
+func (r *RecipientRepository) GetProfile(ctx context.Context, userID string) (*model.RecipientProfile, error) {
+	query := `SELECT contacts, preferences, created_at, updated_at FROM notifier_db.public.recipients WHERE user_id = $1`
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, userID)
+	if err != nil {
+		return nil, postgresError(err, "get recipient")
+	}
+
+	var (
+		contacts    string
+		preferences string
+		createdAt   time.Time
+		updatedAt   time.Time
+	)
+	err = row.Scan(&contacts, &preferences, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.NotFound(apperrors.CodeRecipientNotFound, fmt.Sprintf("recipient '%s' not found", userID), nil)
+	}
+	if err != nil {
+		return nil, postgresError(err, "get recipient")
+	}
+
+	profile := &model.RecipientProfile{UserID: userID, CreatedAt: createdAt, UpdatedAt: updatedAt}
+	if err = json.Unmarshal([]byte(contacts), &profile.Contacts); err != nil {
+		return nil, fmt.Errorf("invalid contacts in postgres: %w", err)
+	}
+	if err = json.Unmarshal([]byte(preferences), &profile.Preferences); err != nil {
+		return nil, fmt.Errorf("invalid preferences in postgres: %w", err)
+	}
+	return profile, nil
+}
+
+// DeleteProfile removes the user; notifications already addressed to them fail when sent.
+func (r *RecipientRepository) DeleteProfile(ctx context.Context, userID string) error {
+	query := `DELETE FROM notifier_db.public.recipients WHERE user_id = $1`
+	res, err := r.db.ExecWithRetry(ctx, r.strategy, query, userID)
+	if err != nil {
+		return postgresError(err, "delete recipient")
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("couldn't get number of rows affected: %w", err)
+	}
+	if deleted == 0 {
+		return apperrors.NotFound(apperrors.CodeRecipientNotFound, fmt.Sprintf("recipient '%s' not found", userID), nil)
+	}
+	return nil
+}
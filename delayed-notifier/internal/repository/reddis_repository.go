@@ -3,15 +3,30 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/wb-go/wbf/redis"
 	"github.com/wb-go/wbf/retry"
 )
 
+var cacheLookupsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_lookups_total",
+		Help: "Notification cache lookups by result: hit, miss or error",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(cacheLookupsTotal)
+}
+
 type RedisRepository struct {
 	redisClient   *redis.Client
 	retryStrategy retry.Strategy
@@ -31,7 +46,7 @@ func (r *RedisRepository) SaveNotification(ctx context.Context, notify *model.No
 	}
 	err = r.redisClient.SetWithExpiration(ctx, key, data, r.expiration)
 	if err != nil {
-		return fmt.Errorf("redis: set key %s: %w", key, err)
+		return fmt.Errorf("redis: set key %s: %w", key, redisError(err, "set"))
 	}
 	return nil
 }
@@ -41,8 +56,15 @@ func (r *RedisRepository) GetNotification(ctx context.Context, id types.UUID) (*
 
 	data, err := r.redisClient.Get(ctx, key)
 	if err != nil {
+		err = redisError(err, "get")
+		if errors.Is(err, apperrors.ErrNotFound) {
+			cacheLookupsTotal.WithLabelValues("miss").Inc()
+		} else {
+			cacheLookupsTotal.WithLabelValues("error").Inc()
+		}
 		return nil, err
 	}
+	cacheLookupsTotal.WithLabelValues("hit").Inc()
 	var notification model.Notification
 
 	if err = json.Unmarshal([]byte(data), &notification); err != nil {
@@ -55,7 +77,7 @@ func (r *RedisRepository) DeleteNotification(ctx context.Context, id types.UUID)
 	key := id.String()
 	err := r.redisClient.Del(ctx, key)
 	if err != nil {
-		return fmt.Errorf("error deleting from redis notification (id '%s'): %w", id, err)
+		return fmt.Errorf("error deleting from redis notification (id '%s'): %w", key, redisError(err, "delete"))
 	}
 	return nil
 }
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/wb-go/wbf/redis"
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const eventDataField = "data"
+
+// RedisEventRepository keeps a bounded history of events in a redis stream
+// (for Last-Event-ID resumption) and fans them out to replicas via pub/sub.
+type RedisEventRepository struct {
+	redisClient   *redis.Client
+	retryStrategy retry.Strategy
+	stream        string
+	channel       string
+	maxLen        int64
+}
+
+func NewRedisEventRepository(
+	redisClient *redis.Client,
+	retryStrategy retry.Strategy,
+	stream string,
+	channel string,
+	maxLen int64,
+) *RedisEventRepository {
+	return &RedisEventRepository{
+		redisClient:   redisClient,
+		retryStrategy: retryStrategy,
+		stream:        stream,
+		channel:       channel,
+		maxLen:        maxLen,
+	}
+}
+
+func (r *RedisEventRepository) Publish(ctx context.Context, event *model.NotificationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("redis: marshal event: %w", err)
+	}
+
+	err = retry.DoContext(ctx, r.retryStrategy, func() error {
+		id, errAdd := r.redisClient.XAdd(ctx, &goredis.XAddArgs{
+			Stream: r.stream,
+			MaxLen: r.maxLen,
+			Approx: true,
+			Values: map[string]interface{}{eventDataField: data},
+		}).Result()
+		if errAdd != nil {
+			return errAdd
+		}
+		event.ID = id
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: add event to stream %s: %w", r.stream, redisError(err, "xadd"))
+	}
+
+	// history entry has no id inside, the live message has
+	data, err = json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("redis: marshal event: %w", err)
+	}
+	err = r.redisClient.Publish(ctx, r.channel, data).Err()
+	if err != nil {
+		return fmt.Errorf("redis: publish event to %s: %w", r.channel, redisError(err, "publish"))
+	}
+	return nil
+}
+
+func (r *RedisEventRepository) Subscribe(ctx context.Context) (<-chan *model.NotificationEvent, error) {
+	pubsub := r.redisClient.Subscribe(ctx, r.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("redis: subscribe to %s: %w", r.channel, redisError(err, "subscribe"))
+	}
+
+	out := make(chan *model.NotificationEvent)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event model.NotificationEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					zlog.Logger.Error().Err(err).Msg("redis: bad event payload in pub/sub")
+					continue
+				}
+				select {
+				case out <- &event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *RedisEventRepository) ReadSince(ctx context.Context, lastID string, limit int64) ([]*model.NotificationEvent, error) {
+	messages, err := r.redisClient.XRangeN(ctx, r.stream, "("+lastID, "+", limit).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: read stream %s since %s: %w", r.stream, lastID, redisError(err, "xrange"))
+	}
+
+	result := make([]*model.NotificationEvent, 0, len(messages))
+	for _, msg := range messages {
+		raw, ok := msg.Values[eventDataField].(string)
+		if !ok {
+			continue
+		}
+		var event model.NotificationEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			zlog.Logger.Error().Err(err).Str("event_id", msg.ID).Msg("redis: bad event in stream")
+			continue
+		}
+		event.ID = msg.ID
+		result = append(result, &event)
+	}
+	return result, nil
+}
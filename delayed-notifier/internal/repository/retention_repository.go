@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+type RetentionRepository struct {
+	db       *dbpg.DB
+	strategy retry.Strategy
+}
+
+func NewRetentionRepository(db *dbpg.DB, strategy retry.Strategy) *RetentionRepository {
+	return &RetentionRepository{
+		db:       db,
+		strategy: strategy,
+	}
+}
+
+func (r *RetentionRepository) EnsurePartition(ctx context.Context, month time.Time) error {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	// имя и границы строим сами из времени, пользовательского ввода тут нет
+	query := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS notifier_db.public.notifications_archive_%s
+			PARTITION OF notifier_db.public.notifications_archive
+			FOR VALUES FROM ('%s') TO ('%s')`,
+		from.Format("2006_01"),
+		from.Format(time.RFC3339),
+		to.Format(time.RFC3339),
+	)
+	_, err := r.db.ExecWithRetry(ctx, r.strategy, query)
+	if err != nil {
+		return postgresError(err, "ensure archive partition")
+	}
+	return nil
+}
+
+// ArchiveBatch moves rows in a single statement, so a row is either in the hot table
+// or in the archive. SKIP LOCKED lets several replicas run the job at once. The audit
+// log, delivery attempts and callbacks of a notification go into its archive row in the
+// same statement; a notification with a callback still being retried is left for later.
+func (r *RetentionRepository) ArchiveBatch(ctx context.Context, status string, olderThan time.Time, limit int) (*model.ArchivedBatch, error) {
+	query := `WITH moved AS (
+			DELETE FROM notifier_db.public.notifications
+			WHERE id IN (
+				SELECT n.id FROM notifier_db.public.notifications n
+				WHERE n.status = $1 AND n.updated_at < $2
+					AND NOT EXISTS (
+						SELECT 1 FROM notifier_db.public.callbacks c
+						WHERE c.notification_id = n.id AND c.status = 'pending'
+					)
+				ORDER BY n.updated_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, recipient, channel, message, scheduled_at, status, tries, last_error,
+				callback_url, created_at, updated_at, deleted_at, subject, body_html, attachments, metadata,
+				user_id, category, channel_preferences, targets, target_index
+		), moved_events AS (
+			DELETE FROM notifier_db.public.notification_events e
+			USING moved
+			WHERE e.notification_id = moved.id
+			RETURNING e.*
+		), moved_attempts AS (
+			DELETE FROM notifier_db.public.delivery_attempts a
+			USING moved
+			WHERE a.notification_id = moved.id
+			RETURNING a.*
+		), moved_callbacks AS (
+			-- callback_attempts удаляются каскадом, но в снимке запроса еще видны
+			DELETE FROM notifier_db.public.callbacks c
+			USING moved
+			WHERE c.notification_id = moved.id
+			RETURNING c.*
+		), archived AS (
+			INSERT INTO notifier_db.public.notifications_archive
+				(id, recipient, channel, message, scheduled_at, status, tries, last_error,
+				callback_url, created_at, updated_at, deleted_at, subject, body_html, attachments, metadata,
+				user_id, category, channel_preferences, targets, target_index,
+				events, delivery_attempts, callbacks)
+			SELECT m.id, m.recipient, m.channel, m.message, m.scheduled_at, m.status, m.tries, m.last_error,
+				m.callback_url, m.created_at, m.updated_at, m.deleted_at, m.subject, m.body_html, m.attachments, m.metadata,
+				m.user_id, m.category, m.channel_preferences, m.targets, m.target_index,
+				(SELECT jsonb_agg(to_jsonb(e) - 'notification_id' ORDER BY e.id)
+					FROM moved_events e WHERE e.notification_id = m.id),
+				(SELECT jsonb_agg(to_jsonb(a) - 'notification_id' ORDER BY a.id)
+					FROM moved_attempts a WHERE a.notification_id = m.id),
+				(SELECT jsonb_agg((to_jsonb(c) - 'notification_id') || jsonb_build_object('attempts', (
+						SELECT COALESCE(jsonb_agg(to_jsonb(ca) - 'callback_id' ORDER BY ca.id), '[]'::jsonb)
+						FROM notifier_db.public.callback_attempts ca WHERE ca.callback_id = c.id
+					)) ORDER BY c.created_at, c.id)
+					FROM moved_callbacks c WHERE c.notification_id = m.id)
+			FROM moved m
+			RETURNING 1
+		)
+		SELECT (SELECT count(*) FROM archived),
+			(SELECT count(*) FROM moved_events),
+			(SELECT count(*) FROM moved_attempts),
+			(SELECT count(*) FROM moved_callbacks)`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.strategy, query, status, olderThan, limit)
+	if err != nil {
+		return nil, postgresError(err, "archive batch")
+	}
+
+	var batch model.ArchivedBatch
+	if err := row.Scan(&batch.Notifications, &batch.Events, &batch.DeliveryAttempts, &batch.Callbacks); err != nil {
+		return nil, postgresError(err, "archive batch")
+	}
+	return &batch, nil
+}
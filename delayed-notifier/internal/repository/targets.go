@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+// newTargetsColumn holds a fallback chain as the JSONB targets column, NULL without one.
+func newTargetsColumn(targets []model.Target) (*string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal targets: %w", err)
+	}
+	return nullString(string(data)), nil
+}
+
+func parseTargets(column *string) ([]model.Target, error) {
+	if column == nil {
+		return nil, nil
+	}
+	var targets []model.Target
+	if err := json.Unmarshal([]byte(*column), &targets); err != nil {
+		return nil, fmt.Errorf("invalid targets in postgres: %w", err)
+	}
+	return targets, nil
+}
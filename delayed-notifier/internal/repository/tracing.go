@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/repository")
+
+// startQuerySpan starts a client span for one query against the notifications table.
+// Retries done by dbpg stay inside it, so the span shows the total time the caller waited.
+func startQuerySpan(ctx context.Context, method string, operation string, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "StoreRepository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBCollectionName("notifications"),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// failSpan records err on span and returns it unchanged.
+func failSpan(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
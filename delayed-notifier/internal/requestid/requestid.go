@@ -0,0 +1,20 @@
+// Package requestid carries the ID of the API request that created a notification,
+// so the worker's logs for it can be matched with the caller's.
+package requestid
+
+import "context"
+
+// Header is read from the caller and echoed back; a new ID is generated without it.
+const Header = "X-Request-ID"
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID or "" outside of a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
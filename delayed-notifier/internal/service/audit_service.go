@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// AuditService reads the append-only log of notification transitions. The log is
+// written by the repository in the same statement as the transition itself.
+type AuditService struct {
+	repo ports.AuditRepository
+}
+
+func NewAuditService(repo ports.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// GetHistory returns the timeline of a notification, including deleted ones.
+func (s *AuditService) GetHistory(ctx context.Context, notificationID types.UUID) ([]*model.HistoryEntry, error) {
+	history, err := s.repo.GetHistory(ctx, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting history from storage: %w", err)
+	}
+	// every notification has at least the 'created' entry
+	if len(history) == 0 {
+		return nil, apperrors.NotFound(apperrors.CodeNotFound, "notification not found", nil)
+	}
+	return history, nil
+}
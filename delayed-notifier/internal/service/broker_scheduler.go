@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wb-go/wbf/zlog"
+)
+
+var (
+	schedulerEnqueuedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_broker_enqueued_total",
+			Help: "Created notifications handed to the delayed exchange by result (success / error / deferred, too far ahead)",
+		},
+		[]string{"result"},
+	)
+	schedulerDueTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_broker_due_total",
+			Help: "Notifications returned by the delayed exchange by outcome (dispatched / stale, cancelled or sent meanwhile)",
+		},
+		[]string{"outcome"},
+	)
+	schedulerDueLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "scheduler_broker_due_lag_seconds",
+			Help:    "How late the delayed exchange returned a notification relative to scheduled_at",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(schedulerEnqueuedTotal, schedulerDueTotal, schedulerDueLag)
+}
+
+// BrokerScheduler implements the broker scheduling strategy: a notification is
+// published to RabbitMQ's delayed exchange as soon as it is created, and the broker
+// returns it to the due queue at scheduled_at. Postgres stays the source of truth:
+// a returned notification is sent only if it is still pending, so cancellations made
+// in between are respected. Whatever the broker doesn't return (the publication
+// failed, the delay is beyond MaxDelay, the message was lost) is left to SendService's
+// poller.
+type BrokerScheduler struct {
+	storageRepo ports.DelayedStoreRepository
+	publisher   ports.DelayedPublisherRepository
+	receiver    ports.DueReceiver
+	sender      *SendService
+	maxDelay    time.Duration
+}
+
+func NewBrokerScheduler(
+	storageRepo ports.DelayedStoreRepository,
+	publisher ports.DelayedPublisherRepository,
+	receiver ports.DueReceiver,
+	sender *SendService,
+	cfg config.SchedulerConfig,
+) *BrokerScheduler {
+	return &BrokerScheduler{
+		storageRepo: storageRepo,
+		publisher:   publisher,
+		receiver:    receiver,
+		sender:      sender,
+		maxDelay:    cfg.MaxDelay,
+	}
+}
+
+// OnTransition is a lifecycle hook scheduling every created notification.
+func (b *BrokerScheduler) OnTransition(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) error {
+	if event.Type != model.EventCreated {
+		return nil
+	}
+	return b.Schedule(ctx, notify)
+}
+
+// Schedule publishes the notification to the delayed exchange. It is marked as
+// enqueued first, so the poller doesn't publish it too while the delay runs.
+func (b *BrokerScheduler) Schedule(ctx context.Context, notify *model.Notification) error {
+	if time.Until(notify.ScheduledAt) > b.maxDelay {
+		// поллер опубликует его, когда подойдет срок
+		schedulerEnqueuedTotal.WithLabelValues("deferred").Inc()
+		return nil
+	}
+
+	if err := b.storageRepo.MarkAsEnqueued(ctx, notify.ID); err != nil {
+		schedulerEnqueuedTotal.WithLabelValues("error").Inc()
+		return fmt.Errorf("couldn't mark notification as enqueued, leaving it to the poller: %w", err)
+	}
+	if err := b.publisher.SendDelayed(ctx, notify); err != nil {
+		schedulerEnqueuedTotal.WithLabelValues("error").Inc()
+		if unmarkErr := b.storageRepo.UnmarkEnqueued(ctx, notify.ID); unmarkErr != nil {
+			// поллер все равно заберет его, но только через EnqueuedGrace после scheduled_at
+			zlog.Logger.Error().Err(unmarkErr).Stringer("notification_id", notify.ID).Msg("couldn't hand notification back to the poller")
+		}
+		return fmt.Errorf("couldn't publish notification to the delayed exchange, leaving it to the poller: %w", err)
+	}
+	schedulerEnqueuedTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+// Run consumes the due queue until ctx is cancelled.
+func (b *BrokerScheduler) Run(ctx context.Context) {
+	err := b.receiver.Receive(ctx, b.handleDue)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		zlog.Logger.Error().Err(err).Msg("due receiver stopped")
+	}
+}
+
+func (b *BrokerScheduler) handleDue(ctx context.Context, due *model.DueNotification) error {
+	ctx = actor.WithActor(ctx, actor.Scheduler)
+	notify, err := b.storageRepo.FetchDue(ctx, due.ID, due.ScheduledAt)
+	if err != nil {
+		return fmt.Errorf("couldn't load due notification: %w", err)
+	}
+	if notify == nil {
+		schedulerDueTotal.WithLabelValues("stale").Inc()
+		zlog.Logger.Debug().Stringer("notification_id", due.ID).Msg("due notification is no longer pending, skipping")
+		return nil
+	}
+
+	schedulerDueTotal.WithLabelValues("dispatched").Inc()
+	schedulerDueLag.Observe(max(time.Since(notify.ScheduledAt).Seconds(), 0))
+	// неудача уже учтена в tries, повторную публикацию сделает поллер
+	if err := b.sender.SendBatch(ctx, []*model.Notification{notify}); err != nil {
+		zlog.Logger.Error().Err(err).Stringer("notification_id", notify.ID).Msg("failed to publish due notification")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/wb-go/wbf/zlog"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	callbackSignatureHeader = "X-Notifier-Signature"
+	callbackEventHeader     = "X-Notifier-Event"
+	callbackDeliveryHeader  = "X-Notifier-Delivery"
+	callbackUserAgent       = "delayed-notifier-callbacks/1.0"
+	callbackMaxBodyRead     = 64 << 10
+)
+
+// CallbackService posts status-change events to notification creators.
+// Events are stored first (outbox) and delivered by Run with exponential backoff,
+// so they survive restarts and are shared between replicas.
+type CallbackService struct {
+	repo        ports.CallbackRepository
+	client      *http.Client
+	secret      []byte
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	pollPeriod  time.Duration
+	batchSize   int
+	workers     int
+}
+
+func NewCallbackService(repo ports.CallbackRepository, cfg config.CallbacksConfig) *CallbackService {
+	// callback_url задает клиент API: без проверки адреса сервис подписывал бы запросы во внутреннюю сеть
+	client := egress.NewClient(time.Duration(cfg.TimeoutMs)*time.Millisecond, egress.Policy{
+		AllowedHosts: cfg.AllowedHosts,
+		AllowPrivate: cfg.AllowPrivate,
+	})
+	return &CallbackService{
+		repo:        repo,
+		client:      client,
+		secret:      []byte(cfg.Secret),
+		maxAttempts: cfg.MaxAttempts,
+		baseDelay:   time.Duration(cfg.BaseDelayMs) * time.Millisecond,
+		maxDelay:    time.Duration(cfg.MaxDelayMs) * time.Millisecond,
+		pollPeriod:  time.Duration(cfg.PollPeriodMs) * time.Millisecond,
+		batchSize:   cfg.BatchSize,
+		workers:     cfg.Workers,
+	}
+}
+
+// OnTransition is a lifecycle hook that schedules a callback for the transition.
+// Creation is not reported: the creator already has the POST /notify response.
+func (s *CallbackService) OnTransition(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) error {
+	if notify.CallbackURL == "" || event.Type == model.EventCreated {
+		return nil
+	}
+
+	payload, err := json.Marshal(dto.ToCallbackEventFromModel(event))
+	if err != nil {
+		return fmt.Errorf("couldn't marshal callback payload: %w", err)
+	}
+
+	id := types.GenerateUUID()
+	err = s.repo.CreateCallback(ctx, &model.Callback{
+		ID:             &id,
+		NotificationID: notify.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		URL:            notify.CallbackURL,
+		Payload:        payload,
+		Status:         model.CallbackPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't schedule callback: %w", err)
+	}
+	return nil
+}
+
+func (s *CallbackService) GetCallbacks(ctx context.Context, notificationID types.UUID) ([]*model.Callback, error) {
+	callbacks, err := s.repo.GetCallbacks(ctx, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting callbacks from storage: %w", err)
+	}
+	return callbacks, nil
+}
+
+func (s *CallbackService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.pollPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx)
+		}
+	}
+}
+
+func (s *CallbackService) deliverDue(ctx context.Context) {
+	// the lease must outlive the whole batch, otherwise another replica picks it up again
+	rounds := (s.batchSize + s.workers - 1) / s.workers
+	lease := time.Duration(rounds+1) * s.client.Timeout
+
+	callbacks, err := s.repo.FetchDueCallbacks(ctx, s.batchSize, lease)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to fetch due callbacks")
+		return
+	}
+
+	var errGroup errgroup.Group
+	errGroup.SetLimit(s.workers)
+	for _, cb := range callbacks {
+		errGroup.Go(func() error {
+			s.deliver(ctx, cb)
+			return nil
+		})
+	}
+	_ = errGroup.Wait()
+}
+
+func (s *CallbackService) deliver(ctx context.Context, cb *model.Callback) {
+	attempt := &model.CallbackAttempt{
+		Attempt:     cb.Attempts + 1,
+		AttemptedAt: time.Now(),
+	}
+
+	statusCode, retryAfter, err := s.post(ctx, cb)
+	attempt.Duration = time.Since(attempt.AttemptedAt)
+
+	retryable := true
+	switch {
+	case err != nil:
+		msg := err.Error()
+		attempt.Error = &msg
+		// запрос на запрещенный адрес не отправлялся и не отправится при повторе
+		retryable = !errors.Is(err, egress.ErrBlocked)
+	case statusCode >= 200 && statusCode < 300:
+		attempt.StatusCode = &statusCode
+	default:
+		attempt.StatusCode = &statusCode
+		msg := fmt.Sprintf("unexpected response status %d", statusCode)
+		attempt.Error = &msg
+		// other 4xx mean the receiver rejected the event, repeating won't help
+		retryable = statusCode >= 500 || statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+	}
+
+	cb.Attempts = attempt.Attempt
+	switch {
+	case attempt.Error == nil:
+		cb.Status = model.CallbackDelivered
+	case !retryable || cb.Attempts >= s.maxAttempts:
+		cb.Status = model.CallbackFailed
+	default:
+		cb.Status = model.CallbackPending
+		cb.NextAttemptAt = time.Now().Add(max(s.backoff(cb.Attempts), retryAfter))
+	}
+
+	if err := s.repo.RecordAttempt(ctx, cb, attempt); err != nil {
+		zlog.Logger.Error().Err(err).Stringer("callback_id", cb.ID).Msg("failed to record callback attempt")
+		return
+	}
+
+	logEvent := zlog.Logger.Debug()
+	if cb.Status == model.CallbackFailed {
+		logEvent = zlog.Logger.Warn()
+	}
+	logEvent.
+		Stringer("callback_id", cb.ID).
+		Stringer("notification_id", cb.NotificationID).
+		Int("attempt", cb.Attempts).
+		Str("status", cb.Status).
+		Msg("callback attempt finished")
+}
+
+// post sends the callback and returns the response status and the Retry-After delay, if any.
+func (s *CallbackService) post(ctx context.Context, cb *model.Callback) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cb.URL, bytes.NewReader(cb.Payload))
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't build callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", callbackUserAgent)
+	req.Header.Set(callbackEventHeader, cb.EventType)
+	req.Header.Set(callbackDeliveryHeader, cb.ID.String())
+	req.Header.Set(callbackSignatureHeader, s.sign(time.Now(), cb.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, callbackMaxBodyRead))
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = min(time.Duration(seconds)*time.Second, s.maxDelay)
+	}
+	return resp.StatusCode, retryAfter, nil
+}
+
+// sign returns "t=<unix>,v1=<hex hmac-sha256 of "<unix>.<body>">".
+// Receivers should recompute the HMAC and reject stale timestamps.
+func (s *CallbackService) sign(at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff doubles the delay with every attempt and adds jitter so receivers
+// recovering from an outage aren't hit by every callback at once.
+func (s *CallbackService) backoff(attempt int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempt && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, s.maxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -4,58 +4,154 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 	"github.com/wb-go/wbf/zlog"
 	"golang.org/x/sync/errgroup"
 )
 
-type SignalFunc func(ctx context.Context, notify *model.Notification) error
-
 type CRUDService struct {
-	storageRepo  ports.CRUDStoreRepositoryInterface
-	redisRepo    ports.CRUDRedisRepositoryInterface
-	funcOnCreate SignalFunc
+	storageRepo ports.CRUDStoreRepositoryInterface
+	redisRepo   ports.CRUDRedisRepositoryInterface
+	hooks       ports.LifecycleHooks
+	channels    ports.ChannelRegistry
+	recipients  ports.RecipientRegistry
+	directory   ports.RecipientDirectory
 }
 
 func NewCrudService(
 	storageRepo ports.CRUDStoreRepositoryInterface,
 	redisRepo ports.CRUDRedisRepositoryInterface,
-	funcOnCreate SignalFunc,
+	hooks ports.LifecycleHooks,
+	channels ports.ChannelRegistry,
+	recipients ports.RecipientRegistry,
+	directory ports.RecipientDirectory,
 ) *CRUDService {
 	return &CRUDService{
-		storageRepo:  storageRepo,
-		redisRepo:    redisRepo,
-		funcOnCreate: funcOnCreate,
+		storageRepo: storageRepo,
+		redisRepo:   redisRepo,
+		hooks:       hooks,
+		channels:    channels,
+		recipients:  recipients,
+		directory:   directory,
 	}
 }
 
 func (s *CRUDService) CreateNotification(ctx context.Context, notify *model.Notification) (*model.Notification, error) {
+	var err error
+	if notify.UserID != "" {
+		err = s.checkUser(ctx, notify)
+	} else {
+		err = s.checkRecipient(ctx, notify)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	uuid := types.GenerateUUID()
 	notify.ID = &uuid
+	notify.Status = model.StatusPending
 
-	err := s.storageRepo.CreateNotify(ctx, notify)
+	err = s.storageRepo.CreateNotify(ctx, notify)
 	if err != nil {
-		return nil, fmt.Errorf("notification storage failed to create: %v", err)
+		return nil, fmt.Errorf("notification storage failed to create: %w", err)
 	}
 
 	s.trySaveInCache(ctx, notify)
-
-	if s.funcOnCreate != nil {
-		go func(notify *model.Notification) {
-			err := s.funcOnCreate(ctx, notify)
-			if err != nil {
-				zlog.Logger.Error().Err(err).Msg(fmt.Sprintf("error in funcOnCreate %v", s.funcOnCreate))
-			}
-		}(notify)
-	}
+	fire(ctx, s.hooks, model.EventCreated, notify, model.StatusPending, nil)
 	zlog.Logger.Info().Msg("success create notification")
 
 	return notify, nil
 }
 
+func (s *CRUDService) checkRecipient(ctx context.Context, notify *model.Notification) error {
+	// без воркера, объявившего очередь канала, уведомление так и осталось бы в брокере;
+	// у цепочки проверяем каналы всех целей, до запасных может дойти очередь
+	channels := []string{notify.Channel.String()}
+	for _, target := range notify.Targets {
+		if !slices.Contains(channels, target.Channel) {
+			channels = append(channels, target.Channel)
+		}
+	}
+	if err := s.checkChannels(ctx, channels); err != nil {
+		return err
+	}
+
+	// провайдер уже ответил, что получателя нет (удаленный push-токен): не отправляем заведомо в dead-letter.
+	// Цепочку отклоняем, только если недоступны все цели: с недоступной уведомление перейдет на следующую
+	targets := notify.Targets
+	if len(targets) == 0 {
+		targets = []model.Target{{Channel: notify.Channel.String(), Recipient: notify.Recipient.String()}}
+	}
+	for _, target := range targets {
+		unreachable, err := s.recipients.IsUnreachable(ctx, target.Channel, target.Recipient)
+		if err != nil {
+			return fmt.Errorf("error checking recipient: %w", err)
+		}
+		if !unreachable {
+			return nil
+		}
+	}
+	if len(notify.Targets) > 1 {
+		return apperrors.Validation(apperrors.CodeRecipientUnreachable, "every target of the chain is unreachable", nil)
+	}
+	return apperrors.Validation(apperrors.CodeRecipientUnreachable,
+		fmt.Sprintf("recipient is unreachable on channel '%s'", notify.Channel), nil)
+}
+
+// checkChannels rejects the notification if a worker accepts none of its channels.
+func (s *CRUDService) checkChannels(ctx context.Context, channels []string) error {
+	for _, channel := range channels {
+		available, err := s.channels.Available(ctx, channel)
+		if err != nil {
+			return fmt.Errorf("error checking channel availability: %w", err)
+		}
+		if !available {
+			return apperrors.Validation(apperrors.CodeChannelUnavailable,
+				fmt.Sprintf("no worker accepts notifications for channel '%s'", channel), nil)
+		}
+	}
+	return nil
+}
+
+// checkUser accepts a notification addressed to a user of the directory. Contacts and
+// opt-outs are checked when it is sent: they may change until then. The channels asked
+// for in notify.Channels must all have a worker, like the targets of a chain; in the
+// order of the user's contacts, where channels without a worker are skipped, one is enough.
+func (s *CRUDService) checkUser(ctx context.Context, notify *model.Notification) error {
+	profile, err := s.directory.GetProfile(ctx, notify.UserID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return apperrors.Validation(apperrors.CodeUnknownUser,
+			fmt.Sprintf("user '%s' is not in the recipient directory", notify.UserID), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("error checking user: %w", err)
+	}
+
+	if len(notify.Channels) > 0 {
+		return s.checkChannels(ctx, notify.Channels)
+	}
+	if len(profile.Contacts) == 0 {
+		// контакты могут добавить до отправки
+		return nil
+	}
+	for _, contact := range profile.Contacts {
+		available, err := s.channels.Available(ctx, contact.Channel)
+		if err != nil {
+			return fmt.Errorf("error checking channel availability: %w", err)
+		}
+		if available {
+			return nil
+		}
+	}
+	return apperrors.Validation(apperrors.CodeChannelUnavailable,
+		fmt.Sprintf("no worker accepts notifications for any channel of user '%s'", notify.UserID), nil)
+}
+
 func (s *CRUDService) GetNotification(ctx context.Context, id types.UUID) (*model.Notification, error) {
 	result, err := s.getObjectFromCache(ctx, id)
 	if err != nil {
@@ -86,25 +182,50 @@ func (s *CRUDService) GetAllNotifications(ctx context.Context) ([]*model.Notific
 	return result, nil
 }
 
+// GetAttempts returns the delivery attempts of the notification across its targets.
+func (s *CRUDService) GetAttempts(ctx context.Context, id types.UUID) ([]*model.DeliveryAttempt, error) {
+	attempts, err := s.storageRepo.GetAttempts(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting delivery attempts from storage: %w", err)
+	}
+	return attempts, nil
+}
+
 func (s *CRUDService) DeleteNotification(ctx context.Context, id types.UUID) error {
 	object, err := s.GetNotification(ctx, id)
 	if err != nil {
 		return fmt.Errorf("error checking object existence: %w", err)
 	}
 
-	if object == nil || object.ID == nil {
-		return errors.New("notification not found")
+	if object == nil {
+		return apperrors.NotFound(apperrors.CodeNotFound, "notification not found", nil)
 	}
 
 	var errGroup errgroup.Group
+	var previousStatus string
 
 	errGroup.Go(func() error {
-		return s.storageRepo.DeleteNotification(ctx, id)
+		var err error
+		previousStatus, err = s.storageRepo.DeleteNotification(ctx, id)
+		return err
 	})
 	errGroup.Go(func() error {
-		return s.redisRepo.DeleteNotification(ctx, id)
+		// cache is best effort, stale entry expires anyway
+		if err := s.redisRepo.DeleteNotification(ctx, id); err != nil {
+			zlog.Logger.Warn().Err(err).Str("id", id.String()).Msg("failed to delete notification from cache")
+		}
+		return nil
 	})
-	errGroup.Wait()
+	if err = errGroup.Wait(); err != nil {
+		return fmt.Errorf("error deleting notification: %w", err)
+	}
+	// запись остается в базе (мягкое удаление), еще не отправленное уведомление отменяется
+	object.Status = previousStatus
+	if previousStatus == model.StatusPending {
+		object.Status = model.StatusCancelled
+		fire(ctx, s.hooks, model.EventCancelled, object, model.StatusCancelled, nil)
+	}
+	fire(ctx, s.hooks, model.EventDeleted, object, object.Status, nil)
 	zlog.Logger.Info().Msg("success delete notification")
 	return nil
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+)
+
+// unreachableRecipients knows the listed channel:recipient pairs as unreachable.
+type unreachableRecipients map[string]bool
+
+func (r unreachableRecipients) MarkUnreachable(ctx context.Context, notify *model.Notification, reason string) error {
+	return nil
+}
+
+func (r unreachableRecipients) IsUnreachable(ctx context.Context, channel, recipient string) (bool, error) {
+	return r[channel+":"+recipient], nil
+}
+
+func TestCheckRecipientUnreachableTargets(t *testing.T) {
+	recipients := unreachableRecipients{"push:fcm:gone": true, "email:old@example.com": true}
+	s := NewCrudService(nil, nil, nil, fakeChannels{}, recipients, nil)
+
+	cases := []struct {
+		name        string
+		channel     domain.NotificationChannel
+		recipient   string
+		targets     []model.Target
+		unreachable bool
+	}{
+		{name: "single reachable", channel: domain.ChannelEmail, recipient: "a@example.com"},
+		{name: "single unreachable", channel: domain.ChannelPush, recipient: "fcm:gone", unreachable: true},
+		{
+			// с недоступной первой цели уведомление перейдет на запасную
+			name: "chain with a reachable fallback", channel: domain.ChannelPush, recipient: "fcm:gone",
+			targets: []model.Target{{Channel: "push", Recipient: "fcm:gone"}, {Channel: "email", Recipient: "a@example.com"}},
+		},
+		{
+			name: "chain with an unreachable fallback", channel: domain.ChannelEmail, recipient: "a@example.com",
+			targets: []model.Target{{Channel: "email", Recipient: "a@example.com"}, {Channel: "push", Recipient: "fcm:gone"}},
+		},
+		{
+			name: "chain of unreachable targets", channel: domain.ChannelPush, recipient: "fcm:gone",
+			targets:     []model.Target{{Channel: "push", Recipient: "fcm:gone"}, {Channel: "email", Recipient: "old@example.com"}},
+			unreachable: true,
+		},
+	}
+	for _, tc := range cases {
+		notify := &model.Notification{
+			Channel:   tc.channel,
+			Recipient: domain.RecipientFromString(tc.recipient),
+			Targets:   tc.targets,
+		}
+		err := s.checkRecipient(context.Background(), notify)
+		var appErr *apperrors.Error
+		gotUnreachable := errors.As(err, &appErr) && appErr.Code == apperrors.CodeRecipientUnreachable
+		if gotUnreachable != tc.unreachable || (err != nil && !gotUnreachable) {
+			t.Errorf("%s: got %v, want recipient unreachable=%v", tc.name, err, tc.unreachable)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type eventSubscriber struct {
+	filter model.EventFilter
+	events chan *model.NotificationEvent
+}
+
+// EventService fans events from the shared bus out to local stream clients.
+// Every replica holds one bus subscription no matter how many clients are connected.
+type EventService struct {
+	bus            ports.EventBusRepository
+	bufferSize     int
+	backlogLimit   int64
+	reconnectDelay time.Duration
+
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+}
+
+func NewEventService(bus ports.EventBusRepository, bufferSize int, backlogLimit int64) *EventService {
+	return &EventService{
+		bus:            bus,
+		bufferSize:     bufferSize,
+		backlogLimit:   backlogLimit,
+		reconnectDelay: time.Second,
+		subscribers:    make(map[*eventSubscriber]struct{}),
+	}
+}
+
+func (s *EventService) Run(ctx context.Context) {
+	for {
+		events, err := s.bus.Subscribe(ctx)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("event service: failed to subscribe to event bus")
+		} else {
+			for event := range events {
+				s.broadcast(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			s.closeAll()
+			return
+		case <-time.After(s.reconnectDelay):
+		}
+	}
+}
+
+// Subscribe streams events matching filter. If lastEventID is set, stored events
+// after it are replayed first; when more than backlogLimit of them were missed, a
+// single EventReset is sent instead. The channel is closed when ctx is done or the
+// client is too slow to keep up; the client should then resume with the last id it got.
+func (s *EventService) Subscribe(ctx context.Context, filter model.EventFilter, lastEventID string) (<-chan *model.NotificationEvent, error) {
+	sub := &eventSubscriber{
+		filter: filter,
+		events: make(chan *model.NotificationEvent, s.bufferSize),
+	}
+	// register before reading the backlog so nothing published in between is lost
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	var backlog []*model.NotificationEvent
+	if lastEventID != "" {
+		var err error
+		// на одно событие больше лимита: так видно, что история не поместилась
+		backlog, err = s.bus.ReadSince(ctx, lastEventID, s.backlogLimit+1)
+		if err != nil {
+			s.unsubscribe(sub)
+			return nil, fmt.Errorf("couldn't read events since '%s': %w", lastEventID, err)
+		}
+	}
+	// события живого потока, пришедшие, пока читалась история, могут в ней уже быть
+	overlapEnd := lastEventID
+	if len(backlog) > 0 {
+		overlapEnd = backlog[len(backlog)-1].ID
+	}
+	if int64(len(backlog)) > s.backlogLimit {
+		backlog = []*model.NotificationEvent{{Type: model.EventReset, At: time.Now().UTC()}}
+	}
+
+	out := make(chan *model.NotificationEvent)
+	go func() {
+		defer close(out)
+		defer s.unsubscribe(sub)
+
+		send := func(event *model.NotificationEvent) bool {
+			if event.Type != model.EventReset && !filter.Match(event) {
+				return true
+			}
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.events:
+				if !ok {
+					return
+				}
+				// Publish добавляет событие в stream и рассылает его разными командами, поэтому
+				// живые события разных реплик могут прийти не по порядку id: отбрасываем только
+				// уже отданные из истории
+				if overlapEnd != "" && !eventIDAfter(event.ID, overlapEnd) {
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *EventService) broadcast(event *model.NotificationEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			zlog.Logger.Warn().Msg("event service: dropping slow subscriber")
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (s *EventService) unsubscribe(sub *eventSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (s *EventService) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// eventIDAfter compares redis stream ids ("<ms>-<seq>").
+func eventIDAfter(id string, than string) bool {
+	idMs, idSeq, okID := parseEventID(id)
+	thanMs, thanSeq, okThan := parseEventID(than)
+	if !okID || !okThan {
+		return true
+	}
+	if idMs != thanMs {
+		return idMs > thanMs
+	}
+	return idSeq > thanSeq
+}
+
+func parseEventID(id string) (uint64, uint64, bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !found {
+		return ms, 0, true
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
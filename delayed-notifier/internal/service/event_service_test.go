@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+)
+
+// fakeEventBus returns a fixed history; live events are broadcast by the test.
+type fakeEventBus struct {
+	history []*model.NotificationEvent
+}
+
+func (b *fakeEventBus) Publish(ctx context.Context, event *model.NotificationEvent) error {
+	return nil
+}
+
+func (b *fakeEventBus) Subscribe(ctx context.Context) (<-chan *model.NotificationEvent, error) {
+	return make(chan *model.NotificationEvent), nil
+}
+
+func (b *fakeEventBus) ReadSince(ctx context.Context, lastID string, limit int64) ([]*model.NotificationEvent, error) {
+	var result []*model.NotificationEvent
+	for _, event := range b.history {
+		if eventIDAfter(event.ID, lastID) && int64(len(result)) < limit {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func testEvent(id string) *model.NotificationEvent {
+	return &model.NotificationEvent{ID: id, Type: model.EventDispatched, Channel: "email", Status: model.StatusSent}
+}
+
+// receiveIDs reads n events and returns their ids, or the types of events without one.
+func receiveIDs(t *testing.T, events <-chan *model.NotificationEvent, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < n; i++ {
+		select {
+		case event := <-events:
+			if event.ID == "" {
+				ids = append(ids, event.Type)
+			} else {
+				ids = append(ids, event.ID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v, want %d events", ids, n)
+		}
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSubscribeDeliversLiveEventsOutOfOrder(t *testing.T) {
+	for _, lastEventID := range []string{"", "100-0"} {
+		s := NewEventService(&fakeEventBus{}, 10, 100)
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := s.Subscribe(ctx, model.NewEventFilter(nil, nil), lastEventID)
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+
+		// две реплики добавили события в stream в одном порядке, а разослали в другом
+		s.broadcast(testEvent("200-1"))
+		s.broadcast(testEvent("200-0"))
+		s.broadcast(testEvent("201-0"))
+		assertIDs(t, receiveIDs(t, events, 3), "200-1", "200-0", "201-0")
+		cancel()
+	}
+}
+
+func TestSubscribeSkipsLiveEventsAlreadyReplayed(t *testing.T) {
+	bus := &fakeEventBus{history: []*model.NotificationEvent{testEvent("1-0"), testEvent("2-0"), testEvent("3-0")}}
+	s := NewEventService(bus, 10, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Subscribe(ctx, model.NewEventFilter(nil, nil), "1-0")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// 3-0 разослано, пока читалась история, и уже есть в ней
+	s.broadcast(testEvent("3-0"))
+	s.broadcast(testEvent("4-0"))
+	assertIDs(t, receiveIDs(t, events, 3), "2-0", "3-0", "4-0")
+}
+
+func TestSubscribeSendsResetWhenBacklogOverflows(t *testing.T) {
+	bus := &fakeEventBus{}
+	for _, id := range []string{"1-0", "2-0", "3-0", "4-0"} {
+		bus.history = append(bus.history, testEvent(id))
+	}
+	// фильтр не должен скрыть сброс
+	filter := model.NewEventFilter([]string{"telegram"}, nil)
+	s := NewEventService(bus, 10, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Subscribe(ctx, filter, "1-0")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	live := testEvent("5-0")
+	live.Channel = "telegram"
+	s.broadcast(live)
+	assertIDs(t, receiveIDs(t, events, 2), model.EventReset, "5-0")
+}
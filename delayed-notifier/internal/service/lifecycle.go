@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// SignalFunc is a lifecycle hook called on every notification status transition.
+type SignalFunc func(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) error
+
+type lifecycleHook struct {
+	name string
+	fn   SignalFunc
+}
+
+// Lifecycle runs hooks in registration order. Hooks are best effort: a failing
+// hook neither stops the others nor fails the operation that caused the transition.
+type Lifecycle struct {
+	hooks []lifecycleHook
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+func (l *Lifecycle) On(name string, fn SignalFunc) *Lifecycle {
+	l.hooks = append(l.hooks, lifecycleHook{name: name, fn: fn})
+	return l
+}
+
+func (l *Lifecycle) Fire(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) {
+	for _, hook := range l.hooks {
+		if err := hook.fn(ctx, event, notify); err != nil {
+			zlog.Logger.Error().
+				Err(err).
+				Str("hook", hook.name).
+				Str("type", event.Type).
+				Str("notification_id", event.NotificationID).
+				Msg("lifecycle hook failed")
+		}
+	}
+}
+
+// EventHook publishes transitions to the event bus. Register it before hooks
+// that need event.ID, it is assigned on publish.
+func EventHook(publisher ports.EventPublisher) SignalFunc {
+	return func(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) error {
+		return publisher.Publish(ctx, event)
+	}
+}
+
+// CancelHook tells the workers to drop a deleted notification that was already
+// published to them: it may still be waiting for its scheduled_at in a worker's heap.
+// Pending notifications are cancelled in storage and never reach the workers.
+func CancelHook(publisher ports.CancelPublisherRepository) SignalFunc {
+	return func(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) error {
+		if event.Type != model.EventDeleted || event.Status != model.StatusSent {
+			return nil
+		}
+		return publisher.SendCancellation(ctx, notify)
+	}
+}
+
+func fire(ctx context.Context, hooks ports.LifecycleHooks, eventType string, notify *model.Notification, status string, cause error) {
+	if hooks == nil {
+		return
+	}
+	event := model.NewNotificationEvent(eventType, notify, status)
+	event.Actor = actor.FromContext(ctx)
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+	hooks.Fire(ctx, event, notify)
+}
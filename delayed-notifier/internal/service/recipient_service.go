@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wb-go/wbf/zlog"
+)
+
+var (
+	recipientResolutionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "recipient_resolutions_total",
+			Help: "Notifications addressed to a user by outcome (resolved / opted_out / no_contact / error)",
+		},
+		[]string{"outcome"},
+	)
+	recipientRetractionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "recipient_retractions_total",
+			Help: "Published notifications taken back from the workers after a directory change, by new status (pending / cancelled / failed)",
+		},
+		[]string{"status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(recipientResolutionsTotal, recipientRetractionsTotal)
+}
+
+var (
+	// ErrRecipientOptedOut means the user has contacts for the notification but opted
+	// out of all of them; the notification is cancelled.
+	ErrRecipientOptedOut = errors.New("user opted out")
+	// ErrNoRecipientContact means no channel can deliver the notification to the user;
+	// the notification fails.
+	ErrNoRecipientContact = errors.New("no deliverable contact")
+)
+
+// RecipientService keeps the recipient directory and resolves notifications addressed
+// to its users.
+type RecipientService struct {
+	directory  ports.RecipientDirectory
+	recipients ports.RecipientRegistry
+	channels   ports.ChannelRegistry
+	inFlight   ports.InFlightRepository
+	cancels    ports.CancelPublisherRepository
+	hooks      ports.LifecycleHooks
+}
+
+func NewRecipientService(
+	directory ports.RecipientDirectory,
+	recipients ports.RecipientRegistry,
+	channels ports.ChannelRegistry,
+	inFlight ports.InFlightRepository,
+	cancels ports.CancelPublisherRepository,
+	hooks ports.LifecycleHooks,
+) *RecipientService {
+	return &RecipientService{
+		directory:  directory,
+		recipients: recipients,
+		channels:   channels,
+		inFlight:   inFlight,
+		cancels:    cancels,
+		hooks:      hooks,
+	}
+}
+
+func (s *RecipientService) SaveRecipient(ctx context.Context, profile *model.RecipientProfile) (*model.RecipientProfile, error) {
+	if err := s.directory.SaveProfile(ctx, profile); err != nil {
+		return nil, fmt.Errorf("recipient storage failed to save: %w", err)
+	}
+	zlog.Logger.Info().Str("user_id", profile.UserID).Msg("success save recipient")
+	s.recheckInFlight(ctx, profile.UserID)
+	return profile, nil
+}
+
+func (s *RecipientService) GetRecipient(ctx context.Context, userID string) (*model.RecipientProfile, error) {
+	profile, err := s.directory.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting recipient from storage: %w", err)
+	}
+	return profile, nil
+}
+
+func (s *RecipientService) DeleteRecipient(ctx context.Context, userID string) error {
+	if err := s.directory.DeleteProfile(ctx, userID); err != nil {
+		return fmt.Errorf("error deleting recipient: %w", err)
+	}
+	zlog.Logger.Info().Str("user_id", userID).Msg("success delete recipient")
+	s.recheckInFlight(ctx, userID)
+	return nil
+}
+
+// recheckInFlight resolves the user's published but undelivered notifications again, so
+// that a changed contact or an opt-out applies to notifications already waiting in a
+// worker. A notification whose recipient no longer holds is taken back from the workers:
+// cancelled when the user opted out, failed when nothing can deliver it, otherwise
+// returned to 'pending' and published to the new recipient by the poller. The directory
+// change is saved either way, so failures here are only logged.
+func (s *RecipientService) recheckInFlight(ctx context.Context, userID string) {
+	inFlight, err := s.inFlight.FetchInFlight(ctx, userID)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Str("user_id", userID).Msg("failed to fetch in-flight notifications of recipient")
+		return
+	}
+	for _, notify := range inFlight {
+		channel, recipient, err := s.resolve(ctx, notify)
+		status, eventType := model.StatusPending, model.EventRerouted
+		switch {
+		case err == nil && channel.String() == notify.Channel.String() && recipient.String() == notify.Recipient.String():
+			continue
+		case err == nil:
+			err = fmt.Errorf("contacts of user '%s' changed, resolving the recipient again", userID)
+		case errors.Is(err, ErrRecipientOptedOut):
+			status, eventType = model.StatusCancelled, model.EventCancelled
+		case errors.Is(err, ErrNoRecipientContact):
+			status, eventType = model.StatusFailed, model.EventFailed
+		default:
+			zlog.Logger.Error().Err(err).Stringer("id", notify.ID).Msg("failed to recheck in-flight notification")
+			continue
+		}
+
+		retracted, retractErr := s.inFlight.Retract(ctx, notify, status, err.Error())
+		if retractErr != nil {
+			zlog.Logger.Error().Err(retractErr).Stringer("id", notify.ID).Msg("failed to retract in-flight notification")
+			continue
+		}
+		if !retracted {
+			// уже доставлено, ушло на другую цель или удалено
+			continue
+		}
+		// копия у воркера адресована прежнему получателю, новая публикация под тем же id ее не заменит
+		if sendErr := s.cancels.SendRetraction(ctx, notify); sendErr != nil {
+			zlog.Logger.Error().Err(sendErr).Stringer("id", notify.ID).Msg("failed to retract notification from workers")
+		}
+		recipientRetractionsTotal.WithLabelValues(status).Inc()
+		zlog.Logger.Info().Err(err).Stringer("id", notify.ID).Str("status", status).Msg("in-flight notification retracted")
+		notify.Status = status
+		fire(ctx, s.hooks, eventType, notify, status, err)
+	}
+}
+
+// Resolve takes the first channel in the order of notify.Channels, or of the user's
+// contacts, where the user has a contact, hasn't opted out of the category, the
+// channel supports the content and has a worker, and the contact isn't known to be
+// unreachable.
+func (s *RecipientService) Resolve(ctx context.Context, notify *model.Notification) (domain.NotificationChannel, domain.Recipient, error) {
+	channel, recipient, err := s.resolve(ctx, notify)
+	switch {
+	case err == nil:
+		recipientResolutionsTotal.WithLabelValues("resolved").Inc()
+	case errors.Is(err, ErrRecipientOptedOut):
+		recipientResolutionsTotal.WithLabelValues("opted_out").Inc()
+	case errors.Is(err, ErrNoRecipientContact):
+		recipientResolutionsTotal.WithLabelValues("no_contact").Inc()
+	default:
+		recipientResolutionsTotal.WithLabelValues("error").Inc()
+	}
+	return channel, recipient, err
+}
+
+func (s *RecipientService) resolve(ctx context.Context, notify *model.Notification) (domain.NotificationChannel, domain.Recipient, error) {
+	var (
+		noChannel   domain.NotificationChannel
+		noRecipient domain.Recipient
+	)
+	profile, err := s.directory.GetProfile(ctx, notify.UserID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// пользователя удалили из справочника после создания уведомления
+		return noChannel, noRecipient, fmt.Errorf("%w: user '%s' is not in the directory", ErrNoRecipientContact, notify.UserID)
+	}
+	if err != nil {
+		return noChannel, noRecipient, fmt.Errorf("couldn't get recipient: %w", err)
+	}
+
+	candidates := notify.Channels
+	if len(candidates) == 0 {
+		for _, contact := range profile.Contacts {
+			candidates = append(candidates, contact.Channel)
+		}
+	}
+
+	optedOut, subscribed := false, false
+	for _, name := range candidates {
+		address, ok := profile.Contact(name)
+		if !ok {
+			continue
+		}
+		if !profile.OptedIn(name, notify.Category) {
+			optedOut = true
+			continue
+		}
+		subscribed = true
+
+		channel, err := domain.NotificationChannelFromString(name)
+		if err != nil {
+			// канал отключили после сохранения контакта
+			continue
+		}
+		if domain.ValidateContent(channel, notify.Content) != nil {
+			continue
+		}
+		available, err := s.channels.Available(ctx, name)
+		if err != nil {
+			return noChannel, noRecipient, fmt.Errorf("error checking channel availability: %w", err)
+		}
+		if !available {
+			continue
+		}
+		unreachable, err := s.recipients.IsUnreachable(ctx, name, address)
+		if err != nil {
+			return noChannel, noRecipient, fmt.Errorf("error checking recipient: %w", err)
+		}
+		if unreachable {
+			continue
+		}
+		return channel, domain.RecipientFromString(address), nil
+	}
+
+	if optedOut && !subscribed && notify.Category == "" {
+		return noChannel, noRecipient, fmt.Errorf("%w of every channel", ErrRecipientOptedOut)
+	}
+	if optedOut && !subscribed {
+		return noChannel, noRecipient, fmt.Errorf("%w of category '%s' on every channel", ErrRecipientOptedOut, notify.Category)
+	}
+	return noChannel, noRecipient, fmt.Errorf("%w for user '%s'", ErrNoRecipientContact, notify.UserID)
+}
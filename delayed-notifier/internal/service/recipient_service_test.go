@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+// fakeDirectory keeps profiles in memory.
+type fakeDirectory map[string]*model.RecipientProfile
+
+func (d fakeDirectory) SaveProfile(ctx context.Context, profile *model.RecipientProfile) error {
+	d[profile.UserID] = profile
+	return nil
+}
+
+func (d fakeDirectory) GetProfile(ctx context.Context, userID string) (*model.RecipientProfile, error) {
+	profile, ok := d[userID]
+	if !ok {
+		return nil, apperrors.NotFound(apperrors.CodeNotFound, "recipient not found", nil)
+	}
+	return profile, nil
+}
+
+func (d fakeDirectory) DeleteProfile(ctx context.Context, userID string) error {
+	delete(d, userID)
+	return nil
+}
+
+// reachableRecipients knows no unreachable recipients.
+type reachableRecipients struct{}
+
+func (reachableRecipients) MarkUnreachable(ctx context.Context, notify *model.Notification, reason string) error {
+	return nil
+}
+
+func (reachableRecipients) IsUnreachable(ctx context.Context, channel, recipient string) (bool, error) {
+	return false, nil
+}
+
+// fakeChannels has a worker for every channel except the listed ones.
+type fakeChannels map[string]bool
+
+func (c fakeChannels) Available(ctx context.Context, channel string) (bool, error) {
+	return !c[channel], nil
+}
+
+// fakeInFlight holds published notifications and records how they were retracted.
+type fakeInFlight struct {
+	notifications []*model.Notification
+	retracted     map[string]string
+}
+
+func (r *fakeInFlight) FetchInFlight(ctx context.Context, userID string) ([]*model.Notification, error) {
+	var result []*model.Notification
+	for _, notify := range r.notifications {
+		if notify.UserID == userID && notify.Status == model.StatusSent {
+			result = append(result, notify)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeInFlight) Retract(ctx context.Context, notify *model.Notification, status string, reason string) (bool, error) {
+	r.retracted[notify.ID.String()] = status
+	return true, nil
+}
+
+// recordingCancels keeps the retractions sent to the workers.
+type recordingCancels struct {
+	retractions []string // канал:получатель
+}
+
+func (c *recordingCancels) SendCancellation(ctx context.Context, notify *model.Notification) error {
+	return errors.New("unexpected cancellation")
+}
+
+func (c *recordingCancels) SendRetraction(ctx context.Context, notify *model.Notification) error {
+	c.retractions = append(c.retractions, notify.Channel.String()+":"+notify.Recipient.String())
+	return nil
+}
+
+// recordingHooks keeps the types of fired events.
+type recordingHooks struct {
+	events []string
+}
+
+func (h *recordingHooks) Fire(ctx context.Context, event *model.NotificationEvent, notify *model.Notification) {
+	h.events = append(h.events, event.Type)
+}
+
+func newInFlightNotification(userID string, channel domain.NotificationChannel, recipient string, category string) *model.Notification {
+	id := types.GenerateUUID()
+	return &model.Notification{
+		ID:        &id,
+		Recipient: domain.RecipientFromString(recipient),
+		Channel:   channel,
+		Message:   "your order has shipped",
+		Status:    model.StatusSent,
+		Audience:  model.Audience{UserID: userID, Category: category},
+	}
+}
+
+func TestSaveRecipientRetractsInFlightNotifications(t *testing.T) {
+	directory := fakeDirectory{"u1": {
+		UserID:   "u1",
+		Contacts: []model.Contact{{Channel: "email", Address: "old@example.com"}, {Channel: "telegram", Address: "123456"}},
+	}}
+	unchanged := newInFlightNotification("u1", domain.ChannelTelegram, "123456", "security")
+	moved := newInFlightNotification("u1", domain.ChannelEmail, "old@example.com", "")
+	unsubscribed := newInFlightNotification("u1", domain.ChannelTelegram, "123456", "marketing")
+	inFlight := &fakeInFlight{
+		notifications: []*model.Notification{unchanged, moved, unsubscribed},
+		retracted:     make(map[string]string),
+	}
+	unchanged.Channels = []string{"telegram"}
+	unsubscribed.Channels = []string{"telegram"}
+	cancels := &recordingCancels{}
+	hooks := &recordingHooks{}
+	s := NewRecipientService(directory, reachableRecipients{}, fakeChannels{}, inFlight, cancels, hooks)
+
+	// пользователь сменил адрес почты и отписался от рассылок, пока уведомления ждали у воркера
+	_, err := s.SaveRecipient(context.Background(), &model.RecipientProfile{
+		UserID:      "u1",
+		Contacts:    []model.Contact{{Channel: "email", Address: "new@example.com"}, {Channel: "telegram", Address: "123456"}},
+		Preferences: []model.Preference{{Category: "marketing", OptedIn: false}},
+	})
+	if err != nil {
+		t.Fatalf("SaveRecipient: %v", err)
+	}
+
+	want := map[string]string{
+		moved.ID.String():        model.StatusPending,
+		unsubscribed.ID.String(): model.StatusCancelled,
+	}
+	if len(inFlight.retracted) != len(want) {
+		t.Fatalf("retracted %v, want %v", inFlight.retracted, want)
+	}
+	for id, status := range want {
+		if inFlight.retracted[id] != status {
+			t.Errorf("notification %s retracted as %q, want %q", id, inFlight.retracted[id], status)
+		}
+	}
+	// воркерам уходит отмена копии, адресованной прежнему получателю
+	if len(cancels.retractions) != 2 || cancels.retractions[0] != "email:old@example.com" || cancels.retractions[1] != "telegram:123456" {
+		t.Errorf("retractions sent to workers %v", cancels.retractions)
+	}
+	if len(hooks.events) != 2 || hooks.events[0] != model.EventRerouted || hooks.events[1] != model.EventCancelled {
+		t.Errorf("events %v, want rerouted, cancelled", hooks.events)
+	}
+}
+
+func TestDeleteRecipientFailsInFlightNotifications(t *testing.T) {
+	directory := fakeDirectory{"u1": {UserID: "u1", Contacts: []model.Contact{{Channel: "email", Address: "a@example.com"}}}}
+	notify := newInFlightNotification("u1", domain.ChannelEmail, "a@example.com", "")
+	inFlight := &fakeInFlight{notifications: []*model.Notification{notify}, retracted: make(map[string]string)}
+	s := NewRecipientService(directory, reachableRecipients{}, fakeChannels{}, inFlight, &recordingCancels{}, &recordingHooks{})
+
+	if err := s.DeleteRecipient(context.Background(), "u1"); err != nil {
+		t.Fatalf("DeleteRecipient: %v", err)
+	}
+	if inFlight.retracted[notify.ID.String()] != model.StatusFailed {
+		t.Fatalf("notification of a deleted user retracted as %q, want failed", inFlight.retracted[notify.ID.String()])
+	}
+}
+
+func TestResolveSkipsChannelsWithoutWorker(t *testing.T) {
+	directory := fakeDirectory{"u1": {
+		UserID:   "u1",
+		Contacts: []model.Contact{{Channel: "email", Address: "a@example.com"}, {Channel: "telegram", Address: "123456"}},
+	}}
+	s := NewRecipientService(directory, reachableRecipients{}, fakeChannels{"email": true}, nil, nil, nil)
+
+	channel, recipient, err := s.Resolve(context.Background(), &model.Notification{Message: "hi", Audience: model.Audience{UserID: "u1"}})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if channel.String() != "telegram" || recipient.String() != "123456" {
+		t.Fatalf("resolved %s:%s, want telegram:123456", channel, recipient)
+	}
+}
+
+func TestCheckUserChannelAvailability(t *testing.T) {
+	directory := fakeDirectory{
+		"u1":      {UserID: "u1", Contacts: []model.Contact{{Channel: "email", Address: "a@example.com"}, {Channel: "telegram", Address: "123456"}}},
+		"u2":      {UserID: "u2", Contacts: []model.Contact{{Channel: "email", Address: "b@example.com"}}},
+		"nothing": {UserID: "nothing"},
+	}
+	s := NewCrudService(nil, nil, nil, fakeChannels{"email": true}, reachableRecipients{}, directory)
+
+	cases := []struct {
+		audience    model.Audience
+		unavailable bool
+	}{
+		// в порядке контактов канал без воркера пропускается
+		{audience: model.Audience{UserID: "u1"}},
+		{audience: model.Audience{UserID: "u2"}, unavailable: true},
+		// явно запрошенные каналы должны быть доступны все
+		{audience: model.Audience{UserID: "u1", Channels: []string{"telegram", "email"}}, unavailable: true},
+		{audience: model.Audience{UserID: "u1", Channels: []string{"telegram"}}},
+		{audience: model.Audience{UserID: "nothing"}},
+	}
+	for _, tc := range cases {
+		err := s.checkUser(context.Background(), &model.Notification{Audience: tc.audience})
+		var appErr *apperrors.Error
+		gotUnavailable := errors.As(err, &appErr) && appErr.Code == apperrors.CodeChannelUnavailable
+		if gotUnavailable != tc.unavailable || (err != nil && !gotUnavailable) {
+			t.Errorf("%+v: got %v, want channel unavailable=%v", tc.audience, err, tc.unavailable)
+		}
+	}
+}
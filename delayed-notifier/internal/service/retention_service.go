@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wb-go/wbf/zlog"
+)
+
+var (
+	retentionArchivedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_archived_notifications_total",
+			Help: "Notifications moved from the hot table into the archive",
+		},
+		[]string{"status"},
+	)
+	retentionArchivedRelatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_archived_related_total",
+			Help: "Rows of other tables moved into the archive together with their notifications",
+		},
+		[]string{"table"},
+	)
+	retentionErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_errors_total",
+			Help: "Retention job failures",
+		},
+		[]string{"stage"},
+	)
+	retentionRunDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "retention_run_duration_seconds",
+			Help:    "Duration of a retention run",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+		},
+	)
+	retentionLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "retention_last_success_timestamp_seconds",
+			Help: "Unix time of the last retention run that finished without errors",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(retentionArchivedTotal, retentionArchivedRelatedTotal, retentionErrorsTotal, retentionRunDuration, retentionLastSuccess)
+}
+
+// RetentionService moves finished notifications older than their status' retention
+// period from the hot table into the partitioned archive, together with the rows of
+// other tables that belong to them.
+type RetentionService struct {
+	repo             ports.RetentionRepository
+	interval         time.Duration
+	batchSize        int
+	maxBatchesPerRun int
+	batchPause       time.Duration
+	periods          map[string]time.Duration
+}
+
+func NewRetentionService(repo ports.RetentionRepository, cfg config.RetentionConfig) *RetentionService {
+	periods := map[string]time.Duration{}
+	for status, period := range map[string]time.Duration{
+		model.StatusSent:      cfg.Sent,
+		model.StatusDelivered: cfg.Delivered,
+		model.StatusFailed:    cfg.Failed,
+		model.StatusCancelled: cfg.Cancelled,
+	} {
+		if period > 0 {
+			periods[status] = period
+		}
+	}
+
+	return &RetentionService{
+		repo:             repo,
+		interval:         cfg.Interval,
+		batchSize:        cfg.BatchSize,
+		maxBatchesPerRun: cfg.MaxBatchesPerRun,
+		batchPause:       cfg.BatchPause,
+		periods:          periods,
+	}
+}
+
+func (s *RetentionService) Run(ctx context.Context) {
+	if len(s.periods) == 0 {
+		zlog.Logger.Info().Msg("retention disabled for every status")
+		return
+	}
+	if s.interval <= 0 {
+		// time.NewTicker паникует на неположительном периоде; конфиг такого не пропускает
+		zlog.Logger.Error().Dur("interval", s.interval).Msg("retention disabled: interval must be positive")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	s.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx)
+		}
+	}
+}
+
+func (s *RetentionService) runOnce(ctx context.Context) {
+	start := time.Now()
+	defer func() {
+		retentionRunDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	failed := false
+	// текущий и следующий месяц, чтобы на стыке месяцев строки не уходили в default
+	for _, month := range []time.Time{start, start.AddDate(0, 1, 0)} {
+		if err := s.repo.EnsurePartition(ctx, month); err != nil {
+			// архивация продолжается, строки попадут в default партицию, но запуск не считается успешным
+			failed = true
+			retentionErrorsTotal.WithLabelValues("partition").Inc()
+			zlog.Logger.Warn().Err(err).Time("month", month).Msg("failed to create archive partition")
+		}
+	}
+
+	for status, period := range s.periods {
+		archived, err := s.archiveStatus(ctx, status, start.Add(-period))
+		if archived > 0 {
+			zlog.Logger.Info().Str("status", status).Int64("archived", archived).Msg("archived old notifications")
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failed = true
+			retentionErrorsTotal.WithLabelValues("archive").Inc()
+			zlog.Logger.Error().Err(err).Str("status", status).Msg("failed to archive notifications")
+		}
+	}
+
+	if !failed {
+		retentionLastSuccess.SetToCurrentTime()
+	}
+}
+
+// archiveStatus works in bounded batches so a large backlog doesn't hold locks
+// for long; whatever is left after maxBatchesPerRun is picked up by the next run.
+func (s *RetentionService) archiveStatus(ctx context.Context, status string, olderThan time.Time) (int64, error) {
+	var total int64
+	// один таймер на все паузы вместо нового time.After на каждую пачку
+	pause := time.NewTimer(s.batchPause)
+	pause.Stop()
+	defer pause.Stop()
+
+	for i := 0; i < s.maxBatchesPerRun; i++ {
+		batch, err := s.repo.ArchiveBatch(ctx, status, olderThan, s.batchSize)
+		if err != nil {
+			return total, err
+		}
+		total += batch.Notifications
+		retentionArchivedTotal.WithLabelValues(status).Add(float64(batch.Notifications))
+		retentionArchivedRelatedTotal.WithLabelValues("notification_events").Add(float64(batch.Events))
+		retentionArchivedRelatedTotal.WithLabelValues("delivery_attempts").Add(float64(batch.DeliveryAttempts))
+		retentionArchivedRelatedTotal.WithLabelValues("callbacks").Add(float64(batch.Callbacks))
+
+		if batch.Notifications < int64(s.batchSize) {
+			return total, nil
+		}
+
+		pause.Reset(s.batchPause)
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-pause.C:
+		}
+	}
+	return total, nil
+}
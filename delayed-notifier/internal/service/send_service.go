@@ -2,43 +2,115 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/config"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/dlq"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/dlq"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/wb-go/wbf/zlog"
 	"golang.org/x/sync/errgroup"
 )
 
+var (
+	senderFetchDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "sender_fetch_duration_seconds",
+			Help:    "Latency of fetching due notifications from Postgres",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+	senderFetchErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sender_fetch_errors_total",
+			Help: "Failed fetches of due notifications",
+		},
+	)
+	senderBatchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "sender_batch_size",
+			Help:    "Notifications fetched per scheduler iteration",
+			Buckets: []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000},
+		},
+	)
+	senderPublishFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sender_publish_failures_total",
+			Help: "Failed publications; stage=batch went to the DLQ, stage=retry failed again from the DLQ",
+		},
+		[]string{"stage"},
+	)
+	senderDLQSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sender_dlq_size",
+			Help: "Notifications that went to the DLQ in the last batch",
+		},
+	)
+	senderSchedulingLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "sender_scheduling_lag_seconds",
+			Help:    "Publish time minus scheduled_at; notifications published ahead of time count as 0",
+			Buckets: []float64{0, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600},
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		senderFetchDuration,
+		senderFetchErrorsTotal,
+		senderBatchSize,
+		senderPublishFailuresTotal,
+		senderDLQSize,
+		senderSchedulingLag,
+	)
+}
+
 type SendService struct {
 	fetchPeriod      time.Duration
 	fetchMaxDiapason time.Duration
+	scheduler        config.SchedulerConfig
 
 	storageFetcherRepo ports.FetcherRepository
 	puvlisherRepo      ports.PublisherRepository
+	resolver           ports.RecipientResolver
+	hooks              ports.LifecycleHooks
+	heartbeat          *health.Heartbeat
 }
 
 func NewSendService(
 	storageRepo ports.FetcherRepository,
 	puvlisherRepo ports.PublisherRepository,
+	resolver ports.RecipientResolver,
+	hooks ports.LifecycleHooks,
 	fetchPeriod time.Duration,
 	fetchMaxDiapason time.Duration,
+	scheduler config.SchedulerConfig,
 ) *SendService {
 	return &SendService{
 		storageFetcherRepo: storageRepo,
 		puvlisherRepo:      puvlisherRepo,
+		resolver:           resolver,
+		hooks:              hooks,
 		fetchPeriod:        fetchPeriod,
 		fetchMaxDiapason:   fetchMaxDiapason,
+		scheduler:          scheduler,
+		heartbeat:          health.NewHeartbeat(),
 	}
 }
 
 func (s *SendService) Run(ctx context.Context) {
+	ctx = actor.WithActor(ctx, actor.Scheduler)
 	ticker := time.NewTicker(s.fetchPeriod)
 	defer ticker.Stop()
 	s.lifeCycle(ctx)
+	s.heartbeat.Beat()
 
 out:
 	for {
@@ -47,43 +119,40 @@ out:
 			break out
 		case <-ticker.C:
 			s.lifeCycle(ctx)
+			s.heartbeat.Beat()
 		}
 	}
 }
 
+// CheckLoop fails when the fetch loop is stuck. A single iteration may take a while
+// (publishing with retries), hence the extra minute on top of a few fetch periods.
+func (s *SendService) CheckLoop(ctx context.Context) error {
+	return s.heartbeat.Check(3*s.fetchPeriod + time.Minute)(ctx)
+}
+
 func (s *SendService) QuickSend(ctx context.Context, obj *model.Notification) error {
 	err := s.puvlisherRepo.SendOne(ctx, obj) // it calls retry inside!
-	go func() {
-		errMark := s.storageFetcherRepo.MarkAsSent(ctx, []*types.UUID{obj.ID})
-		if errMark != nil {
-			zlog.Logger.Error().Err(errMark).Msg("failed to mark as sent")
-		}
-	}()
-	return err
+	if err != nil {
+		s.markAsFailed(ctx, obj, err)
+		return err
+	}
+	s.markAsSent(ctx, []*model.Notification{obj})
+	return nil
 }
 
 func (s *SendService) SendBatch(ctx context.Context, notifycationsToSent []*model.Notification) error {
+	notifycationsToSent = s.resolveRecipients(ctx, notifycationsToSent)
 	DLQ := s.puvlisherRepo.SendMany(ctx, notifycationsToSent)
 
 	var err error
 	errGroup := &errgroup.Group{}
 	errCount := 0
-
-	go func() {
-		ids := make([]*types.UUID, len(notifycationsToSent))
-		for i, obj := range notifycationsToSent {
-			ids[i] = obj.ID
-		}
-		err := s.storageFetcherRepo.MarkAsSent(ctx, ids)
-		if err != nil {
-			zlog.Logger.Error().Err(err).Msg("failed to mark as sent")
-		}
-	}()
-
-	// remark failede msg ToDo
+	failed := make(map[*model.Notification]struct{})
 
 	for obj := range DLQ.Items() {
 		errCount++
+		senderPublishFailuresTotal.WithLabelValues("batch").Inc()
+		failed[obj.Value()] = struct{}{}
 		errGroup.Go(func() error {
 			return func(obj *dlq.Item[*model.Notification]) error {
 				zlog.Logger.Error().
@@ -93,6 +162,7 @@ func (s *SendService) SendBatch(ctx context.Context, notifycationsToSent []*mode
 
 				err := s.QuickSend(ctx, obj.Value())
 				if err != nil {
+					senderPublishFailuresTotal.WithLabelValues("retry").Inc()
 					zlog.Logger.Error().
 						Err(obj.Error()).
 						Stringer("id", obj.Value().ID).
@@ -110,6 +180,16 @@ func (s *SendService) SendBatch(ctx context.Context, notifycationsToSent []*mode
 		})
 	}
 
+	// DLQ is closed once SendMany has tried every object, the rest is published
+	sent := make([]*model.Notification, 0, len(notifycationsToSent)-len(failed))
+	for _, obj := range notifycationsToSent {
+		if _, ok := failed[obj]; !ok {
+			sent = append(sent, obj)
+		}
+	}
+	s.markAsSent(ctx, sent)
+	senderDLQSize.Set(float64(errCount))
+
 	err = errGroup.Wait()
 	if err != nil {
 		return fmt.Errorf("failed to send '%d' objects, example err: %w", errCount, err)
@@ -117,15 +197,106 @@ func (s *SendService) SendBatch(ctx context.Context, notifycationsToSent []*mode
 	return nil
 }
 
+// resolveRecipients picks the channel and the recipient of notifications addressed to a
+// user right before publishing, so the latest contacts and opt-outs apply. Notifications
+// that can't be resolved are left out of the batch.
+func (s *SendService) resolveRecipients(ctx context.Context, batch []*model.Notification) []*model.Notification {
+	resolved := make([]*model.Notification, 0, len(batch))
+	for _, obj := range batch {
+		if obj.UserID == "" {
+			resolved = append(resolved, obj)
+			continue
+		}
+
+		channel, recipient, err := s.resolver.Resolve(ctx, obj)
+		if err == nil {
+			err = s.storageFetcherRepo.AssignRecipient(ctx, obj.ID, channel.String(), recipient.String())
+		}
+		switch {
+		case err == nil:
+			obj.Channel, obj.Recipient = channel, recipient
+			resolved = append(resolved, obj)
+		case errors.Is(err, ErrRecipientOptedOut):
+			s.closeUnresolved(ctx, obj, model.StatusCancelled, model.EventCancelled, err)
+		case errors.Is(err, ErrNoRecipientContact):
+			s.closeUnresolved(ctx, obj, model.StatusFailed, model.EventFailed, err)
+		default:
+			// справочник недоступен: попытка засчитывается, как неудачная публикация
+			s.markAsFailed(ctx, obj, fmt.Errorf("couldn't resolve recipient: %w", err))
+		}
+	}
+	return resolved
+}
+
+func (s *SendService) closeUnresolved(ctx context.Context, obj *model.Notification, status string, eventType string, cause error) {
+	closed, err := s.storageFetcherRepo.CloseUnresolved(ctx, obj.ID, status, cause.Error())
+	if err != nil {
+		zlog.Logger.Error().Err(err).Stringer("id", obj.ID).Msg("failed to close unresolved notification")
+		return
+	}
+	if !closed {
+		// уже отменено или отправлено другой репликой
+		return
+	}
+	zlog.Logger.Info().Err(cause).Stringer("id", obj.ID).Str("status", status).Msg("notification recipient not resolved")
+	fire(ctx, s.hooks, eventType, obj, status, cause)
+}
+
+func (s *SendService) markAsSent(ctx context.Context, notifications []*model.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+
+	ids := make([]*types.UUID, len(notifications))
+	for i, obj := range notifications {
+		ids[i] = obj.ID
+	}
+	err := s.storageFetcherRepo.MarkAsSent(ctx, ids)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to mark as sent")
+		return
+	}
+
+	now := time.Now()
+	for _, obj := range notifications {
+		// ahead of time is normal: the worker holds the notification until scheduled_at
+		senderSchedulingLag.Observe(max(now.Sub(obj.ScheduledAt).Seconds(), 0))
+		fire(ctx, s.hooks, model.EventDispatched, obj, model.StatusSent, nil)
+	}
+}
+
+func (s *SendService) markAsFailed(ctx context.Context, obj *model.Notification, sendErr error) {
+	status, err := s.storageFetcherRepo.MarkAsFailed(ctx, obj.ID, sendErr.Error())
+	if err != nil {
+		zlog.Logger.Error().Err(err).Stringer("id", obj.ID).Msg("failed to mark as failed")
+		return
+	}
+
+	// still pending means it will be retried on the next fetch
+	eventType := model.EventRescheduled
+	if status == model.StatusFailed {
+		eventType = model.EventFailed
+	}
+	fire(ctx, s.hooks, eventType, obj, status, sendErr)
+}
+
 func (s *SendService) lifeCycle(ctx context.Context) {
 	now := time.Now()
 
 	dateTimeForSent := now.Add(s.fetchPeriod)
-	batch, err := s.storageFetcherRepo.FetchFromDb(ctx, dateTimeForSent)
+	enqueuedDueBefore := dateTimeForSent
+	if s.scheduler.Strategy == config.SchedulerBroker {
+		// уведомления в отложенном exchange вернет брокер, поллер подбирает только потерянные
+		enqueuedDueBefore = now.Add(-s.scheduler.EnqueuedGrace)
+	}
+	batch, err := s.storageFetcherRepo.FetchFromDb(ctx, dateTimeForSent, enqueuedDueBefore)
+	senderFetchDuration.Observe(time.Since(now).Seconds())
 	if err != nil {
+		senderFetchErrorsTotal.Inc()
 		zlog.Logger.Error().Err(fmt.Errorf("failed to fetch batch for sending: %w", err)).Msg("error in SenderService loop")
 		return
 	}
+	senderBatchSize.Observe(float64(len(batch)))
 	if len(batch) > 0 {
 		zlog.Logger.Info().Int("amount", len(batch)).Stringer("max_publication_at", dateTimeForSent).Msg("fetched batch")
 	}
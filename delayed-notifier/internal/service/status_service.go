@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/actor"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/delayed-notifier/internal/ports"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// StatusService applies delivery reports sent back by the worker.
+type StatusService struct {
+	receiver    ports.StatusReceiver
+	storageRepo ports.StatusStoreRepository
+	redisRepo   ports.CRUDRedisRepositoryInterface
+	hooks       ports.LifecycleHooks
+	recipients  ports.RecipientRegistry
+}
+
+func NewStatusService(
+	receiver ports.StatusReceiver,
+	storageRepo ports.StatusStoreRepository,
+	redisRepo ports.CRUDRedisRepositoryInterface,
+	hooks ports.LifecycleHooks,
+	recipients ports.RecipientRegistry,
+) *StatusService {
+	return &StatusService{
+		receiver:    receiver,
+		storageRepo: storageRepo,
+		redisRepo:   redisRepo,
+		hooks:       hooks,
+		recipients:  recipients,
+	}
+}
+
+func (s *StatusService) Run(ctx context.Context) {
+	err := s.receiver.Receive(ctx, s.handleReport)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		zlog.Logger.Error().Err(err).Msg("status receiver stopped")
+	}
+}
+
+func (s *StatusService) handleReport(ctx context.Context, report *model.StatusReport) error {
+	ctx = actor.WithActor(ctx, actor.Worker)
+	notify, attempt, err := s.storageRepo.ApplyReport(ctx, report)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// уведомление успели удалить или оно уже ушло на другую цель, отчет больше некуда применять
+		zlog.Logger.Warn().Stringer("notification_id", report.ID).Msg("status report for unknown notification or stale target")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't apply status report: %w", err)
+	}
+
+	// удаленное уведомление не возвращаем в кэш, иначе оно снова станет видно через GET
+	if notify.DeletedAt == nil {
+		if err := s.redisRepo.SaveNotification(ctx, notify); err != nil {
+			zlog.Logger.Warn().Err(err).Stringer("notification_id", notify.ID).Msg("failed to refresh notification in cache")
+		}
+	}
+
+	if report.Unreachable {
+		s.markUnreachable(ctx, notify, attempt, report)
+	}
+
+	eventType := model.EventDelivered
+	var cause error
+	if report.Status == model.StatusFailed {
+		eventType = model.EventFailed
+		if notify.Status == model.StatusPending {
+			eventType = model.EventRerouted
+		}
+		if report.Error != nil {
+			cause = errors.New(*report.Error)
+		}
+	}
+	fire(ctx, s.hooks, eventType, notify, notify.Status, cause)
+
+	zlog.Logger.Debug().Stringer("notification_id", notify.ID).Str("status", report.Status).
+		Int("target_index", attempt.TargetIndex).Msg("status report applied")
+	return nil
+}
+
+// markUnreachable stops new notifications to the recipient of the attempted target, which
+// is no longer the current one after a reroute. The mark is best effort: the report is
+// applied anyway, and the next failed delivery marks it again.
+func (s *StatusService) markUnreachable(ctx context.Context, notify *model.Notification, attempt *model.DeliveryAttempt, report *model.StatusReport) {
+	channel, err := domain.NotificationChannelFromString(attempt.Channel)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Stringer("notification_id", notify.ID).Msg("failed to mark recipient unreachable")
+		return
+	}
+	attempted := *notify
+	attempted.Channel = channel
+	attempted.Recipient = domain.RecipientFromString(attempt.Recipient)
+
+	reason := ""
+	if report.Error != nil {
+		reason = *report.Error
+	}
+	if err := s.recipients.MarkUnreachable(ctx, &attempted, reason); err != nil {
+		zlog.Logger.Error().Err(err).Stringer("notification_id", notify.ID).Msg("failed to mark recipient unreachable")
+		return
+	}
+	zlog.Logger.Info().Stringer("notification_id", notify.ID).Str("channel", attempt.Channel).Msg("recipient marked unreachable")
+}
@@ -0,0 +1,174 @@
+// Package domain holds the notification types both services agree on: the channel
+// registry and recipient validation.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+const (
+	// EMAIL is the constant value for email channel string value
+	EMAIL = "email"
+	// TELEGRAM is the constant value for telegram channel string value
+	TELEGRAM = "telegram"
+	// CONSOLE is the constant value for console channel string value
+	CONSOLE = "console"
+	// SLACK is the constant value for slack channel string value
+	SLACK = "slack"
+	// MATTERMOST is the constant value for mattermost channel string value
+	MATTERMOST = "mattermost"
+	// SMS is the constant value for sms channel string value
+	SMS = "sms"
+	// PUSH is the constant value for push channel string value
+	PUSH = "push"
+)
+
+// ChannelSpec describes a notification channel. A new channel is added once, to
+// channelSpecs: delayed-notifier starts accepting it and the worker declares its queue.
+type ChannelSpec struct {
+	Name string
+	// ValidateRecipient rejects recipients the channel can't deliver to, nil accepts any.
+	ValidateRecipient func(recipient string) error
+	// Content lists the rich content fields the channel delivers; the zero value is
+	// plain text only.
+	Content ContentSupport
+}
+
+var channelSpecs = []ChannelSpec{
+	{Name: EMAIL, ValidateRecipient: validateEmail, Content: AllContent},
+	{Name: TELEGRAM, ValidateRecipient: validateTelegram, Content: ContentSupport{Attachments: true}},
+	{Name: CONSOLE, Content: AllContent},
+	{Name: SLACK, ValidateRecipient: validateChatRecipient, Content: ContentSupport{Subject: true}},
+	{Name: MATTERMOST, ValidateRecipient: validateChatRecipient, Content: ContentSupport{Subject: true}},
+	{Name: SMS, ValidateRecipient: validatePhone},
+	{Name: PUSH, ValidateRecipient: validatePushToken, Content: ContentSupport{Subject: true}},
+}
+
+var (
+	// ChannelEmail is an example channel with value EMAIL
+	ChannelEmail      = NotificationChannel{val: EMAIL}
+	ChannelTelegram   = NotificationChannel{val: TELEGRAM}
+	ChannelConsole    = NotificationChannel{val: CONSOLE}
+	ChannelSlack      = NotificationChannel{val: SLACK}
+	ChannelMattermost = NotificationChannel{val: MATTERMOST}
+	ChannelSMS        = NotificationChannel{val: SMS}
+	ChannelPush       = NotificationChannel{val: PUSH}
+)
+
+var ErrInvalidNotificationChannelValue = errors.New("invalid notification channel value")
+
+// RegisterChannel adds a channel whose sender lives outside this repository, e.g. in a
+// worker sender plugin. It must be called at startup, before notifications are parsed.
+func RegisterChannel(spec ChannelSpec) error {
+	if spec.Name == "" {
+		return errors.New("channel name is empty")
+	}
+	if _, ok := LookupChannel(spec.Name); ok {
+		return fmt.Errorf("channel '%s' is already registered", spec.Name)
+	}
+	channelSpecs = append(channelSpecs, spec)
+	return nil
+}
+
+// ChannelNames lists every registered channel in registration order.
+func ChannelNames() []string {
+	names := make([]string, 0, len(channelSpecs))
+	for _, spec := range channelSpecs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// LookupChannel returns the spec of a registered channel.
+func LookupChannel(name string) (ChannelSpec, bool) {
+	for _, spec := range channelSpecs {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return ChannelSpec{}, false
+}
+
+type NotificationChannel struct {
+	val types.AnyText
+}
+
+func (c NotificationChannel) String() string {
+	return c.val.String()
+}
+
+func (c NotificationChannel) MarshalText() ([]byte, error) {
+	return []byte(c.val.String()), nil
+}
+
+func (c *NotificationChannel) UnmarshalText(text []byte) error {
+	parsed, err := NotificationChannelFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func NotificationChannelFromString(val string) (NotificationChannel, error) {
+	if _, ok := LookupChannel(val); !ok {
+		return NotificationChannel{}, fmt.Errorf("%w: possible ones are: '%s'", ErrInvalidNotificationChannelValue, strings.Join(ChannelNames(), "', '"))
+	}
+	return NotificationChannel{val: types.NewAnyText(val)}, nil
+}
+
+func validateEmail(recipient string) error {
+	if _, err := mail.ParseAddress(recipient); err != nil {
+		return fmt.Errorf("invalid email address: %w", err)
+	}
+	return nil
+}
+
+func validateTelegram(recipient string) error {
+	if _, err := strconv.ParseInt(recipient, 10, 64); err != nil {
+		return fmt.Errorf("invalid telegram address: %s", recipient)
+	}
+	return nil
+}
+
+// e164Pattern matches a phone number in E.164: a plus, a country code and at most 15 digits.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+func validatePhone(recipient string) error {
+	if !e164Pattern.MatchString(recipient) {
+		return fmt.Errorf("invalid phone number '%s': expected E.164 like '+15551234567'", recipient)
+	}
+	return nil
+}
+
+// chatChannelPattern matches a chat channel or user: "#ops", "@oncall", "C0123ABCD".
+var chatChannelPattern = regexp.MustCompile(`^[#@]?[A-Za-z0-9][A-Za-z0-9._-]{0,79}$`)
+
+// validateChatRecipient accepts an incoming-webhook URL or a channel identifier; the
+// latter is posted through the webhook configured in the worker. A webhook URL must
+// not name an internal address; the worker checks resolved addresses again on send.
+func validateChatRecipient(recipient string) error {
+	if strings.HasPrefix(recipient, "http://") || strings.HasPrefix(recipient, "https://") {
+		u, err := url.Parse(recipient)
+		if err != nil || u.Host == "" {
+			return errors.New("invalid webhook url")
+		}
+		if err := (egress.Policy{}).Check(u.Hostname()); err != nil {
+			return fmt.Errorf("invalid webhook url: %w", err)
+		}
+		return nil
+	}
+	if !chatChannelPattern.MatchString(recipient) {
+		return fmt.Errorf("invalid chat recipient '%s': expected a webhook url or a channel like '#ops'", recipient)
+	}
+	return nil
+}
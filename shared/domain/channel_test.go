@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+)
+
+func TestValidateChatRecipient(t *testing.T) {
+	cases := map[string]bool{
+		"#ops":      true,
+		"@oncall":   true,
+		"C0123ABCD": true,
+		"https://hooks.slack.com/services/T000/B000/XXX": true,
+		"https://chat.example.com/hooks/abc":             true,
+		"ftp://hooks.example.com/x":                      false,
+		"https://":                                       false,
+		"http://127.0.0.1:8080/hooks/abc":                false,
+		"http://localhost/hooks/abc":                     false,
+		"http://169.254.169.254/latest/meta-data":        false,
+		"http://[::1]/hooks/abc":                         false,
+		"http://10.0.0.5/hooks/abc":                      false,
+		"# ops":                                          false,
+	}
+	for recipient, valid := range cases {
+		err := validateChatRecipient(recipient)
+		if (err == nil) != valid {
+			t.Errorf("validateChatRecipient(%q) = %v, want valid=%v", recipient, err, valid)
+		}
+	}
+	if err := validateChatRecipient("http://127.0.0.1/hooks"); !errors.Is(err, egress.ErrBlocked) {
+		t.Errorf("internal webhook: got %v, want ErrBlocked", err)
+	}
+}
+
+func TestValidatePhone(t *testing.T) {
+	cases := map[string]bool{
+		"+15551234567":      true,
+		"+4915112345678":    true,
+		"+12":               true,
+		"+123456789012345":  true, // 15 цифр — максимум E.164
+		"+1234567890123456": false,
+		"+1":                false,
+		"15551234567":       false,
+		"+05551234567":      false,
+		"+1 555 123 4567":   false,
+		"+1-555-123-4567":   false,
+		"+1555123456a":      false,
+		"":                  false,
+	}
+	for phone, valid := range cases {
+		if err := validatePhone(phone); (err == nil) != valid {
+			t.Errorf("validatePhone(%q) = %v, want valid=%v", phone, err, valid)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Limits of rich content. Attachments are references, so their limits bound the blobs
+// a sender downloads, not the message itself.
+const (
+	MaxSubjectLength        = 255       // символов
+	MaxBodyHTMLSize         = 512 << 10 // байт
+	MaxAttachments          = 10
+	MaxAttachmentSize       = 10 << 20 // байт, по заявленному size
+	MaxAttachmentsTotalSize = 25 << 20 // байт, сумма size всех вложений
+	MaxMetadataLabels       = 32
+	MaxMetadataValueLength  = 256 // символов
+	MaxAttachmentNameLength = 255 // символов
+)
+
+// Content is the part of a notification beyond the plain-text message. The message
+// stays required: it is the fallback for clients that can't show the rest.
+type Content struct {
+	Subject     string            `json:"subject,omitempty"`
+	BodyHTML    string            `json:"body_html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"` // метки для поиска и аналитики, получателю не отправляются
+}
+
+// Attachment references a blob stored elsewhere; the sender downloads it at send time
+// and must not read more than Size bytes.
+type Attachment struct {
+	URL         string `json:"url"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"` // байт
+}
+
+// ContentSupport tells which rich content fields a channel delivers. Metadata is
+// accepted on every channel.
+type ContentSupport struct {
+	Subject     bool
+	BodyHTML    bool
+	Attachments bool
+}
+
+// AllContent is the support of channels that deliver every field, and of plugin
+// channels, whose plugin decides what to do with them.
+var AllContent = ContentSupport{Subject: true, BodyHTML: true, Attachments: true}
+
+// metadataKeyPattern matches a metadata label name: "campaign", "order.id".
+var metadataKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// ValidateContent checks content against the limits and the fields channel supports.
+func ValidateContent(channel NotificationChannel, content Content) error {
+	spec, ok := LookupChannel(channel.String())
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrInvalidNotificationChannelValue, channel)
+	}
+	support := spec.Content
+
+	switch {
+	case content.Subject != "" && !support.Subject:
+		return fmt.Errorf("channel '%s' doesn't support 'subject'", channel)
+	case content.BodyHTML != "" && !support.BodyHTML:
+		return fmt.Errorf("channel '%s' doesn't support 'body_html'", channel)
+	case len(content.Attachments) > 0 && !support.Attachments:
+		return fmt.Errorf("channel '%s' doesn't support 'attachments'", channel)
+	}
+	return ValidateContentLimits(content)
+}
+
+// ValidateContentLimits checks content against the limits only, for notifications
+// whose channel is not known yet.
+func ValidateContentLimits(content Content) error {
+	if utf8.RuneCountInString(content.Subject) > MaxSubjectLength {
+		return fmt.Errorf("'subject' is longer than %d characters", MaxSubjectLength)
+	}
+	if len(content.BodyHTML) > MaxBodyHTMLSize {
+		return fmt.Errorf("'body_html' is larger than %d bytes", MaxBodyHTMLSize)
+	}
+	if err := validateAttachments(content.Attachments); err != nil {
+		return err
+	}
+	return validateMetadata(content.Metadata)
+}
+
+func validateAttachments(attachments []Attachment) error {
+	if len(attachments) > MaxAttachments {
+		return fmt.Errorf("more than %d 'attachments'", MaxAttachments)
+	}
+	var total int64
+	for i, a := range attachments {
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("attachment %d: 'url' must be an http(s) url", i)
+		}
+		if a.Filename == "" || utf8.RuneCountInString(a.Filename) > MaxAttachmentNameLength {
+			return fmt.Errorf("attachment %d: 'filename' must be 1 to %d characters", i, MaxAttachmentNameLength)
+		}
+		if a.Size <= 0 || a.Size > MaxAttachmentSize {
+			return fmt.Errorf("attachment %d: 'size' must be 1 to %d bytes", i, MaxAttachmentSize)
+		}
+		total += a.Size
+	}
+	if total > MaxAttachmentsTotalSize {
+		return fmt.Errorf("'attachments' are larger than %d bytes in total", MaxAttachmentsTotalSize)
+	}
+	return nil
+}
+
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataLabels {
+		return fmt.Errorf("more than %d 'metadata' labels", MaxMetadataLabels)
+	}
+	for key, value := range metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid 'metadata' label '%s': expected lowercase letters, digits, '_', '.', '-'", key)
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return fmt.Errorf("'metadata' label '%s' is longer than %d characters", key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
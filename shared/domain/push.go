@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Push platforms a device token belongs to.
+const (
+	PushFCM  = "fcm"
+	PushAPNs = "apns"
+)
+
+// maxPushTokenLength bounds a device token of either platform.
+const maxPushTokenLength = 4096
+
+var (
+	// apnsTokenPattern matches an APNs device token: 32 bytes or more in hex.
+	apnsTokenPattern = regexp.MustCompile(`^[0-9a-fA-F]{64,200}$`)
+	// fcmTokenPattern matches an FCM registration token; its length is checked apart,
+	// regexp repetition stops at 1000.
+	fcmTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_:.-]{20,}$`)
+)
+
+// ParsePushToken splits a push recipient into its platform and device token. The
+// recipient is "fcm:<token>", "apns:<token>" or a bare token; a bare token of hex
+// digits only is taken for an APNs one, anything else for an FCM one.
+func ParsePushToken(recipient string) (platform, token string, err error) {
+	platform, token, found := strings.Cut(recipient, ":")
+	switch {
+	case found && platform == PushAPNs:
+	case found && platform == PushFCM:
+	case apnsTokenPattern.MatchString(recipient):
+		platform, token = PushAPNs, recipient
+	default:
+		platform, token = PushFCM, recipient
+	}
+
+	pattern := fcmTokenPattern
+	if platform == PushAPNs {
+		pattern = apnsTokenPattern
+	}
+	if !pattern.MatchString(token) || len(token) > maxPushTokenLength {
+		return "", "", fmt.Errorf("invalid %s device token '%s'", platform, recipient)
+	}
+	return platform, token, nil
+}
+
+func validatePushToken(recipient string) error {
+	_, _, err := ParsePushToken(recipient)
+	return err
+}
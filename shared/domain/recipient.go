@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+)
+
+type Recipient struct {
+	Val types.AnyText
+}
+
+func (c Recipient) String() string {
+	return c.Val.String()
+}
+
+// RecipientFromString wraps a recipient that has already been validated.
+func RecipientFromString(val string) Recipient {
+	return Recipient{
+		Val: types.NewAnyText(val),
+	}
+}
+
+// NewSendTo validates Val with the rules of channel.
+func NewSendTo(Val types.AnyText, channel NotificationChannel) (Recipient, error) {
+	if spec, ok := LookupChannel(channel.String()); ok && spec.ValidateRecipient != nil {
+		if err := spec.ValidateRecipient(Val.String()); err != nil {
+			return Recipient{}, err
+		}
+	}
+	return Recipient{Val: Val}, nil
+}
@@ -0,0 +1,137 @@
+// Package egress builds HTTP clients for requests whose destination comes from API
+// clients (callback URLs, webhook recipients), so they can't be pointed at the
+// service's own network: loopback, private ranges or cloud metadata endpoints.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is wrapped by errors for requests the policy doesn't allow. Such
+// requests are never sent, so repeating them won't help.
+var ErrBlocked = errors.New("destination is not allowed")
+
+// Policy tells where requests may go.
+type Policy struct {
+	AllowedHosts []string // если задан — только эти хосты; ".example.com" разрешает поддомены
+	AllowPrivate bool     // разрешить loopback и внутренние адреса (локальная разработка)
+}
+
+// blockedPrefixes are ranges not covered by the netip predicates used in BlockedAddr.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, часто — внутренняя сеть облака
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 ведет на произвольный IPv4
+}
+
+// BlockedAddr reports whether addr is loopback, private, link-local (including
+// 169.254.169.254), multicast, unspecified or reserved.
+func BlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostAllowed checks host against AllowedHosts; an empty list allows every host.
+func (p Policy) HostAllowed(host string) bool {
+	if len(p.AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, ".") {
+			if strings.HasSuffix(host, allowed) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns a client that checks every request, redirects included, against
+// the policy. Addresses are checked after DNS resolution, right before connecting,
+// so a host can't resolve to a public address at validation and a private one later.
+func NewClient(timeout time.Duration, policy Policy) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: NewTransport(policy)}
+}
+
+// NewTransport is the RoundTripper of NewClient, for clients that need their own settings.
+func NewTransport(policy Policy) http.RoundTripper {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !policy.AllowPrivate {
+		dialer.Control = controlAddr
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// через прокси соединение идет с адресом прокси, и проверка адреса назначения теряет смысл
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &guardedTransport{policy: policy, next: transport}
+}
+
+func controlAddr(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected address '%s'", ErrBlocked, address)
+	}
+	if BlockedAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: address %s is internal", ErrBlocked, addrPort.Addr())
+	}
+	return nil
+}
+
+type guardedTransport struct {
+	policy Policy
+	next   http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: scheme '%s'", ErrBlocked, req.URL.Scheme)
+	}
+	if !t.policy.HostAllowed(req.URL.Hostname()) {
+		return nil, fmt.Errorf("%w: host '%s' is not in the allow-list", ErrBlocked, req.URL.Hostname())
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Check is a quick check of a URL before it is accepted: the host must be allowed, and a
+// literal IP must not be internal. Names are resolved only when connecting.
+func (p Policy) Check(host string) error {
+	if !p.HostAllowed(host) {
+		return fmt.Errorf("%w: host '%s' is not in the allow-list", ErrBlocked, host)
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && BlockedAddr(addr) {
+		return fmt.Errorf("%w: address %s is internal", ErrBlocked, addr)
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("%w: host '%s' is internal", ErrBlocked, host)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package egress
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBlockedAddr(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"8.8.8.8":          false,
+		"2001:4860::8888":  false,
+	}
+	for raw, want := range cases {
+		if got := BlockedAddr(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("BlockedAddr(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	policy := Policy{AllowedHosts: []string{"hooks.example.com", ".partner.io"}}
+	cases := map[string]bool{
+		"hooks.example.com":  true,
+		"HOOKS.example.com.": true,
+		"api.partner.io":     true,
+		"partner.io":         false,
+		"evilpartner.io":     false,
+		"example.com":        false,
+	}
+	for host, want := range cases {
+		if got := policy.HostAllowed(host); got != want {
+			t.Errorf("HostAllowed(%s) = %v, want %v", host, got, want)
+		}
+	}
+	if !(Policy{}).HostAllowed("anything.example") {
+		t.Error("empty allow-list must allow every host")
+	}
+}
+
+func TestClientBlocksLoopback(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second, Policy{}).Get(server.URL)
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("request to %s: got %v, want ErrBlocked", server.URL, err)
+	}
+	if hits != 0 {
+		t.Fatalf("blocked request reached the server")
+	}
+
+	resp, err := NewClient(time.Second, Policy{AllowPrivate: true}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("AllowPrivate: %v", err)
+	}
+	resp.Body.Close()
+	if hits != 1 {
+		t.Fatalf("hits = %d, want 1", hits)
+	}
+}
+
+func TestClientBlocksRedirectToInternal(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached the internal server")
+	}))
+	defer internal.Close()
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	// оба сервера на loopback: первый запрашиваем как localhost из allow-list,
+	// а редирект ведет на 127.0.0.1, которого в нем нет
+	client := NewClient(time.Second, Policy{AllowedHosts: []string{"localhost"}, AllowPrivate: true})
+	_, err := client.Get("http://localhost:" + public.URL[len("http://127.0.0.1:"):])
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("redirect to a host outside the allow-list: got %v, want ErrBlocked", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy := Policy{}
+	for _, host := range []string{"127.0.0.1", "[::1]", "169.254.169.254", "localhost", "api.localhost"} {
+		if err := policy.Check(host); !errors.Is(err, ErrBlocked) {
+			t.Errorf("Check(%s) = %v, want ErrBlocked", host, err)
+		}
+	}
+	if err := policy.Check("hooks.example.com"); err != nil {
+		t.Errorf("Check(hooks.example.com) = %v", err)
+	}
+	if err := (Policy{AllowPrivate: true}).Check("localhost"); err != nil {
+		t.Errorf("AllowPrivate Check(localhost) = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package health runs named dependency checks and reports their results as JSON
+// for /healthz and /readyz probes.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check returns nil when the dependency is usable.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry runs its checks concurrently, each bounded by timeout.
+type Registry struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+func (r *Registry) Add(name string, check Check) *Registry {
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+	return r
+}
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+func (r *Registry) Run(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(r.checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := r.runOne(ctx, c.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (r *Registry) runOne(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// a check that ignores ctx must not hang the probe
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusUp, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Heartbeat is beaten by a background loop on every iteration; its Check fails
+// when the loop hasn't run for longer than staleAfter.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func NewHeartbeat() *Heartbeat {
+	h := &Heartbeat{}
+	h.Beat()
+	return h
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+func (h *Heartbeat) Last() time.Time {
+	return time.Unix(0, h.last.Load())
+}
+
+func (h *Heartbeat) Check(staleAfter time.Duration) Check {
+	return func(ctx context.Context) error {
+		if since := time.Since(h.Last()); since > staleAfter {
+			return &StaleError{Since: since, StaleAfter: staleAfter}
+		}
+		return nil
+	}
+}
+
+type StaleError struct {
+	Since      time.Duration
+	StaleAfter time.Duration
+}
+
+func (e *StaleError) Error() string {
+	return "loop last ran " + e.Since.Round(time.Millisecond).String() + " ago, limit " + e.StaleAfter.String()
+}
@@ -0,0 +1,48 @@
+// Package plugin defines how the worker talks to sender plugins: separate executables
+// that deliver notifications of one channel.
+//
+// The worker starts the plugin and speaks JSON lines over its stdin and stdout:
+//
+//  1. the plugin writes a Handshake line with ProtocolVersion and its channel;
+//  2. the worker writes Request lines, the plugin answers each with a Response carrying
+//     the same ID. Requests may be in flight concurrently and answered in any order;
+//  3. when stdin is closed the plugin finishes the requests in flight and exits.
+//
+// Everything a plugin writes to stderr ends up in the worker log. Plugins written in
+// Go implement Sender and call Serve.
+package plugin
+
+import (
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+)
+
+// ProtocolVersion is bumped on incompatible changes of the messages below.
+const ProtocolVersion = 1
+
+// Methods of Request.
+const (
+	MethodSend   = "send"
+	MethodHealth = "health"
+)
+
+// Handshake is the first line a plugin writes.
+type Handshake struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Channel         string `json:"channel"` // канал, который доставляет плагин; совпадает с именем файла
+}
+
+// Request is a call from the worker.
+type Request struct {
+	ID           uint64             `json:"id"`
+	Method       string             `json:"method"`
+	Notification *wire.Notification `json:"notification,omitempty"` // для send
+	RequestID    string             `json:"request_id,omitempty"`   // X-Request-ID создания уведомления, для логов плагина
+	TimeoutMs    int64              `json:"timeout_ms,omitempty"`   // после этого срока воркер считает вызов неудачным
+}
+
+// Response answers the Request with the same ID. An empty Error means success.
+type Response struct {
+	ID        uint64 `json:"id"`
+	Error     string `json:"error,omitempty"`
+	Permanent bool   `json:"permanent,omitempty"` // повтор не поможет: уведомление уйдет в dead-letter
+}
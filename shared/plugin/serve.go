@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+)
+
+// Sender delivers notifications inside a plugin.
+type Sender interface {
+	Send(ctx context.Context, notification *wire.Notification) error
+}
+
+// HealthChecker is implemented by senders that can tell whether their provider is
+// reachable. Senders without it are healthy while the process runs.
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+var errPermanent = errors.New("permanent failure")
+
+// Permanent marks err as a failure retrying cannot fix, e.g. a recipient the provider
+// rejects.
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", errPermanent, err)
+}
+
+// Serve runs the plugin side of the protocol over stdin and stdout until stdin is closed.
+func Serve(channel string, sender Sender) error {
+	return ServeIO(context.Background(), channel, sender, os.Stdin, os.Stdout)
+}
+
+// ServeIO is Serve over arbitrary streams.
+func ServeIO(ctx context.Context, channel string, sender Sender, in io.Reader, out io.Writer) error {
+	var writeMu sync.Mutex
+	encoder := json.NewEncoder(out)
+	write := func(v any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return encoder.Encode(v)
+	}
+
+	if err := write(Handshake{ProtocolVersion: ProtocolVersion, Channel: channel}); err != nil {
+		return fmt.Errorf("couldn't write handshake: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := write(handle(ctx, sender, req)); err != nil {
+				fmt.Fprintf(os.Stderr, "couldn't write response %d: %v\n", req.ID, err)
+			}
+		}()
+	}
+	return scanner.Err()
+}
+
+func handle(ctx context.Context, sender Sender, req Request) Response {
+	if req.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	var err error
+	switch req.Method {
+	case MethodSend:
+		if req.Notification == nil {
+			err = Permanent(errors.New("send request without notification"))
+			break
+		}
+		err = sender.Send(ctx, req.Notification)
+	case MethodHealth:
+		if checker, ok := sender.(HealthChecker); ok {
+			err = checker.Check(ctx)
+		}
+	default:
+		err = Permanent(fmt.Errorf("unknown method '%s'", req.Method))
+	}
+
+	resp := Response{ID: req.ID}
+	if err != nil {
+		resp.Error = err.Error()
+		resp.Permanent = errors.Is(err, errPermanent)
+	}
+	return resp
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+)
+
+// recipientSender rejects one recipient permanently and another one temporarily.
+type recipientSender struct{}
+
+func (recipientSender) Send(ctx context.Context, notification *wire.Notification) error {
+	switch notification.Recipient {
+	case "rejected":
+		return Permanent(errors.New("unknown recipient"))
+	case "busy":
+		return errors.New("provider busy")
+	}
+	return nil
+}
+
+func TestServeIO(t *testing.T) {
+	in, requests := io.Pipe()
+	responses, out := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeIO(context.Background(), "test", recipientSender{}, in, out)
+		_ = out.Close()
+	}()
+	lines := bufio.NewScanner(responses)
+
+	if !lines.Scan() {
+		t.Fatalf("no handshake: %v", lines.Err())
+	}
+	var handshake Handshake
+	if err := json.Unmarshal(lines.Bytes(), &handshake); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if handshake.ProtocolVersion != ProtocolVersion || handshake.Channel != "test" {
+		t.Fatalf("handshake %+v", handshake)
+	}
+
+	want := map[uint64]Response{
+		1: {ID: 1},
+		2: {ID: 2, Error: "permanent failure: unknown recipient", Permanent: true},
+		3: {ID: 3, Error: "provider busy"},
+		4: {ID: 4},
+		5: {ID: 5, Error: "permanent failure: send request without notification", Permanent: true},
+		6: {ID: 6, Error: "permanent failure: unknown method 'resend'", Permanent: true},
+	}
+	go func() {
+		encoder := json.NewEncoder(requests)
+		for _, req := range []Request{
+			{ID: 1, Method: MethodSend, Notification: &wire.Notification{Recipient: "ok"}},
+			{ID: 2, Method: MethodSend, Notification: &wire.Notification{Recipient: "rejected"}},
+			{ID: 3, Method: MethodSend, Notification: &wire.Notification{Recipient: "busy"}, TimeoutMs: 1000},
+			{ID: 4, Method: MethodHealth},
+			{ID: 5, Method: MethodSend},
+			{ID: 6, Method: "resend"},
+		} {
+			_ = encoder.Encode(req)
+		}
+		// EOF на stdin: плагин отвечает на оставшиеся запросы и выходит
+		_ = requests.Close()
+	}()
+
+	// ответы приходят в любом порядке
+	for lines.Scan() {
+		var resp Response
+		if err := json.Unmarshal(lines.Bytes(), &resp); err != nil {
+			t.Fatalf("response: %v", err)
+		}
+		expected, ok := want[resp.ID]
+		if !ok {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+		if resp != expected {
+			t.Errorf("response %+v, want %+v", resp, expected)
+		}
+		delete(want, resp.ID)
+	}
+	if len(want) != 0 {
+		t.Errorf("no responses to %v", want)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("ServeIO: %v", err)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
+
+	"github.com/wb-go/wbf/zlog"
 )
 
 type HTTPServer struct {
@@ -51,7 +52,7 @@ func listenSignal(ctx context.Context, httpServer *http.Server, serverStopped <-
 		defer cancel()
 		err := httpServer.Shutdown(shutdownCtx)
 		if err != nil {
-			log.Fatalf("error while shutting down http server: %v", err)
+			zlog.Logger.Fatal().Err(err).Msg("error while shutting down http server")
 		}
 
 	}
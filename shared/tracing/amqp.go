@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+)
+
+// AMQPHeadersCarrier adapts AMQP message headers to propagation.TextMapCarrier.
+type AMQPHeadersCarrier amqp091.Table
+
+func (c AMQPHeadersCarrier) Get(key string) string {
+	if v, ok := c[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (c AMQPHeadersCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c AMQPHeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectAMQP writes the trace context of ctx into headers, creating them if needed.
+func InjectAMQP(ctx context.Context, headers amqp091.Table) amqp091.Table {
+	if headers == nil {
+		headers = amqp091.Table{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, AMQPHeadersCarrier(headers))
+	return headers
+}
+
+// ExtractAMQP returns ctx carrying the trace context found in headers.
+func ExtractAMQP(ctx context.Context, headers amqp091.Table) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, AMQPHeadersCarrier(headers))
+}
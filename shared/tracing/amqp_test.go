@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupInMemory installs a provider that keeps finished spans in memory.
+func setupInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return exporter
+}
+
+func TestAMQPPropagationLinksConsumerToProducer(t *testing.T) {
+	exporter := setupInMemory(t)
+	tracer := otel.Tracer("test")
+
+	// delayed-notifier: спан публикации пишет контекст в заголовки сообщения
+	ctx, producer := tracer.Start(context.Background(), "publish email", trace.WithSpanKind(trace.SpanKindProducer))
+	headers := InjectAMQP(ctx, amqp091.Table{"x-schema-version": int32(2)})
+	producer.End()
+
+	if headers["traceparent"] == nil {
+		t.Fatalf("traceparent header not injected: %v", headers)
+	}
+	if headers["x-schema-version"] != int32(2) {
+		t.Fatalf("existing headers must be kept: %v", headers)
+	}
+
+	// воркер: спан приема продолжает трассу из заголовков
+	_, consumer := tracer.Start(ExtractAMQP(context.Background(), headers), "receive email", trace.WithSpanKind(trace.SpanKindConsumer))
+	consumer.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	published, received := spans[0], spans[1]
+	if received.SpanContext.TraceID() != published.SpanContext.TraceID() {
+		t.Errorf("consumer trace %s, want producer trace %s", received.SpanContext.TraceID(), published.SpanContext.TraceID())
+	}
+	if received.Parent.SpanID() != published.SpanContext.SpanID() {
+		t.Errorf("consumer parent %s, want producer span %s", received.Parent.SpanID(), published.SpanContext.SpanID())
+	}
+	if !received.Parent.IsRemote() {
+		t.Error("consumer parent must be remote")
+	}
+}
+
+func TestInjectAMQPCreatesHeaders(t *testing.T) {
+	setupInMemory(t)
+	ctx, span := otel.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+
+	headers := InjectAMQP(ctx, nil)
+	if _, ok := headers["traceparent"].(string); !ok {
+		t.Fatalf("traceparent header not injected into new headers: %v", headers)
+	}
+}
+
+func TestExtractAMQPWithoutHeadersStartsNewTrace(t *testing.T) {
+	exporter := setupInMemory(t)
+
+	_, span := otel.Tracer("test").Start(ExtractAMQP(context.Background(), amqp091.Table{}), "receive")
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Parent.IsValid() {
+		t.Fatalf("span without trace headers must be a root: %+v", spans)
+	}
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	exporter := setupInMemory(t)
+	tracer := otel.Tracer("test")
+
+	// запрос на создание: traceparent сохраняется в строке уведомления
+	ctx, request := tracer.Start(context.Background(), "POST /notify")
+	stored := TraceParent(ctx)
+	request.End()
+	if stored == "" {
+		t.Fatal("TraceParent returned nothing for a recording span")
+	}
+
+	// публикация много позже продолжает ту же трассу
+	_, publish := tracer.Start(WithTraceParent(context.Background(), stored), "publish")
+	publish.End()
+
+	spans := exporter.GetSpans()
+	if spans[1].Parent.SpanID() != spans[0].SpanContext.SpanID() {
+		t.Errorf("publish parent %s, want request span %s", spans[1].Parent.SpanID(), spans[0].SpanContext.SpanID())
+	}
+	if TraceParent(context.Background()) != "" {
+		t.Error("TraceParent without a span must be empty")
+	}
+	if ctx := WithTraceParent(context.Background(), ""); trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("empty traceparent must not add a parent")
+	}
+}
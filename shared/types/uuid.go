@@ -33,3 +33,15 @@ func(v * UUID) String() string {
 }
 
 
+func (v UUID) MarshalText() ([]byte, error) {
+	return []byte(v.value.String()), nil
+}
+
+func (v *UUID) UnmarshalText(text []byte) error {
+	parsed, err := NewUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
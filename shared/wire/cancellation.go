@@ -0,0 +1,49 @@
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+)
+
+// CancelContentType is set on every published cancellation.
+const CancelContentType = "application/vnd.delayed-notifier.cancellation+json"
+
+// Cancellation tells workers to drop a notification that was already published to
+// them but not sent yet. It is fanned out to every worker, since any of them may
+// hold the notification. With Channel and Recipient set only the copy addressed to
+// them is dropped: the notification may be published again under the same id to a
+// recipient resolved anew.
+type Cancellation struct {
+	ID        string `json:"id"`
+	Channel   string `json:"channel,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+}
+
+// EncodeCancellation marshals c.
+func EncodeCancellation(c Cancellation) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal cancellation: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeCancellation unmarshals a cancellation. contentType may be empty.
+func DecodeCancellation(contentType string, body []byte) (Cancellation, error) {
+	var c Cancellation
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != CancelContentType {
+			return c, fmt.Errorf("%w '%s'", ErrUnsupportedContentType, contentType)
+		}
+	}
+	if err := json.Unmarshal(body, &c); err != nil {
+		return c, fmt.Errorf("invalid cancellation json: %w", err)
+	}
+	if c.ID == "" {
+		return c, errors.New("cancellation has no 'id'")
+	}
+	return c, nil
+}
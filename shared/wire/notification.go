@@ -0,0 +1,123 @@
+// Package wire defines the messages exchanged between delayed-notifier and the worker
+// over RabbitMQ. The JSON Schema of the current version is notification.schema.json.
+//
+// Every change of the format gets a new schema version. Consumers accept the current and
+// the previous version, so a producer and a consumer can be deployed in any order.
+package wire
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"time"
+)
+
+const (
+	// ContentType is set on every published notification.
+	ContentType = "application/vnd.delayed-notifier.notification+json"
+	// LegacyContentType was set by producers before the schema was versioned.
+	LegacyContentType = "application/json"
+
+	// HeaderSchemaVersion carries the schema version in the message headers, so it can
+	// be read without decoding the body.
+	HeaderSchemaVersion = "schema_version"
+)
+
+// Schema versions of Notification.
+const (
+	// SchemaV1 is the unversioned message: no schema_version, scheduled_at in seconds.
+	SchemaV1 = 1
+	// SchemaV2 adds schema_version and keeps the fractional seconds of scheduled_at.
+	SchemaV2 = 2
+	// SchemaV3 adds the optional rich content: subject, body_html, attachments, metadata.
+	SchemaV3 = 3
+
+	CurrentSchemaVersion = SchemaV3
+	// MinSchemaVersion is the oldest version consumers still accept.
+	MinSchemaVersion = SchemaV1
+)
+
+var (
+	ErrUnsupportedVersion     = errors.New("unsupported schema version")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// NotificationSchema is the JSON Schema of the current Notification version.
+//
+//go:embed notification.schema.json
+var NotificationSchema []byte
+
+// Notification is the message delayed-notifier publishes for the worker to send.
+type Notification struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Recipient     string    `json:"recipient"` // email, telegram id и т.д.
+	Channel       string    `json:"channel"`   // routing key и очередь воркера
+	Message       string    `json:"message"`
+	ScheduledAt   time.Time `json:"scheduled_at"` // RFC3339, в v2 с долями секунды
+
+	Subject     string            `json:"subject,omitempty"` // с v3
+	BodyHTML    string            `json:"body_html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Attachment references a blob the worker downloads when sending.
+type Attachment struct {
+	URL         string `json:"url"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// EncodeNotification marshals n with the current schema version.
+func EncodeNotification(n Notification) ([]byte, error) {
+	n.SchemaVersion = CurrentSchemaVersion
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal notification: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeNotification unmarshals a message of any supported schema version. contentType
+// may be empty for messages published without one.
+func DecodeNotification(contentType string, body []byte) (*Notification, error) {
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != ContentType && mediaType != LegacyContentType) {
+			return nil, fmt.Errorf("%w '%s'", ErrUnsupportedContentType, contentType)
+		}
+	}
+
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("invalid notification json: %w", err)
+	}
+	if n.SchemaVersion == 0 {
+		n.SchemaVersion = SchemaV1
+	}
+	if n.SchemaVersion < MinSchemaVersion || n.SchemaVersion > CurrentSchemaVersion {
+		return nil, fmt.Errorf("%w %d: expected %d to %d", ErrUnsupportedVersion, n.SchemaVersion, MinSchemaVersion, CurrentSchemaVersion)
+	}
+	if err := n.validate(); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (n *Notification) validate() error {
+	switch {
+	case n.ID == "":
+		return errors.New("notification has no 'id'")
+	case n.Channel == "":
+		return errors.New("notification has no 'channel'")
+	case n.Recipient == "":
+		return errors.New("notification has no 'recipient'")
+	case n.ScheduledAt.IsZero():
+		return errors.New("notification has no 'scheduled_at'")
+	}
+	return nil
+}
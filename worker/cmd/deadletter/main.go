@@ -0,0 +1,100 @@
+// Command deadletter inspects, replays and purges the worker's dead-letter queue.
+//
+//	deadletter list   [-limit 20]
+//	deadletter replay [-limit N] [-id NOTIFICATION_ID]
+//	deadletter purge  (-id NOTIFICATION_ID | -all)
+//
+// It reads the same environment as the worker.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/rabbitConsumer"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const usage = `usage: deadletter <command> [flags]
+
+commands:
+  list    print dead-lettered messages as JSON lines, leaving them in the queue
+  replay  publish dead-lettered messages back to their original exchange and routing key
+  purge   delete dead-lettered messages
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	limit := flags.Int("limit", 0, "how many messages to process, 0 for all (list defaults to 20)")
+	id := flags.String("id", "", "only the message of this notification id")
+	all := flags.Bool("all", false, "purge: delete every dead-lettered message")
+
+	switch command {
+	case "list", "replay", "purge":
+		_ = flags.Parse(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	zlog.InitConsole()
+	cfg, err := config.NewConfig("", "")
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("couldn't load config")
+	}
+
+	consumer, _, err := rabbitconsumer.NewRabbitConsumer(ctx, cfg.RabbitMQ, config.MakeStrategy(cfg.ConsumerRetry))
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to connect to rabbitmq")
+	}
+	defer consumer.Close()
+
+	switch command {
+	case "list":
+		if *limit <= 0 {
+			*limit = 20
+		}
+		messages, err := consumer.DeadLetters(*limit)
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Msg("couldn't list dead-lettered messages")
+		}
+		enc := json.NewEncoder(os.Stdout)
+		for _, m := range messages {
+			if err := enc.Encode(m); err != nil {
+				zlog.Logger.Fatal().Err(err).Msg("couldn't write message")
+			}
+		}
+
+	case "replay":
+		n, err := consumer.ReplayDeadLetters(ctx, *limit, *id)
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Int("replayed", n).Msg("replay stopped")
+		}
+		zlog.Logger.Info().Int("replayed", n).Str("queue", cfg.RabbitMQ.DeadLetterQueue).Msg("dead-lettered messages replayed")
+
+	case "purge":
+		if *id == "" && !*all {
+			zlog.Logger.Fatal().Msg("purge needs -id or -all")
+		}
+		n, err := consumer.PurgeDeadLetters(*id)
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Int("purged", n).Msg("purge stopped")
+		}
+		zlog.Logger.Info().Int("purged", n).Str("queue", cfg.RabbitMQ.DeadLetterQueue).Msg("dead-lettered messages purged")
+	}
+}
@@ -2,43 +2,78 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/server"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/handler"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/ports"
 	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/rabbitConsumer"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/repository/receivers"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/repository/reporters"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/repository/senders"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/service"
 	"github.com/wb-go/wbf/zlog"
 )
 
+const healthCheckTimeout = 2 * time.Second
+
 func main() {
 	// make context
 	ctx := context.Background()
-	ctx, ctxStop := signal.NotifyContext(ctx, os.Interrupt)
+	// SIGTERM — так останавливает под Kubernetes, без него уведомления в отправке не дочитываются
+	ctx, ctxStop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	// init logger (before config, so config errors are logged the same way)
+	zlog.InitConsole()
 
 	cfg, err := config.NewConfig("", "")
-	fmt.Println(cfg)
 	if err != nil {
-		log.Fatal(err)
+		zlog.Logger.Fatal().Err(err).Msg("couldn't load config")
 	}
 
-	// init logger
-	zlog.InitConsole()
 	err = zlog.SetLevel(cfg.Env)
 	if err != nil {
-		log.Fatal(fmt.Errorf("error setting log level to '%s': %w", cfg.Env, err))
+		zlog.Logger.Fatal().Err(err).Str("env", cfg.Env).Msg("couldn't set log level")
 	}
+	logging.SetRules(logging.Rules{
+		Recipient:  cfg.Log.Recipient,
+		SecretKeys: cfg.Log.SecretKeys,
+	})
 	zlog.Logger.Info().
 		Str("env", cfg.Env).
 		Msg("Start app...")
+	zlog.Logger.Debug().
+		Interface("config", logging.Redacted(cfg)).
+		Msg("config loaded")
 
-	slog.Info("starting app", slog.String("env", cfg.Env))
+	// init tracing
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "worker",
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("couldn't set up tracing")
+	}
+	defer func() {
+		// контекст приложения к этому моменту уже отменен, даем время дослать спаны
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			zlog.Logger.Error().Err(err).Msg("couldn't flush traces")
+		}
+	}()
 
 	// init strategies
 	consumerRetryStrategy := config.MakeStrategy(cfg.ConsumerRetry)
@@ -53,8 +88,92 @@ func main() {
 	}
 
 	// init reciver and sender
-	receiver := receivers.NewRabbitMQReceiver(consumer, receiverRetryStrategy)
-	sender := senders.NewConsoleSender()
+	receiver := receivers.NewRabbitMQReceiver(consumer, receiverRetryStrategy, cfg.RabbitMQ.AckMode)
+	// sender plugins: свой процесс на канал, плагин заменяет и встроенный отправитель;
+	// каналы без отправителя пишет консольный
+	pluginSenders := make(map[string]*senders.PluginSender)
+	// вебхуки из получателя задает клиент API: их нельзя направить во внутреннюю сеть
+	webhookPolicy := egress.Policy{AllowedHosts: cfg.Webhooks.AllowedHosts, AllowPrivate: cfg.Webhooks.AllowPrivate}
+	routes := map[string]ports.NotificationSender{
+		domain.SLACK:      senders.NewSlackSender(cfg.Webhooks.SlackWebhookURL, cfg.Webhooks.Timeout, webhookPolicy),
+		domain.MATTERMOST: senders.NewMattermostSender(cfg.Webhooks.MattermostWebhookURL, cfg.Webhooks.Timeout, webhookPolicy),
+	}
+	switch cfg.SMS.Provider {
+	case "http":
+		provider, err := senders.NewHTTPSMSProvider(senders.HTTPSMSProviderConfig{
+			URL:           cfg.SMS.URL,
+			Method:        cfg.SMS.Method,
+			Authorization: cfg.SMS.AuthToken,
+			ContentType:   cfg.SMS.ContentType,
+			BodyTemplate:  cfg.SMS.BodyTemplate,
+			Timeout:       cfg.SMS.Timeout,
+		})
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Msg("couldn't set up sms provider")
+		}
+		routes[domain.SMS] = senders.NewSMSSender(provider, cfg.SMS.From, cfg.SMS.MaxSegments)
+	default:
+		// консольный отправитель «доставил» бы SMS, которое никто не получит
+		routes[domain.SMS] = senders.NewDisabledSender("no sms provider configured (WORKER_SMS_PROVIDER)")
+	}
+	if cfg.Push.FCMCredentialsFile != "" || cfg.Push.APNsKeyFile != "" {
+		var fcm *senders.FCMSender
+		var apns *senders.APNsSender
+		if cfg.Push.FCMCredentialsFile != "" {
+			fcm, err = senders.NewFCMSender(senders.FCMSenderConfig{
+				CredentialsFile: cfg.Push.FCMCredentialsFile,
+				BaseURL:         cfg.Push.FCMBaseURL,
+				TokenURL:        cfg.Push.FCMTokenURL,
+				Timeout:         cfg.Push.Timeout,
+			})
+			if err != nil {
+				zlog.Logger.Fatal().Err(err).Msg("couldn't set up fcm sender")
+			}
+		}
+		if cfg.Push.APNsKeyFile != "" {
+			apns, err = senders.NewAPNsSender(senders.APNsSenderConfig{
+				KeyFile: cfg.Push.APNsKeyFile,
+				KeyID:   cfg.Push.APNsKeyID,
+				TeamID:  cfg.Push.APNsTeamID,
+				Topic:   cfg.Push.APNsTopic,
+				BaseURL: cfg.Push.APNsBaseURL,
+				Timeout: cfg.Push.Timeout,
+			})
+			if err != nil {
+				zlog.Logger.Fatal().Err(err).Msg("couldn't set up apns sender")
+			}
+		}
+		routes[domain.PUSH] = senders.NewPushSender(fcm, apns)
+	} else {
+		routes[domain.PUSH] = senders.NewDisabledSender("no push credentials configured (WORKER_FCM_CREDENTIALS_FILE, WORKER_APNS_KEY_FILE)")
+	}
+	for _, channel := range cfg.RabbitMQ.Channels {
+		path, ok := cfg.Plugins.Executables[channel]
+		if !ok {
+			continue
+		}
+		if _, known := domain.LookupChannel(channel); !known {
+			// получателя канала проверяет сам плагин
+			if err := domain.RegisterChannel(domain.ChannelSpec{Name: channel, Content: domain.AllContent}); err != nil {
+				zlog.Logger.Fatal().Err(err).Str("channel", channel).Msg("couldn't register plugin channel")
+			}
+		}
+		pluginSender := senders.NewPluginSender(channel, path, cfg.Plugins.Timeout)
+		if err := pluginSender.Start(); err != nil {
+			// не фатально: плагин перезапустится при следующей отправке, /readyz покажет ошибку
+			zlog.Logger.Error().Err(err).Str("channel", channel).Msg("couldn't start sender plugin")
+		}
+		defer pluginSender.Close()
+		pluginSenders[channel] = pluginSender
+		routes[channel] = pluginSender
+	}
+	for _, channel := range cfg.RabbitMQ.Channels {
+		if disabled, ok := routes[channel].(*senders.DisabledSender); ok {
+			zlog.Logger.Warn().Str("channel", channel).Str("reason", disabled.Reason()).Msg("channel has no provider, its notifications will fail")
+		}
+	}
+	sender := senders.NewChannelRouter(senders.NewConsoleSender(), routes)
+	reporter := reporters.NewRabbitReporter(consumer, consumerRetryStrategy)
 
 	// init duration
 	duration, err := time.ParseDuration(cfg.CheckPeriod)
@@ -65,8 +184,29 @@ func main() {
 			Msg("invalid check period")
 	}
 
-	// init notificationService and run
-	notificationService := service.NewNotificationService(receiver, sender, duration)
+	// init notificationService
+	notificationService := service.NewNotificationService(receiver, sender, reporter, duration, cfg.Dispatch, cfg.RabbitMQ.RequeueDelay)
+
+	// init probes server
+	liveness := health.NewRegistry(healthCheckTimeout).
+		Add("heap_loop", notificationService.CheckLoop)
+	readiness := health.NewRegistry(healthCheckTimeout).
+		Add("rabbitmq_consumer", consumer.Check).
+		Add("heap_loop", notificationService.CheckLoop)
+	for channel, pluginSender := range pluginSenders {
+		readiness.Add("plugin_"+channel, pluginSender.Check)
+	}
+	httpServer := server.NewHTTPServer(handler.NewRouter(liveness, readiness))
+	go func() {
+		err := httpServer.GracefulRun(ctx, cfg.Server.Host, cfg.Server.Port)
+		if err != nil {
+			zlog.Logger.Error().
+				Err(err).
+				Msg("probes server stopped")
+		}
+	}()
+
+	// run notificationService
 	err = notificationService.Run(ctx, cfg.RabbitMQ)
 	if err != nil {
 		zlog.Logger.Fatal().
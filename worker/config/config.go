@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
 	"github.com/wb-go/wbf/config"
 	"github.com/wb-go/wbf/retry"
 )
@@ -14,6 +18,14 @@ type Config struct {
 	ConsumerRetry RetryConfig    
 	ReceiverRetry RetryConfig   
 	CheckPeriod   string         
+	Server        ServerConfig
+	Tracing       TracingConfig
+	Log           LogConfig
+	Dispatch      DispatchConfig
+	Plugins       PluginsConfig
+	Webhooks      WebhooksConfig
+	SMS           SMSConfig
+	Push          PushConfig
 }
 
 func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
@@ -44,8 +56,207 @@ func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
 	myConfig.RabbitMQ.VHost = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_VHOST")
 	myConfig.RabbitMQ.Exchange = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_EXCHANGE")
 	myConfig.RabbitMQ.Queue = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_QUEUE")
+	myConfig.RabbitMQ.StatusRoutingKey = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_STATUS_ROUTING_KEY")
+	if myConfig.RabbitMQ.StatusRoutingKey == "" {
+		myConfig.RabbitMQ.StatusRoutingKey = "status"
+	}
+	myConfig.RabbitMQ.Prefetch = cfg.GetInt("WORKER_RABBITMQ_PREFETCH")
+	if myConfig.RabbitMQ.Prefetch <= 0 {
+		myConfig.RabbitMQ.Prefetch = 100
+	}
+	myConfig.RabbitMQ.AckMode = cfg.GetString("WORKER_RABBITMQ_ACK_MODE")
+	switch myConfig.RabbitMQ.AckMode {
+	case "":
+		myConfig.RabbitMQ.AckMode = AckOnDelivery
+	case AckOnDelivery, AckOnReceive:
+	default:
+		return nil, fmt.Errorf("invalid WORKER_RABBITMQ_ACK_MODE '%s': expected on_delivery or on_receive", myConfig.RabbitMQ.AckMode)
+	}
+	var err error
+	requeueDelay := cfg.GetString("WORKER_RABBITMQ_REQUEUE_DELAY")
+	if requeueDelay == "" {
+		requeueDelay = "1s"
+	}
+	myConfig.RabbitMQ.RequeueDelay, err = time.ParseDuration(requeueDelay)
+	if err != nil || myConfig.RabbitMQ.RequeueDelay < 0 {
+		return nil, fmt.Errorf("invalid WORKER_RABBITMQ_REQUEUE_DELAY '%s': expected a duration like '1s'", requeueDelay)
+	}
+	myConfig.RabbitMQ.DeadLetterExchange = cfg.GetString("WORKER_RABBITMQ_DEAD_LETTER_EXCHANGE")
+	if myConfig.RabbitMQ.DeadLetterExchange == "" {
+		myConfig.RabbitMQ.DeadLetterExchange = myConfig.RabbitMQ.Exchange + ".dead-letter"
+	}
+	myConfig.RabbitMQ.DeadLetterQueue = cfg.GetString("WORKER_RABBITMQ_DEAD_LETTER_QUEUE")
+	if myConfig.RabbitMQ.DeadLetterQueue == "" {
+		myConfig.RabbitMQ.DeadLetterQueue = myConfig.RabbitMQ.Queue + ".dead-letter"
+	}
+	myConfig.RabbitMQ.CancelExchange = cfg.GetString("DELAYED_NOTIFIER_RABBITMQ_CANCEL_EXCHANGE")
+	if myConfig.RabbitMQ.CancelExchange == "" {
+		myConfig.RabbitMQ.CancelExchange = myConfig.RabbitMQ.Exchange + ".cancel"
+	}
+
+	// Sender plugins: исполняемые файлы <dir>/<канал>
+	myConfig.Plugins.Dir = cfg.GetString("WORKER_PLUGINS_DIR")
+	pluginsTimeout := cfg.GetString("WORKER_PLUGINS_TIMEOUT")
+	if pluginsTimeout == "" {
+		pluginsTimeout = "10s"
+	}
+	myConfig.Plugins.Timeout, err = time.ParseDuration(pluginsTimeout)
+	if err != nil || myConfig.Plugins.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid WORKER_PLUGINS_TIMEOUT '%s': expected a positive duration like '10s'", pluginsTimeout)
+	}
+	if myConfig.Plugins.Dir != "" {
+		myConfig.Plugins.Executables, err = discoverPlugins(myConfig.Plugins.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKER_PLUGINS_DIR '%s': %w", myConfig.Plugins.Dir, err)
+		}
+	}
+
+	channels := cfg.GetString("WORKER_CHANNELS")
+	if channels == "" {
+		channels = strings.Join(myConfig.Plugins.channelNames(), ",")
+	}
+	for _, channel := range strings.Split(channels, ",") {
+		channel = strings.TrimSpace(channel)
+		if _, ok := myConfig.Plugins.Executables[channel]; !ok {
+			if _, err := domain.NotificationChannelFromString(channel); err != nil {
+				return nil, fmt.Errorf("invalid WORKER_CHANNELS '%s': %w", channels, err)
+			}
+		}
+		myConfig.RabbitMQ.Channels = append(myConfig.RabbitMQ.Channels, channel)
+	}
 	myConfig.CheckPeriod = cfg.GetString("CHECK_PERIOD")
 
+	// Dispatch (пулы отправителей по каналам)
+	myConfig.Dispatch.Concurrency = cfg.GetInt("WORKER_DISPATCH_CONCURRENCY")
+	myConfig.Dispatch.QueueSize = cfg.GetInt("WORKER_DISPATCH_QUEUE_SIZE")
+	if myConfig.Dispatch.Concurrency <= 0 {
+		myConfig.Dispatch.Concurrency = 4
+	}
+	if myConfig.Dispatch.QueueSize <= 0 {
+		myConfig.Dispatch.QueueSize = 100
+	}
+	drainTimeout := cfg.GetString("WORKER_DISPATCH_DRAIN_TIMEOUT")
+	if drainTimeout == "" {
+		drainTimeout = "30s"
+	}
+	myConfig.Dispatch.DrainTimeout, err = time.ParseDuration(drainTimeout)
+	if err != nil || myConfig.Dispatch.DrainTimeout <= 0 {
+		return nil, fmt.Errorf("invalid WORKER_DISPATCH_DRAIN_TIMEOUT '%s': expected a positive duration like '30s'", drainTimeout)
+	}
+	myConfig.Dispatch.Channels = make(map[string]ChannelDispatchConfig)
+	for _, channel := range myConfig.RabbitMQ.Channels {
+		prefix := "WORKER_DISPATCH_" + strings.ToUpper(channel) + "_"
+		myConfig.Dispatch.Channels[channel] = ChannelDispatchConfig{
+			Concurrency: cfg.GetInt(prefix + "CONCURRENCY"),
+			QueueSize:   cfg.GetInt(prefix + "QUEUE_SIZE"),
+		}
+	}
+
+	// Slack / Mattermost incoming webhooks
+	myConfig.Webhooks.SlackWebhookURL = cfg.GetString("WORKER_SLACK_WEBHOOK_URL")
+	myConfig.Webhooks.MattermostWebhookURL = cfg.GetString("WORKER_MATTERMOST_WEBHOOK_URL")
+	webhookTimeout := cfg.GetString("WORKER_WEBHOOK_TIMEOUT")
+	if webhookTimeout == "" {
+		webhookTimeout = "10s"
+	}
+	myConfig.Webhooks.Timeout, err = time.ParseDuration(webhookTimeout)
+	if err != nil || myConfig.Webhooks.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid WORKER_WEBHOOK_TIMEOUT '%s': expected a positive duration like '10s'", webhookTimeout)
+	}
+	for _, host := range strings.Split(cfg.GetString("WORKER_WEBHOOK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			myConfig.Webhooks.AllowedHosts = append(myConfig.Webhooks.AllowedHosts, host)
+		}
+	}
+	myConfig.Webhooks.AllowPrivate = cfg.GetBool("WORKER_WEBHOOK_ALLOW_PRIVATE")
+
+	// SMS
+	myConfig.SMS.Provider = cfg.GetString("WORKER_SMS_PROVIDER")
+	switch myConfig.SMS.Provider {
+	case "", "http":
+	default:
+		return nil, fmt.Errorf("invalid WORKER_SMS_PROVIDER '%s': expected 'http' or empty", myConfig.SMS.Provider)
+	}
+	myConfig.SMS.From = cfg.GetString("WORKER_SMS_FROM")
+	myConfig.SMS.MaxSegments = cfg.GetInt("WORKER_SMS_MAX_SEGMENTS")
+	if myConfig.SMS.MaxSegments <= 0 {
+		myConfig.SMS.MaxSegments = 6
+	}
+	myConfig.SMS.URL = cfg.GetString("WORKER_SMS_HTTP_URL")
+	if myConfig.SMS.Provider == "http" && myConfig.SMS.URL == "" {
+		return nil, fmt.Errorf("invalid WORKER_SMS_HTTP_URL '': expected the gateway url when WORKER_SMS_PROVIDER is 'http'")
+	}
+	myConfig.SMS.Method = cfg.GetString("WORKER_SMS_HTTP_METHOD")
+	myConfig.SMS.AuthToken = cfg.GetString("WORKER_SMS_HTTP_AUTH_TOKEN")
+	myConfig.SMS.ContentType = cfg.GetString("WORKER_SMS_HTTP_CONTENT_TYPE")
+	myConfig.SMS.BodyTemplate = cfg.GetString("WORKER_SMS_HTTP_BODY_TEMPLATE")
+	smsTimeout := cfg.GetString("WORKER_SMS_TIMEOUT")
+	if smsTimeout == "" {
+		smsTimeout = "10s"
+	}
+	myConfig.SMS.Timeout, err = time.ParseDuration(smsTimeout)
+	if err != nil || myConfig.SMS.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid WORKER_SMS_TIMEOUT '%s': expected a positive duration like '10s'", smsTimeout)
+	}
+
+	// Push (FCM / APNs)
+	myConfig.Push.FCMCredentialsFile = cfg.GetString("WORKER_FCM_CREDENTIALS_FILE")
+	myConfig.Push.FCMBaseURL = cfg.GetString("WORKER_FCM_BASE_URL")
+	myConfig.Push.FCMTokenURL = cfg.GetString("WORKER_FCM_TOKEN_URL")
+	myConfig.Push.APNsKeyFile = cfg.GetString("WORKER_APNS_KEY_FILE")
+	myConfig.Push.APNsKeyID = cfg.GetString("WORKER_APNS_KEY_ID")
+	myConfig.Push.APNsTeamID = cfg.GetString("WORKER_APNS_TEAM_ID")
+	myConfig.Push.APNsTopic = cfg.GetString("WORKER_APNS_TOPIC")
+	myConfig.Push.APNsBaseURL = cfg.GetString("WORKER_APNS_BASE_URL")
+	pushTimeout := cfg.GetString("WORKER_PUSH_TIMEOUT")
+	if pushTimeout == "" {
+		pushTimeout = "10s"
+	}
+	myConfig.Push.Timeout, err = time.ParseDuration(pushTimeout)
+	if err != nil || myConfig.Push.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid WORKER_PUSH_TIMEOUT '%s': expected a positive duration like '10s'", pushTimeout)
+	}
+
+	// HTTP server (probes)
+	myConfig.Server.Host = cfg.GetString("WORKER_SERVER_HOST")
+	myConfig.Server.Port = cfg.GetInt("WORKER_SERVER_PORT")
+	if myConfig.Server.Host == "" {
+		myConfig.Server.Host = "0.0.0.0"
+	}
+	if myConfig.Server.Port == 0 {
+		myConfig.Server.Port = 8090
+	}
+
+	// Logging
+	myConfig.Log.Recipient = cfg.GetString("WORKER_LOG_RECIPIENT")
+	switch myConfig.Log.Recipient {
+	case "":
+		myConfig.Log.Recipient = "mask"
+	case "keep", "mask", "hash":
+	default:
+		return nil, fmt.Errorf("invalid WORKER_LOG_RECIPIENT '%s': expected keep, mask or hash", myConfig.Log.Recipient)
+	}
+	secretKeys := cfg.GetString("WORKER_LOG_SECRET_KEYS")
+	if secretKeys == "" {
+		secretKeys = "password,secret,token,dsn,webhook"
+	}
+	myConfig.Log.SecretKeys = strings.Split(secretKeys, ",")
+
+	// Tracing
+	myConfig.Tracing.Exporter = cfg.GetString("WORKER_TRACING_EXPORTER")
+	myConfig.Tracing.Endpoint = cfg.GetString("WORKER_TRACING_ENDPOINT")
+	myConfig.Tracing.Insecure = cfg.GetBool("WORKER_TRACING_INSECURE")
+	myConfig.Tracing.SampleRatio = cfg.GetFloat64("WORKER_TRACING_SAMPLE_RATIO")
+	if myConfig.Tracing.Exporter == "" {
+		myConfig.Tracing.Exporter = "none"
+	}
+	if myConfig.Tracing.Endpoint == "" {
+		myConfig.Tracing.Endpoint = "localhost:4318"
+	}
+	if myConfig.Tracing.SampleRatio <= 0 || myConfig.Tracing.SampleRatio > 1 {
+		myConfig.Tracing.SampleRatio = 1
+	}
+
 	// Retry
 	// Consumer retry
 	myConfig.ConsumerRetry.Attempts = cfg.GetInt("DELAYED_NOTIFIER_RETRY_CONSUMER_ATTEMPTS")
@@ -60,6 +271,26 @@ func NewConfig(envFilePath string, configFilePath string) (*Config, error) {
 	return myConfig, nil
 }
 
+// discoverPlugins maps every executable file in dir to the channel named after it.
+func discoverPlugins(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	executables := make(map[string]string)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		executables[entry.Name()] = filepath.Join(dir, entry.Name())
+	}
+	return executables, nil
+}
+
 func MakeStrategy(c RetryConfig) retry.Strategy {
 	return retry.Strategy{
 		Attempts: c.Attempts,
@@ -1,5 +1,12 @@
 package config
 
+import (
+	"sort"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+)
+
 type RabbitMQConfig struct {
 	User     string `yaml:"user" env:"RABBITMQ_USER"`         // Логин для подключения к RabbitMQ
 	Password string `yaml:"password" env:"RABBITMQ_PASSWORD"` // Пароль для подключения
@@ -7,9 +14,123 @@ type RabbitMQConfig struct {
 	Port     int    `yaml:"port" env:"RABBITMQ_PORT"`         // Порт RabbitMQ (обычно 5672)
 	VHost    string `yaml:"vhost" env:"RABBITMQ_VHOST"`       // Виртуальный хост в RabbitMQ, для логической сегментации очередей
 	Exchange string `yaml:"exchange" env:"RABBITMQ_EXCHANGE"` // Название exchange для публикации сообщений
-	Queue    string `yaml:"queue" env:"RABBITMQ_QUEUE"`       // Название очереди, в которую будут публиковаться сообщения
-	AutoAck bool 
+	Queue    string `yaml:"queue" env:"RABBITMQ_QUEUE"`       // префикс очередей каналов: <queue>.<channel>
+
+	StatusRoutingKey string `yaml:"status_routing_key" env:"RABBITMQ_STATUS_ROUTING_KEY"` // routing key отчетов о доставке
+	Prefetch         int    `yaml:"prefetch" env:"PREFETCH"`                             // сколько неподтвержденных сообщений брокер отдает воркеру (QoS)
+	AckMode          string `yaml:"ack_mode" env:"ACK_MODE"`                             // on_delivery (после отправки) / on_receive (сразу после получения)
+	RequeueDelay     time.Duration `yaml:"requeue_delay" env:"REQUEUE_DELAY"`            // пауза перед возвратом сообщения в очередь после временной ошибки
+	DeadLetterExchange string `yaml:"dead_letter_exchange" env:"DEAD_LETTER_EXCHANGE"` // fanout exchange для неразбираемых и окончательно не отправленных сообщений
+	DeadLetterQueue    string `yaml:"dead_letter_queue" env:"DEAD_LETTER_QUEUE"`       // очередь, где они хранятся до разбора
+	Channels           []string `yaml:"channels" env:"CHANNELS"`                       // каналы, которые обслуживает этот воркер (своя очередь на каждый)
+	CancelExchange     string   `yaml:"cancel_exchange" env:"CANCEL_EXCHANGE"`         // fanout exchange, откуда приходят отмены уведомлений из кучи
+
+}
+
+// QueueFor names the queue of a notification channel; delayed-notifier checks for
+// it before accepting notifications for the channel.
+func (c RabbitMQConfig) QueueFor(channel string) string {
+	return c.Queue + "." + channel
+}
+
+// Ack modes of the notification consumer.
+const (
+	AckOnDelivery = "on_delivery"
+	AckOnReceive  = "on_receive"
+)
+
+type ServerConfig struct {
+	Host string `yaml:"host" env:"HOST"` // например, "0.0.0.0"
+	Port int    `yaml:"port" env:"PORT"` // порт для /healthz и /readyz
+}
+
+type LogConfig struct {
+	Recipient  string   `yaml:"recipient" env:"RECIPIENT"`     // как писать получателя в логи: keep / mask / hash
+	SecretKeys []string `yaml:"secret_keys" env:"SECRET_KEYS"` // подстроки имен полей, значения которых скрываются
+}
+
+type DispatchConfig struct {
+	Concurrency  int                              `yaml:"concurrency" env:"CONCURRENCY"`     // отправителей на канал по умолчанию
+	QueueSize    int                              `yaml:"queue_size" env:"QUEUE_SIZE"`       // наступивших уведомлений в очереди канала по умолчанию
+	DrainTimeout time.Duration                    `yaml:"drain_timeout" env:"DRAIN_TIMEOUT"` // сколько ждать отправок при остановке
+	Channels     map[string]ChannelDispatchConfig `yaml:"channels"`                          // переопределения для отдельных каналов
+}
+
+type ChannelDispatchConfig struct {
+	Concurrency int `yaml:"concurrency" env:"CONCURRENCY"`
+	QueueSize   int `yaml:"queue_size" env:"QUEUE_SIZE"`
+}
+
+// For returns the pool settings of a channel, falling back to the defaults.
+func (c DispatchConfig) For(channel string) ChannelDispatchConfig {
+	result := ChannelDispatchConfig{Concurrency: c.Concurrency, QueueSize: c.QueueSize}
+	if override, ok := c.Channels[channel]; ok {
+		if override.Concurrency > 0 {
+			result.Concurrency = override.Concurrency
+		}
+		if override.QueueSize > 0 {
+			result.QueueSize = override.QueueSize
+		}
+	}
+	return result
+}
+
+type PluginsConfig struct {
+	Dir         string            `yaml:"dir" env:"DIR"`         // каталог sender plugins, пусто — без плагинов
+	Timeout     time.Duration     `yaml:"timeout" env:"TIMEOUT"` // предел на вызов плагина и на его запуск
+	Executables map[string]string `yaml:"-"`                     // канал → путь к исполняемому файлу
+}
+
+// channelNames lists the built-in channels followed by the channels of plugins.
+func (c PluginsConfig) channelNames() []string {
+	names := domain.ChannelNames()
+	plugins := make([]string, 0, len(c.Executables))
+	for channel := range c.Executables {
+		if _, ok := domain.LookupChannel(channel); !ok {
+			plugins = append(plugins, channel)
+		}
+	}
+	sort.Strings(plugins)
+	return append(names, plugins...)
+}
+
+type WebhooksConfig struct {
+	SlackWebhookURL      string        `yaml:"slack_url" env:"SLACK_WEBHOOK_URL"`           // вебхук для получателей-каналов Slack ("#ops")
+	MattermostWebhookURL string        `yaml:"mattermost_url" env:"MATTERMOST_WEBHOOK_URL"` // то же для Mattermost
+	Timeout              time.Duration `yaml:"timeout" env:"TIMEOUT"`                       // таймаут запроса к вебхуку
+	AllowedHosts         []string      `yaml:"allowed_hosts" env:"ALLOWED_HOSTS"`           // куда можно слать вебхуки из получателя; пусто — любой внешний хост
+	AllowPrivate         bool          `yaml:"allow_private" env:"ALLOW_PRIVATE"`           // разрешить вебхуки из получателя на внутренние адреса
+}
+
+type SMSConfig struct {
+	Provider     string        `yaml:"provider" env:"PROVIDER"`                     // пусто — без SMS, http — шлюз по HTTP
+	From         string        `yaml:"from" env:"FROM"`                             // имя или номер отправителя
+	MaxSegments  int           `yaml:"max_segments" env:"MAX_SEGMENTS"`             // длиннее — ошибка без повторов
+	URL          string        `yaml:"http_url" env:"HTTP_URL"`                     // адрес API шлюза
+	Method       string        `yaml:"http_method" env:"HTTP_METHOD"`               // по умолчанию POST
+	AuthToken    string        `yaml:"http_auth_token" env:"HTTP_AUTH_TOKEN"`       // значение заголовка Authorization
+	ContentType  string        `yaml:"http_content_type" env:"HTTP_CONTENT_TYPE"`   // по умолчанию application/json
+	BodyTemplate string        `yaml:"http_body_template" env:"HTTP_BODY_TEMPLATE"` // text/template над To, From, Text, NotificationID
+	Timeout      time.Duration `yaml:"timeout" env:"TIMEOUT"`                       // таймаут запроса к шлюзу
+}
+
+type PushConfig struct {
+	FCMCredentialsFile string        `yaml:"fcm_credentials_file" env:"FCM_CREDENTIALS_FILE"` // JSON-ключ service account, пусто — без FCM
+	FCMBaseURL         string        `yaml:"fcm_base_url" env:"FCM_BASE_URL"`                 // пусто — https://fcm.googleapis.com
+	FCMTokenURL        string        `yaml:"fcm_token_url" env:"FCM_TOKEN_URL"`               // пусто — token_uri из ключа
+	APNsKeyFile        string        `yaml:"apns_key_file" env:"APNS_KEY_FILE"`               // .p8-ключ, пусто — без APNs
+	APNsKeyID          string        `yaml:"apns_key_id" env:"APNS_KEY_ID"`
+	APNsTeamID         string        `yaml:"apns_team_id" env:"APNS_TEAM_ID"`
+	APNsTopic          string        `yaml:"apns_topic" env:"APNS_TOPIC"`       // bundle id приложения
+	APNsBaseURL        string        `yaml:"apns_base_url" env:"APNS_BASE_URL"` // пусто — https://api.push.apple.com
+	Timeout            time.Duration `yaml:"timeout" env:"TIMEOUT"`             // таймаут запроса к FCM / APNs
+}
 
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"EXPORTER"`         // none / stdout / otlp
+	Endpoint    string  `yaml:"endpoint" env:"ENDPOINT"`         // host:port OTLP/HTTP коллектора
+	Insecure    bool    `yaml:"insecure" env:"INSECURE"`         // отправлять трассы без TLS
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"` // доля новых трасс; трассы из delayed-notifier пишутся по решению родителя
 }
 
 type RetryConfig struct {
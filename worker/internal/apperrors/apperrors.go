@@ -0,0 +1,36 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPermanent marks send failures that retrying cannot fix: a malformed recipient,
+// a channel without a sender, a message the provider refuses. Such notifications are
+// rejected to the dead-letter exchange instead of being requeued.
+var ErrPermanent = errors.New("permanent failure")
+
+// Permanent wraps err so that IsPermanent reports true for it.
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// IsPermanent reports whether err, or any error it wraps, is permanent.
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrPermanent)
+}
+
+// ErrUnreachable marks a recipient that can't receive anything on its channel any
+// more, e.g. a push token of an uninstalled app. It is reported back to
+// delayed-notifier, which stops accepting notifications for the recipient.
+var ErrUnreachable = errors.New("recipient unreachable")
+
+// Unreachable wraps err so that both IsUnreachable and IsPermanent report true for it.
+func Unreachable(err error) error {
+	return Permanent(fmt.Errorf("%w: %w", ErrUnreachable, err))
+}
+
+// IsUnreachable reports whether err, or any error it wraps, is an unreachable recipient.
+func IsUnreachable(err error) bool {
+	return errors.Is(err, ErrUnreachable)
+}
@@ -0,0 +1,179 @@
+// Package dispatcher sends due notifications through bounded worker pools, one per
+// channel, so a slow channel can't hold up deliveries through the others.
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dispatchQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_dispatch_queued",
+			Help: "Due notifications waiting for a free sender, by channel",
+		},
+		[]string{"channel"},
+	)
+	dispatchBusy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_dispatch_busy_workers",
+			Help: "Senders currently sending, by channel",
+		},
+		[]string{"channel"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(dispatchQueued, dispatchBusy)
+}
+
+// ErrClosed is returned by Submit after Drain has started.
+var ErrClosed = errors.New("dispatcher is closed")
+
+// Handler sends one notification; ctx is cancelled only when draining runs out of time.
+type Handler func(ctx context.Context, notification *model.Notification)
+
+type Dispatcher struct {
+	cfg     config.DispatchConfig
+	handler Handler
+
+	poolsMu sync.Mutex
+	pools   map[string]*pool
+
+	// Submit держит mu на чтение, Drain берет его на запись, чтобы после закрытия
+	// ничего не попало в очереди, которые воркеры уже дочитали
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{} // будит заблокированные Submit
+	closeOnce sync.Once
+	draining  chan struct{} // воркеры дочитывают очереди и выходят
+
+	sendCtx    context.Context // не отменяется при остановке сервиса, только по истечении DrainTimeout
+	cancelSend context.CancelFunc
+	workers    sync.WaitGroup
+}
+
+type pool struct {
+	channel string
+	queue   chan *model.Notification
+}
+
+func New(cfg config.DispatchConfig, handler Handler) *Dispatcher {
+	sendCtx, cancelSend := context.WithCancel(context.Background())
+	return &Dispatcher{
+		cfg:        cfg,
+		handler:    handler,
+		pools:      make(map[string]*pool),
+		closing:    make(chan struct{}),
+		draining:   make(chan struct{}),
+		sendCtx:    sendCtx,
+		cancelSend: cancelSend,
+	}
+}
+
+// Submit queues the notification for its channel's pool. It blocks while that queue
+// is full, which stops the caller from taking more work, or until ctx is done.
+func (d *Dispatcher) Submit(ctx context.Context, notification *model.Notification) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+
+	p := d.poolFor(notification.Channel.String())
+	select {
+	case p.queue <- notification:
+		dispatchQueued.WithLabelValues(p.channel).Inc()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.closing:
+		return ErrClosed
+	}
+}
+
+// poolFor returns the channel's pool, starting it on first use.
+func (d *Dispatcher) poolFor(channel string) *pool {
+	d.poolsMu.Lock()
+	defer d.poolsMu.Unlock()
+	if p, ok := d.pools[channel]; ok {
+		return p
+	}
+	poolCfg := d.cfg.For(channel)
+	p := &pool{
+		channel: channel,
+		queue:   make(chan *model.Notification, poolCfg.QueueSize),
+	}
+	d.pools[channel] = p
+	d.workers.Add(poolCfg.Concurrency)
+	for range poolCfg.Concurrency {
+		go d.work(p)
+	}
+	return p
+}
+
+func (d *Dispatcher) work(p *pool) {
+	defer d.workers.Done()
+	for {
+		select {
+		case notification := <-p.queue:
+			d.send(p, notification)
+		case <-d.draining:
+			for {
+				select {
+				case notification := <-p.queue:
+					d.send(p, notification)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) send(p *pool, notification *model.Notification) {
+	dispatchQueued.WithLabelValues(p.channel).Dec()
+	dispatchBusy.WithLabelValues(p.channel).Inc()
+	defer dispatchBusy.WithLabelValues(p.channel).Dec()
+	d.handler(d.sendCtx, notification)
+}
+
+// Drain stops accepting notifications and waits for queued and in-flight sends.
+// After DrainTimeout the sends still running are cancelled; Drain returns once they exit.
+func (d *Dispatcher) Drain() error {
+	// сначала будим Submit, заблокированные на полной очереди, иначе Lock их не дождется
+	d.closeOnce.Do(func() { close(d.closing) })
+	d.mu.Lock()
+	alreadyClosed := d.closed
+	d.closed = true
+	d.mu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+	close(d.draining)
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d.cfg.DrainTimeout)
+	defer timer.Stop()
+	defer d.cancelSend()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		d.cancelSend()
+		<-done
+		return errors.New("drain timed out, in-flight sends were cancelled")
+	}
+}
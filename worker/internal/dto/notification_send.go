@@ -1,59 +1,91 @@
 package dto
 
 import (
-	"encoding/json"
 	"fmt"
-	"time"
 
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/internaltypes"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/pkg/types"
-
 )
 
-type NotificationToSend struct {
-	ID          string `json:"id"`
-	Recipient   string `json:"recipient" db:"recipient"`       // email, telegram id и т.д.
-	Channel     string `json:"channel" db:"channel"`           // email, telegram
-	Message     string `json:"message" db:"message"`           // текст уведомления
-	ScheduledAt string `json:"scheduled_at" db:"scheduled_at"` // время отправки
-}
-
-func ToModelFromSend(send []byte) (*model.Notification, error) {
-	obj, err := ToDTOFromSend(send)
+// ToModelFromSend decodes a notification of any schema version the wire package
+// still accepts, so the worker keeps working while delayed-notifier is upgraded.
+func ToModelFromSend(contentType string, send []byte) (*model.Notification, error) {
+	obj, err := wire.DecodeNotification(contentType, send)
 	if err != nil {
-		return nil, fmt.Errorf("invalid notification json: %w", err)
+		return nil, err
 	}
-	
+
 	uuid, err := types.NewUUID(obj.ID)
 	if err != nil {
 		return nil, fmt.Errorf("novalid id in dto: %w", err)
 	}
-	
-	rec := internaltypes.RecipientFromString(obj.Recipient)
-	ch, err := internaltypes.NotificationChannelFromString(obj.Channel)
+
+	rec := domain.RecipientFromString(obj.Recipient)
+	ch, err := domain.NotificationChannelFromString(obj.Channel)
 	if err != nil {
 		return nil, fmt.Errorf("novalid Channel in dto: %w", err)
 	}
-	shAt, err := time.Parse("2006-01-02T15:04:05Z07:00", obj.ScheduledAt)
-	if err != nil {
-		return nil, fmt.Errorf("novalid ScheduledAt in dto: %w", err)
-	}
 	return &model.Notification{
 		ID:          &uuid,
 		Recipient:   rec,
 		Channel:     ch,
 		Message:     obj.Message,
-		ScheduledAt: shAt, // ISO8601
+		ScheduledAt: obj.ScheduledAt,
+		Content: domain.Content{
+			Subject:     obj.Subject,
+			BodyHTML:    obj.BodyHTML,
+			Attachments: toModelAttachments(obj.Attachments),
+			Metadata:    obj.Metadata,
+		},
 	}, nil
 }
 
+// ToWireFromModel builds the wire form of a notification for sender plugins.
+func ToWireFromModel(notification *model.Notification) *wire.Notification {
+	return &wire.Notification{
+		SchemaVersion: wire.CurrentSchemaVersion,
+		ID:            notification.ID.String(),
+		Recipient:     notification.Recipient.String(),
+		Channel:       notification.Channel.String(),
+		Message:       notification.Message,
+		ScheduledAt:   notification.ScheduledAt,
+		Subject:       notification.Subject,
+		BodyHTML:      notification.BodyHTML,
+		Attachments:   toWireAttachments(notification.Attachments),
+		Metadata:      notification.Metadata,
+	}
+}
 
-func ToDTOFromSend(send []byte) (*NotificationToSend, error) {
-	var NotifySend NotificationToSend
-	err := json.Unmarshal(send, &NotifySend)
-	if err != nil {
-		return nil, fmt.Errorf("could not marshal NotificationToSend: %w", err)
+func toModelAttachments(attachments []wire.Attachment) []domain.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	result := make([]domain.Attachment, len(attachments))
+	for i, a := range attachments {
+		result[i] = domain.Attachment{
+			URL:         a.URL,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
+	}
+	return result
+}
+
+func toWireAttachments(attachments []domain.Attachment) []wire.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	result := make([]wire.Attachment, len(attachments))
+	for i, a := range attachments {
+		result[i] = wire.Attachment{
+			URL:         a.URL,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
 	}
-	return &NotifySend, nil
+	return result
 }
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+const (
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// StatusReportMessage is sent back to delayed-notifier after a delivery attempt.
+type StatusReportMessage struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`          // delivered / failed
+	Error  string `json:"error,omitempty"` // текст ошибки для failed
+	At     string `json:"at"`              // время попытки, RFC3339
+
+	Unreachable bool `json:"unreachable,omitempty"` // получатель больше не может получать уведомления канала
+
+	// цель попытки: по ней delayed-notifier отличает отчет о прошлой цели цепочки от отчета о текущей
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+}
+
+func ToStatusReportFromModel(notification *model.Notification, sendErr error, at time.Time) ([]byte, error) {
+	msg := StatusReportMessage{
+		ID:     notification.ID.String(),
+		Status: StatusDelivered,
+		At:     at.UTC().Format(time.RFC3339Nano),
+
+		Channel:   notification.Channel.String(),
+		Recipient: notification.Recipient.String(),
+	}
+	if sendErr != nil {
+		msg.Status = StatusFailed
+		msg.Error = sendErr.Error()
+		msg.Unreachable = apperrors.IsUnreachable(sendErr)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal StatusReportMessage: %w", err)
+	}
+	return body, nil
+}
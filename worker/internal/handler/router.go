@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRouter serves k8s probes and Prometheus metrics. Liveness only looks at
+// the worker's own loop, readiness also at the RabbitMQ connection.
+func NewRouter(liveness *health.Registry, readiness *health.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", reportHandler(liveness))
+	mux.HandleFunc("GET /readyz", reportHandler(readiness))
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return mux
+}
+
+func reportHandler(registry *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := registry.Run(r.Context())
+
+		status := http.StatusOK
+		if !report.Up() {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,45 @@
+// Package logging is the worker's only way to log: zlog with correlation fields
+// taken from the context and with secrets and recipients redacted by Rules.
+package logging
+
+import (
+	"context"
+
+	"github.com/wb-go/wbf/zlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ctxKey struct{}
+
+// Correlation fields attached to every log line about a message.
+const (
+	FieldRequestID      = "request_id"      // X-Request-ID запроса на создание в delayed-notifier
+	FieldNotificationID = "notification_id" // id уведомления (MessageId сообщения)
+	FieldChannel        = "channel"
+	FieldRecipient      = "recipient" // уже замаскированный по правилам
+	FieldTraceID        = "trace_id"
+)
+
+// WithFields returns ctx whose logger has the given string fields added to those already
+// in ctx. Empty values are skipped, so callers don't need to check what they know.
+func WithFields(ctx context.Context, fields ...string) context.Context {
+	logCtx := Ctx(ctx).With()
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] != "" {
+			logCtx = logCtx.Str(fields[i], fields[i+1])
+		}
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logCtx = logCtx.Str(FieldTraceID, sc.TraceID().String())
+	}
+	logger := logCtx.Logger()
+	return context.WithValue(ctx, ctxKey{}, &logger)
+}
+
+// Ctx returns the logger stored by WithFields, or the global one.
+func Ctx(ctx context.Context) *zlog.Zerolog {
+	if logger, ok := ctx.Value(ctxKey{}).(*zlog.Zerolog); ok {
+		return logger
+	}
+	return &zlog.Logger
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+)
+
+// How recipients appear in logs.
+const (
+	RecipientKeep = "keep" // как есть, только для отладки
+	RecipientMask = "mask" // j***@example.com, 12***89
+	RecipientHash = "hash" // sha256:<12 hex>, одинаковый для одного получателя
+)
+
+const redacted = "***"
+
+type Rules struct {
+	Recipient  string   // keep / mask / hash
+	SecretKeys []string // поле считается секретом, если его имя содержит одну из подстрок (без учета регистра)
+}
+
+var rules atomic.Pointer[Rules]
+
+// SetRules replaces the redaction rules. Until it is called recipients are masked
+// and nothing is treated as a secret.
+func SetRules(r Rules) {
+	keys := make([]string, 0, len(r.SecretKeys))
+	for _, key := range r.SecretKeys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	r.SecretKeys = keys
+	rules.Store(&r)
+}
+
+func currentRules() Rules {
+	if r := rules.Load(); r != nil {
+		return *r
+	}
+	return Rules{Recipient: RecipientMask}
+}
+
+// Recipient returns the recipient as it may appear in logs.
+func Recipient(value string) string {
+	switch currentRules().Recipient {
+	case RecipientKeep:
+		return value
+	case RecipientHash:
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	default:
+		return maskRecipient(value)
+	}
+}
+
+func maskRecipient(value string) string {
+	if local, domain, ok := strings.Cut(value, "@"); ok && local != "" {
+		return local[:1] + redacted + "@" + domain
+	}
+	if len(value) > 6 {
+		return value[:2] + redacted + value[len(value)-2:]
+	}
+	return redacted
+}
+
+// Redacted returns v as a JSON-like map with the values of secret fields replaced,
+// for logging configs and other structs that may hold credentials.
+func Redacted(v any) any {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return redacted
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return redacted
+	}
+	return redactValue(decoded, currentRules().SecretKeys)
+}
+
+func redactValue(v any, secretKeys []string) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			if isSecret(key, secretKeys) {
+				value[key] = redacted
+				continue
+			}
+			value[key] = redactValue(nested, secretKeys)
+		}
+	case []any:
+		for i, nested := range value {
+			value[i] = redactValue(nested, secretKeys)
+		}
+	}
+	return v
+}
+
+func isSecret(key string, secretKeys []string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,18 +3,51 @@ package model
 import (
 	"time"
 
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/internaltypes"
-	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/pkg/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
 )
 
 
 type Notification struct {
 	ID          *types.UUID                            `json:"id" db:"id"`                           // PRIMARY KEY,
-	Recipient   internaltypes.Recipient              `json:"recipient" db:"recipient"`             // email, telegram id и т.д.
-	Channel     internaltypes.NotificationChannel `json:"channel" db:"channel"`                 // email, telegram
+	Recipient   domain.Recipient              `json:"recipient" db:"recipient"`             // email, telegram id и т.д.
+	Channel     domain.NotificationChannel `json:"channel" db:"channel"`                 // email, telegram
 	Message     string                            `json:"message" db:"message"`                 // текст уведомления
 	ScheduledAt time.Time                         `json:"scheduled_at" db:"scheduled_at"`       // время отправки
 	Status      string                            `json:"status" db:"status"`                   // pending / sent / cancelled / failed
 	Tries       int                               `json:"tries" db:"tries"`                     // количество попыток отправки
 	LastError   *string                           `json:"last_error,omitempty" db:"last_error"` // текст последней ошибки (может быть NULL)
-}
\ No newline at end of file
+
+	domain.Content // subject, body_html, attachments, metadata (схема v3); отправитель берет то, что поддерживает канал
+
+	TraceParent string    `json:"-"` // W3C traceparent спана получения из RabbitMQ
+	ReceivedAt  time.Time `json:"-"` // когда сообщение попало в кучу, начало спана ожидания
+	RequestID   string    `json:"-"` // X-Request-ID создания в delayed-notifier (CorrelationId сообщения), для логов
+
+	Delivery Acknowledger `json:"-"` // подтверждение сообщения RabbitMQ после отправки, nil в режиме on_receive
+}
+
+// Cancellation asks to drop a notification received earlier. With Channel and Recipient
+// set it applies only to the copy addressed to them: delayed-notifier may publish the
+// notification again under the same id to a recipient it resolved anew.
+type Cancellation struct {
+	ID        string
+	Channel   string // пусто — любая копия уведомления
+	Recipient string
+}
+
+// Matches reports whether the cancellation applies to notification.
+func (c Cancellation) Matches(notification *Notification) bool {
+	if notification.ID.String() != c.ID {
+		return false
+	}
+	return c.Channel == "" || (notification.Channel.String() == c.Channel && notification.Recipient.String() == c.Recipient)
+}
+
+// Acknowledger settles the broker message a notification was received from.
+// It is nil when the message was acknowledged on receipt.
+type Acknowledger interface {
+	Ack() error                 // уведомление отправлено
+	Requeue() error             // временная ошибка, брокер доставит сообщение снова
+	Reject(reason string) error // постоянная ошибка, сообщение с причиной уходит в dead-letter exchange
+}
@@ -0,0 +1,11 @@
+package model
+
+// SMS is a text message handed to an SMS provider.
+type SMS struct {
+	NotificationID string
+	To             string // E.164
+	From           string // имя или номер отправителя, пусто — по умолчанию провайдера
+	Text           string
+	Encoding       string // gsm7 / ucs2
+	Segments       int    // сколько SMS получит абонент
+}
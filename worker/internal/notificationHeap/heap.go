@@ -0,0 +1,133 @@
+package notificationheap
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Heap is a min-heap of values keyed by K and ordered by due time; values due at
+// the same time come out in insertion order. Push, Pop and Remove are O(log n).
+// Heap is not safe for concurrent use.
+type Heap[K comparable, V any] struct {
+	items entries[K, V]
+	index map[K]*entry[K, V]
+	seq   uint64 // порядок вставки для равных времен
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	at    time.Time
+	seq   uint64
+	value V
+	pos   int // позиция в items, поддерживается Swap
+}
+
+func New[K comparable, V any]() *Heap[K, V] {
+	return &Heap[K, V]{index: make(map[K]*entry[K, V])}
+}
+
+func (h *Heap[K, V]) Len() int {
+	return len(h.items)
+}
+
+// Push schedules value at the given time. A value already stored under key is
+// replaced and moved to the new time, so redelivered messages don't fire twice;
+// the replaced value is returned so the caller can release it.
+func (h *Heap[K, V]) Push(key K, at time.Time, value V) (old V, replaced bool) {
+	h.seq++
+	if e, ok := h.index[key]; ok {
+		old = e.value
+		e.at, e.seq, e.value = at, h.seq, value
+		heap.Fix(&h.items, e.pos)
+		return old, true
+	}
+	e := &entry[K, V]{key: key, at: at, seq: h.seq, value: value}
+	h.index[key] = e
+	heap.Push(&h.items, e)
+	return old, false
+}
+
+// Get returns the value stored under key without removing it.
+func (h *Heap[K, V]) Get(key K) (V, bool) {
+	e, ok := h.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Peek returns the earliest value and its due time without removing it.
+func (h *Heap[K, V]) Peek() (V, time.Time, bool) {
+	if len(h.items) == 0 {
+		var zero V
+		return zero, time.Time{}, false
+	}
+	root := h.items[0]
+	return root.value, root.at, true
+}
+
+// Pop removes and returns the earliest value.
+func (h *Heap[K, V]) Pop() (V, bool) {
+	if len(h.items) == 0 {
+		var zero V
+		return zero, false
+	}
+	e := heap.Pop(&h.items).(*entry[K, V])
+	delete(h.index, e.key)
+	return e.value, true
+}
+
+// PopDue removes and returns the earliest value if it is due at now.
+func (h *Heap[K, V]) PopDue(now time.Time) (V, bool) {
+	if len(h.items) == 0 || h.items[0].at.After(now) {
+		var zero V
+		return zero, false
+	}
+	return h.Pop()
+}
+
+// Remove drops the value stored under key, e.g. when its notification is cancelled.
+func (h *Heap[K, V]) Remove(key K) (V, bool) {
+	e, ok := h.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	heap.Remove(&h.items, e.pos)
+	delete(h.index, key)
+	return e.value, true
+}
+
+// entries implements heap.Interface; only Heap calls it.
+type entries[K comparable, V any] []*entry[K, V]
+
+func (es entries[K, V]) Len() int { return len(es) }
+
+func (es entries[K, V]) Less(i, j int) bool {
+	if !es[i].at.Equal(es[j].at) {
+		return es[i].at.Before(es[j].at)
+	}
+	return es[i].seq < es[j].seq
+}
+
+func (es entries[K, V]) Swap(i, j int) {
+	es[i], es[j] = es[j], es[i]
+	es[i].pos = i
+	es[j].pos = j
+}
+
+func (es *entries[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.pos = len(*es)
+	*es = append(*es, e)
+}
+
+func (es *entries[K, V]) Pop() any {
+	old := *es
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil // не держим ссылку на отправленное уведомление
+	*es = old[:n-1]
+	return e
+}
@@ -0,0 +1,254 @@
+package notificationheap
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// reference is the model the heap is checked against: a plain slice searched linearly.
+type reference struct {
+	items []refItem
+	seq   uint64
+}
+
+type refItem struct {
+	key   int
+	at    time.Time
+	seq   uint64
+	value int
+}
+
+func (m *reference) push(key int, at time.Time, value int) (int, bool) {
+	m.seq++
+	for i, item := range m.items {
+		if item.key == key {
+			m.items[i] = refItem{key: key, at: at, seq: m.seq, value: value}
+			return item.value, true
+		}
+	}
+	m.items = append(m.items, refItem{key: key, at: at, seq: m.seq, value: value})
+	return 0, false
+}
+
+// min returns the index of the earliest item, insertion order breaking ties.
+func (m *reference) min() int {
+	best := -1
+	for i, item := range m.items {
+		if best < 0 || item.at.Before(m.items[best].at) ||
+			(item.at.Equal(m.items[best].at) && item.seq < m.items[best].seq) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (m *reference) take(i int) refItem {
+	item := m.items[i]
+	m.items = append(m.items[:i], m.items[i+1:]...)
+	return item
+}
+
+func (m *reference) remove(key int) (int, bool) {
+	for i, item := range m.items {
+		if item.key == key {
+			return m.take(i).value, true
+		}
+	}
+	return 0, false
+}
+
+func TestHeapMatchesModel(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for seed := uint64(1); seed <= 50; seed++ {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(seed, seed))
+			h := New[int, int]()
+			ref := &reference{}
+			// мало ключей и времен: чаще замены и равные времена
+			randomTime := func() time.Time { return base.Add(time.Duration(rnd.IntN(20)) * time.Second) }
+
+			for step := 0; step < 2000; step++ {
+				switch op := rnd.IntN(10); {
+				case op < 5:
+					key, at, value := rnd.IntN(50), randomTime(), step
+					gotOld, gotReplaced := h.Push(key, at, value)
+					wantOld, wantReplaced := ref.push(key, at, value)
+					if gotReplaced != wantReplaced || gotOld != wantOld {
+						t.Fatalf("step %d: Push(%d) = %d, %v, want %d, %v", step, key, gotOld, gotReplaced, wantOld, wantReplaced)
+					}
+				case op < 6:
+					got, gotOK := h.Pop()
+					var want int
+					i := ref.min()
+					if i >= 0 {
+						want = ref.take(i).value
+					}
+					if gotOK != (i >= 0) || got != want {
+						t.Fatalf("step %d: Pop() = %d, %v, want %d, %v", step, got, gotOK, want, i >= 0)
+					}
+				case op < 8:
+					now := randomTime()
+					got, gotOK := h.PopDue(now)
+					var want int
+					i := ref.min()
+					wantOK := i >= 0 && !ref.items[i].at.After(now)
+					if wantOK {
+						want = ref.take(i).value
+					}
+					if gotOK != wantOK || got != want {
+						t.Fatalf("step %d: PopDue(%s) = %d, %v, want %d, %v", step, now, got, gotOK, want, wantOK)
+					}
+				default:
+					key := rnd.IntN(50)
+					got, gotOK := h.Remove(key)
+					want, wantOK := ref.remove(key)
+					if gotOK != wantOK || got != want {
+						t.Fatalf("step %d: Remove(%d) = %d, %v, want %d, %v", step, key, got, gotOK, want, wantOK)
+					}
+				}
+
+				if h.Len() != len(ref.items) {
+					t.Fatalf("step %d: Len() = %d, want %d", step, h.Len(), len(ref.items))
+				}
+				value, at, ok := h.Peek()
+				if i := ref.min(); ok != (i >= 0) || (ok && (value != ref.items[i].value || !at.Equal(ref.items[i].at))) {
+					t.Fatalf("step %d: Peek() = %d, %s, %v, want item %+v", step, value, at, ok, ref.items)
+				}
+			}
+		})
+	}
+}
+
+func TestPopDueReleasesInOrder(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rnd := rand.New(rand.NewPCG(7, 7))
+	h := New[int, int]()
+	for key := 0; key < 10000; key++ {
+		h.Push(key, base.Add(time.Duration(rnd.IntN(100))*time.Millisecond), key)
+	}
+	for _, key := range rnd.Perm(10000)[:1000] {
+		h.Remove(key)
+	}
+
+	// время идет вперед рывками, как у serveHeap: каждый раз отдается все наступившее
+	var lastAt time.Time
+	lastKey := -1
+	released := 0
+	for now := base; h.Len() > 0; now = now.Add(time.Duration(rnd.IntN(5)) * time.Millisecond) {
+		for {
+			_, at, ok := h.Peek()
+			key, due := h.PopDue(now)
+			if !due {
+				if ok && !at.After(now) {
+					t.Fatalf("item due at %s not released at %s", at, now)
+				}
+				break
+			}
+			if at.After(now) {
+				t.Fatalf("item due at %s released early at %s", at, now)
+			}
+			if at.Before(lastAt) || (at.Equal(lastAt) && key < lastKey) {
+				t.Fatalf("released %d at %s after %d at %s", key, at, lastKey, lastAt)
+			}
+			lastAt, lastKey = at, key
+			released++
+		}
+	}
+	if released != 9000 {
+		t.Fatalf("released %d items, want 9000", released)
+	}
+}
+
+func TestPushReplacesAndReschedules(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := New[string, string]()
+	h.Push("a", base.Add(time.Second), "a1")
+	h.Push("b", base.Add(2*time.Second), "b1")
+
+	// повторная доставка переносит уведомление, а не добавляет второе
+	old, replaced := h.Push("a", base.Add(3*time.Second), "a2")
+	if !replaced || old != "a1" {
+		t.Fatalf("Push(a) = %q, %v, want a1, true", old, replaced)
+	}
+	if h.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", h.Len())
+	}
+	if value, ok := h.Get("a"); !ok || value != "a2" {
+		t.Fatalf("Get(a) = %q, %v, want a2, true", value, ok)
+	}
+	if value, _ := h.PopDue(base.Add(2 * time.Second)); value != "b1" {
+		t.Fatalf("first due = %q, want b1", value)
+	}
+	if _, ok := h.PopDue(base.Add(2 * time.Second)); ok {
+		t.Fatal("a2 released before its new time")
+	}
+	if _, ok := h.Remove("a"); !ok {
+		t.Fatal("Remove(a) found nothing")
+	}
+	if _, ok := h.Remove("a"); ok {
+		t.Fatal("second Remove(a) found a value")
+	}
+	if _, ok := h.Pop(); ok || h.Len() != 0 {
+		t.Fatal("heap must be empty")
+	}
+}
+
+const benchmarkSize = 1_000_000
+
+// filledHeap returns a heap of benchmarkSize items due within an hour of base.
+func filledHeap(b *testing.B, base time.Time) *Heap[int, int] {
+	b.Helper()
+	rnd := rand.New(rand.NewPCG(1, 1))
+	h := New[int, int]()
+	for key := 0; key < benchmarkSize; key++ {
+		h.Push(key, base.Add(time.Duration(rnd.Int64N(int64(time.Hour)))), key)
+	}
+	return h
+}
+
+// BenchmarkPushPop1M measures one Push and one Pop on a heap holding 1M items.
+func BenchmarkPushPop1M(b *testing.B) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := filledHeap(b, base)
+	rnd := rand.New(rand.NewPCG(2, 2))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Push(benchmarkSize+i, base.Add(time.Duration(rnd.Int64N(int64(time.Hour)))), i)
+		h.Pop()
+	}
+}
+
+// BenchmarkRemove1M measures removing a random item from a heap holding 1M items.
+func BenchmarkRemove1M(b *testing.B) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := filledHeap(b, base)
+	rnd := rand.New(rand.NewPCG(2, 2))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := rnd.IntN(benchmarkSize)
+		at := base.Add(time.Duration(rnd.Int64N(int64(time.Hour))))
+		h.Remove(key)
+		// возвращаем элемент, чтобы размер кучи не менялся
+		b.StopTimer()
+		h.Push(key, at, key)
+		b.StartTimer()
+	}
+}
+
+// BenchmarkFillAndDrain1M pushes 1M items and releases them all with PopDue.
+func BenchmarkFillAndDrain1M(b *testing.B) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h := filledHeap(b, base)
+		for {
+			if _, ok := h.PopDue(base.Add(time.Hour)); !ok {
+				break
+			}
+		}
+	}
+}
@@ -12,6 +12,17 @@ type NotificationReceiver interface {
 	StopReceiving() error
 }
 
+// CancellationReceiver is implemented by receivers that also deliver cancellations
+// of notifications they already handed out.
+type CancellationReceiver interface {
+	ReceiveCancellations(ctx context.Context, cancel func(ctx context.Context, cancellation model.Cancellation)) error
+}
+
 type NotificationSender interface {
 	Send(ctx context.Context, notification *model.Notification) error
-}
\ No newline at end of file
+}
+
+// StatusReporter tells delayed-notifier how a delivery attempt ended.
+type StatusReporter interface {
+	Report(ctx context.Context, notification *model.Notification, sendErr error) error
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+// SMSProvider hands a message to an SMS gateway. Errors the gateway will keep returning,
+// like a rejected number, are wrapped in apperrors.Permanent.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, sms model.SMS) error
+}
@@ -0,0 +1,45 @@
+package rabbitconsumer
+
+import (
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumeCancellations subscribes to the cancel exchange with a queue of this worker
+// only: every worker must see every cancellation, since any of them may hold the
+// notification in its heap. The queue is deleted with the connection, cancellations
+// published while the worker is down are lost together with its heap.
+func (c *Consumer) ConsumeCancellations() (<-chan amqp091.Delivery, error) {
+	if err := c.Chan.ExchangeDeclare(
+		c.Cfg.CancelExchange,
+		"fanout",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return nil, fmt.Errorf("error declaring cancel exchange: %w", err)
+	}
+	queue, err := c.Chan.QueueDeclare(
+		"",    // имя выберет брокер
+		false, // durable
+		true,  // autoDelete
+		true,  // exclusive
+		false, // noWait
+		nil,   // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error declaring cancel queue: %w", err)
+	}
+	if err := c.Chan.QueueBind(queue.Name, "", c.Cfg.CancelExchange, false, nil); err != nil {
+		return nil, fmt.Errorf("error binding cancel queue: %w", err)
+	}
+	// отмена не требует подтверждения: потерянная отмена оставляет то же поведение, что и без нее
+	deliveries, err := c.Chan.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't consume cancel queue '%s': %w", queue.Name, err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,94 @@
+package rabbitconsumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Headers added to a dead-lettered message. Replay uses the original exchange and
+// routing key to put the message back where it came from.
+const (
+	HeaderDeadLetterCause   = "x-dead-letter-cause"  // malformed / permanent
+	HeaderDeadLetterReason  = "x-dead-letter-reason" // текст ошибки
+	HeaderDeadLetteredAt    = "x-dead-lettered-at"   // RFC3339
+	HeaderOriginalExchange  = "x-original-exchange"
+	HeaderOriginalRouting   = "x-original-routing-key"
+	HeaderOriginalQueue     = "x-original-queue"
+	HeaderDeadLetterReplays = "x-dead-letter-replays" // сколько раз сообщение уже возвращали в работу
+)
+
+// Causes of dead-lettering.
+const (
+	CauseMalformed = "malformed" // тело не разбирается
+	CausePermanent = "permanent" // отправка завершилась постоянной ошибкой
+)
+
+var workerDeadLetteredTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "worker_dead_lettered_total",
+		Help: "Messages moved to the dead-letter queue by cause (malformed / permanent)",
+	},
+	[]string{"cause"},
+)
+
+func init() {
+	prometheus.MustRegister(workerDeadLetteredTotal)
+}
+
+// declareDeadLetter declares the dead-letter exchange and the queue that keeps
+// everything published to it. The exchange is fanout: messages keep their original
+// routing key in headers, not in the binding.
+func declareDeadLetter(ch *amqp091.Channel, rabbitCfg config.RabbitMQConfig) error {
+	if err := ch.ExchangeDeclare(rabbitCfg.DeadLetterExchange, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring dead-letter exchange '%s': %w", rabbitCfg.DeadLetterExchange, err)
+	}
+	if _, err := ch.QueueDeclare(rabbitCfg.DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring dead-letter queue '%s': %w", rabbitCfg.DeadLetterQueue, err)
+	}
+	if err := ch.QueueBind(rabbitCfg.DeadLetterQueue, "", rabbitCfg.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("error binding dead-letter queue '%s': %w", rabbitCfg.DeadLetterQueue, err)
+	}
+	return nil
+}
+
+// DeadLetter publishes a copy of the delivery to the dead-letter exchange with the
+// failure in headers and acknowledges the original. If the copy can't be published,
+// the original is returned to the queue rather than lost.
+func (c *Consumer) DeadLetter(ctx context.Context, delivery amqp091.Delivery, cause, reason string) error {
+	headers := amqp091.Table{}
+	for k, v := range delivery.Headers {
+		headers[k] = v
+	}
+	headers[HeaderDeadLetterCause] = cause
+	headers[HeaderDeadLetterReason] = reason
+	headers[HeaderDeadLetteredAt] = time.Now().UTC().Format(time.RFC3339)
+	headers[HeaderOriginalExchange] = delivery.Exchange
+	headers[HeaderOriginalRouting] = delivery.RoutingKey
+	headers[HeaderOriginalQueue] = c.Cfg.QueueFor(delivery.RoutingKey)
+
+	err := c.Chan.PublishWithContext(ctx, c.Cfg.DeadLetterExchange, delivery.RoutingKey, false, false, amqp091.Publishing{
+		Headers:       headers,
+		ContentType:   delivery.ContentType,
+		DeliveryMode:  amqp091.Persistent,
+		CorrelationId: delivery.CorrelationId,
+		MessageId:     delivery.MessageId,
+		Timestamp:     delivery.Timestamp,
+		Body:          delivery.Body,
+	})
+	if err != nil {
+		if nackErr := delivery.Nack(false, true); nackErr != nil {
+			return fmt.Errorf("couldn't dead-letter message: %w (requeue failed: %v)", err, nackErr)
+		}
+		return fmt.Errorf("couldn't dead-letter message, returned it to the queue: %w", err)
+	}
+	workerDeadLetteredTotal.WithLabelValues(cause).Inc()
+	if err := delivery.Ack(false); err != nil {
+		return fmt.Errorf("message dead-lettered, but couldn't ack the original: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package rabbitconsumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetterMessage is a dead-lettered message as the admin command shows it.
+type DeadLetterMessage struct {
+	MessageID      string `json:"message_id"`     // id уведомления
+	CorrelationID  string `json:"correlation_id"` // X-Request-ID создания
+	Cause          string `json:"cause"`
+	Reason         string `json:"reason"`
+	DeadLetteredAt string `json:"dead_lettered_at"`
+	Exchange       string `json:"exchange"`
+	RoutingKey     string `json:"routing_key"`
+	Replays        int64  `json:"replays"`
+	Body           string `json:"body"`
+}
+
+func newDeadLetterMessage(d amqp091.Delivery) DeadLetterMessage {
+	return DeadLetterMessage{
+		MessageID:      d.MessageId,
+		CorrelationID:  d.CorrelationId,
+		Cause:          headerString(d.Headers, HeaderDeadLetterCause),
+		Reason:         headerString(d.Headers, HeaderDeadLetterReason),
+		DeadLetteredAt: headerString(d.Headers, HeaderDeadLetteredAt),
+		Exchange:       headerString(d.Headers, HeaderOriginalExchange),
+		RoutingKey:     headerString(d.Headers, HeaderOriginalRouting),
+		Replays:        headerInt(d.Headers, HeaderDeadLetterReplays),
+		Body:           string(d.Body),
+	}
+}
+
+// DeadLetters returns up to limit dead-lettered messages without removing them.
+func (c *Consumer) DeadLetters(limit int) ([]DeadLetterMessage, error) {
+	var result []DeadLetterMessage
+	_, err := c.scanDeadLetters(limit, func(amqp091.Delivery) bool { return true }, func(d amqp091.Delivery) (bool, error) {
+		result = append(result, newDeadLetterMessage(d))
+		return false, nil
+	})
+	return result, err
+}
+
+// ReplayDeadLetters publishes dead-lettered messages back to the exchange and routing
+// key they were consumed from and removes them from the dead-letter queue. With an
+// empty messageID it replays up to limit messages (all when limit <= 0).
+func (c *Consumer) ReplayDeadLetters(ctx context.Context, limit int, messageID string) (int, error) {
+	return c.scanDeadLetters(limit, matchMessageID(messageID), func(d amqp091.Delivery) (bool, error) {
+		exchange := headerString(d.Headers, HeaderOriginalExchange)
+		if exchange == "" {
+			exchange = c.Cfg.Exchange
+		}
+		routingKey := headerString(d.Headers, HeaderOriginalRouting)
+		if routingKey == "" {
+			routingKey = d.RoutingKey
+		}
+		// заголовки трассировки и прочие сохраняем, причину падения убираем
+		headers := amqp091.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		for _, k := range []string{HeaderDeadLetterCause, HeaderDeadLetterReason, HeaderDeadLetteredAt, HeaderOriginalExchange, HeaderOriginalRouting, HeaderOriginalQueue} {
+			delete(headers, k)
+		}
+		headers[HeaderDeadLetterReplays] = headerInt(d.Headers, HeaderDeadLetterReplays) + 1
+
+		err := c.Chan.PublishWithContext(ctx, exchange, routingKey, false, false, amqp091.Publishing{
+			Headers:       headers,
+			ContentType:   d.ContentType,
+			DeliveryMode:  amqp091.Persistent,
+			CorrelationId: d.CorrelationId,
+			MessageId:     d.MessageId,
+			Timestamp:     d.Timestamp,
+			Body:          d.Body,
+		})
+		if err != nil {
+			return false, fmt.Errorf("couldn't replay message '%s': %w", d.MessageId, err)
+		}
+		return true, nil
+	})
+}
+
+// PurgeDeadLetters deletes dead-lettered messages: the one with messageID, or the
+// whole dead-letter queue when messageID is empty.
+func (c *Consumer) PurgeDeadLetters(messageID string) (int, error) {
+	if messageID == "" {
+		n, err := c.Chan.QueuePurge(c.Cfg.DeadLetterQueue, false)
+		if err != nil {
+			return 0, fmt.Errorf("couldn't purge dead-letter queue '%s': %w", c.Cfg.DeadLetterQueue, err)
+		}
+		return n, nil
+	}
+	return c.scanDeadLetters(0, matchMessageID(messageID), func(amqp091.Delivery) (bool, error) {
+		return true, nil
+	})
+}
+
+// scanDeadLetters reads the dead-letter queue and hands matching messages to take,
+// which reports whether the message is done with and can be acknowledged. Everything
+// else is held unacknowledged until the scan ends, so each message is seen once, and
+// then returned to the queue. At most limit messages are taken (no limit when limit <= 0).
+// The scan stops at the queue depth it started with: a replayed message that fails again
+// is dead-lettered to the tail of the queue and must not be replayed again in the same run.
+func (c *Consumer) scanDeadLetters(limit int, match func(amqp091.Delivery) bool, take func(amqp091.Delivery) (bool, error)) (int, error) {
+	queue, err := c.Chan.QueueDeclarePassive(c.Cfg.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't inspect dead-letter queue '%s': %w", c.Cfg.DeadLetterQueue, err)
+	}
+
+	var kept []amqp091.Delivery
+	defer func() {
+		for _, d := range kept {
+			_ = d.Nack(false, true)
+		}
+	}()
+
+	seen, taken := 0, 0
+	for read := 0; read < queue.Messages && (limit <= 0 || seen < limit); read++ {
+		d, ok, err := c.Chan.Get(c.Cfg.DeadLetterQueue, false)
+		if err != nil {
+			return taken, fmt.Errorf("couldn't read dead-letter queue '%s': %w", c.Cfg.DeadLetterQueue, err)
+		}
+		if !ok {
+			break
+		}
+		if !match(d) {
+			kept = append(kept, d)
+			continue
+		}
+		seen++
+		done, err := take(d)
+		if err != nil || !done {
+			kept = append(kept, d)
+			if err != nil {
+				return taken, err
+			}
+			continue
+		}
+		if err := d.Ack(false); err != nil {
+			return taken, fmt.Errorf("couldn't remove message '%s' from dead-letter queue: %w", d.MessageId, err)
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+func matchMessageID(messageID string) func(amqp091.Delivery) bool {
+	return func(d amqp091.Delivery) bool {
+		return messageID == "" || d.MessageId == messageID
+	}
+}
+
+func headerString(headers amqp091.Table, key string) string {
+	if v, ok := headers[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// headerInt reads an integer header; AMQP tables decode integers into different widths.
+func headerInt(headers amqp091.Table, key string) int64 {
+	switch v := headers[key].(type) {
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package rabbitconsumer
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
 	"github.com/rabbitmq/amqp091-go"
@@ -10,6 +11,7 @@ import (
 )
 
 type Consumer struct {
+	conn *amqp091.Connection
 	Chan *amqp091.Channel
 	Cfg  config.RabbitMQConfig
 }
@@ -52,67 +54,115 @@ func NewRabbitConsumer(ctx context.Context, rabbitCfg config.RabbitMQConfig, rab
 		return nil, nil, fmt.Errorf("error declaring exchange: %w", err)
 	}
 
-	err = retry.DoContext(ctx, rabbitmqRetryStrategy, func() error {
-		_, errQ := ch.QueueDeclare(rabbitCfg.Queue, // имя очереди
-			true,  // durable
-			false, // autoDelete
-			false, // exclusive
-			false, // noWait
-			nil,   // args)
-		)
-		if errQ == nil {
-			errQ = ch.QueueBind(rabbitCfg.Queue, "email", rabbitCfg.Exchange, false, nil)
+	// сюда уходят сообщения, которые нельзя разобрать или отправить
+	if err := declareDeadLetter(ch, rabbitCfg); err != nil {
+		return nil, nil, err
+	}
 
+	// своя очередь на каждый канал: воркеры можно развернуть отдельно под каждый канал
+	for _, channel := range rabbitCfg.Channels {
+		queue := rabbitCfg.QueueFor(channel)
+		err = retry.DoContext(ctx, rabbitmqRetryStrategy, func() error {
+			_, errQ := ch.QueueDeclare(queue, // имя очереди
+				true,  // durable
+				false, // autoDelete
+				false, // exclusive
+				false, // noWait
+				nil,   // args
+			)
+			if errQ == nil {
+				errQ = ch.QueueBind(queue, channel, rabbitCfg.Exchange, false, nil)
+			}
+			return errQ
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error declaring queue '%s': %w", queue, err)
 		}
-		return errQ
-	},
-	)
+	}
 
-	if err != nil {
-		return nil, nil, fmt.Errorf("error declaring queue '%s': %w", rabbitCfg.Queue, err)
+	// брокер не отдает больше Prefetch неподтвержденных сообщений, пока воркер не подтвердит старые
+	if err := ch.Qos(rabbitCfg.Prefetch, 0, false); err != nil {
+		return nil, nil, fmt.Errorf("error setting prefetch %d: %w", rabbitCfg.Prefetch, err)
 	}
 
 	return &Consumer{
+		conn: conn,
 		Chan: ch,
 		Cfg:  rabbitCfg,
 	}, ch, nil
 }
 
+// Check reports whether the consumer is still connected, used by /readyz.
+func (c *Consumer) Check(ctx context.Context) error {
+	if c.conn == nil || c.conn.IsClosed() {
+		return fmt.Errorf("rabbitmq connection is closed")
+	}
+	if c.Chan == nil || c.Chan.IsClosed() {
+		return fmt.Errorf("rabbitmq channel is closed")
+	}
+	return nil
+}
+
+// ConsumeWithRetry subscribes to the queue of every channel and forwards all deliveries
+// to out until ctx is cancelled or the AMQP channel is closed.
 func (c *Consumer) ConsumeWithRetry(ctx context.Context, out chan amqp091.Delivery, retryStrategy retry.Strategy) error {
-	for {
+	subscriptions := make([]<-chan amqp091.Delivery, 0, len(c.Cfg.Channels))
+	for _, channel := range c.Cfg.Channels {
+		queue := c.Cfg.QueueFor(channel)
 		var deliveries <-chan amqp091.Delivery
-
 		err := retry.DoContext(ctx, retryStrategy, func() error {
 			var err error
 			deliveries, err = c.Chan.Consume(
-				c.Cfg.Queue, // имя очереди
-				"",          // consumer — пустая строка, RabbitMQ сгенерирует уникальный тег
-				false,       // autoAck
-				false,       // exclusive
-				false,       // noLocal (не поддерживается RabbitMQ, оставляем false)
-				false,       // noWait
-				nil,         // args
+				queue, // имя очереди
+				"",    // consumer — пустая строка, RabbitMQ сгенерирует уникальный тег
+				false, // autoAck
+				false, // exclusive
+				false, // noLocal (не поддерживается RabbitMQ, оставляем false)
+				false, // noWait
+				nil,   // args
 			)
 			return err
 		})
 		if err != nil {
-			// контекст завершён — выходим
-			return err
+			return fmt.Errorf("couldn't consume queue '%s': %w", queue, err)
 		}
+		subscriptions = append(subscriptions, deliveries)
+	}
 
-		for {
+	// все подписки живут на одном канале AMQP и закрываются вместе с ним
+	var wg sync.WaitGroup
+	errs := make(chan error, len(subscriptions))
+	for _, deliveries := range subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- forward(ctx, deliveries, out)
+		}()
+	}
+	wg.Wait()
+	return <-errs
+}
+
+func forward(ctx context.Context, deliveries <-chan amqp091.Delivery, out chan amqp091.Delivery) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-deliveries:
+			if !ok {
+				// можно в будующем добавить reconnect
+				return fmt.Errorf("deliveries channel closed")
+			}
 			select {
+			case out <- m:
 			case <-ctx.Done():
 				return ctx.Err()
-			case m, ok := <-deliveries:
-				if !ok {
-					// можно в будующем добавить reconnect
-					return fmt.Errorf("deliveries channel closed")
-				}
-				out <- m
-
 			}
 		}
 	}
+}
 
+// Close closes the connection together with its channels.
+func (c *Consumer) Close() error {
+	return c.conn.Close()
 }
@@ -3,53 +3,75 @@ package receivers
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
 	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/rabbitConsumer"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/retry"
-
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/repository/receivers")
+
 type RabbitMQReceiver struct {
 	consumer      *rabbitconsumer.Consumer
 	messages      chan amqp091.Delivery
 	objectsChan   chan *model.Notification
 	retryStrategy retry.Strategy
+	ackOnReceive  bool // подтверждать сразу, а не после отправки уведомления
 }
 
-func NewRabbitMQReceiver(consumer *rabbitconsumer.Consumer, retryStrategy retry.Strategy) *RabbitMQReceiver {
+func NewRabbitMQReceiver(consumer *rabbitconsumer.Consumer, retryStrategy retry.Strategy, ackMode string) *RabbitMQReceiver {
 	return &RabbitMQReceiver{
 		consumer:      consumer,
 		messages:      make(chan amqp091.Delivery),
 		objectsChan:   make(chan *model.Notification),
 		retryStrategy: retryStrategy,
+		ackOnReceive:  ackMode == config.AckOnReceive,
 	}
 }
 
+// deliveryAcknowledger settles a delivery once the service knows how sending ended.
+// amqp091 channels are safe for concurrent use, so dispatcher workers call it directly.
+type deliveryAcknowledger struct {
+	consumer *rabbitconsumer.Consumer
+	delivery amqp091.Delivery
+}
+
+func (a deliveryAcknowledger) Ack() error {
+	return a.delivery.Ack(false)
+}
+
+func (a deliveryAcknowledger) Requeue() error {
+	return a.delivery.Nack(false, true)
+}
+
+func (a deliveryAcknowledger) Reject(reason string) error {
+	return a.consumer.DeadLetter(context.Background(), a.delivery, rabbitconsumer.CausePermanent, reason)
+}
+
 func (r *RabbitMQReceiver) StartReceiving(ctx context.Context) (chan *model.Notification, error) {
 	go func() {
 		err := r.consumer.ConsumeWithRetry(ctx, r.messages, r.retryStrategy)
 		if err != nil {
-			slog.Error("ConsumeWithRetry", "err:", err)
+			logging.Ctx(ctx).Error().Err(err).Msg("stopped consuming notifications")
 		}
 	}()
 
 	go func() {
 		defer close(r.objectsChan)
 		for delivery := range r.messages {
-			data := delivery.Body
-			object, err := r.processMessage(data)
-			if err != nil {
-				slog.Error("ConsumeWithRetry", "err:", err)
-				continue
-				// обработка ошибок далее реализую
-			}
-			r.objectsChan <- object
-			delivery.Ack(false)
-
+			r.handleDelivery(ctx, delivery)
 		}
 
 	}()
@@ -57,6 +79,84 @@ func (r *RabbitMQReceiver) StartReceiving(ctx context.Context) (chan *model.Noti
 	return r.objectsChan, nil
 }
 
+// handleDelivery decodes a delivery, continues the delayed-notifier trace from its
+// headers and hands the notification on; malformed messages are dead-lettered.
+func (r *RabbitMQReceiver) handleDelivery(ctx context.Context, delivery amqp091.Delivery) {
+	// продолжаем трассу delayed-notifier из заголовков сообщения
+	spanCtx, span := tracer.Start(tracing.ExtractAMQP(ctx, delivery.Headers), "receive "+delivery.RoutingKey,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeReceive,
+			semconv.MessagingDestinationName(delivery.Exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKey(delivery.RoutingKey),
+			semconv.MessagingMessageBodySize(len(delivery.Body)),
+		),
+	)
+	// id из свойств сообщения: их можно записать в лог, даже если тело не разбирается
+	msgCtx := logging.WithFields(spanCtx,
+		logging.FieldRequestID, delivery.CorrelationId,
+		logging.FieldNotificationID, delivery.MessageId,
+	)
+	object, err := r.processMessage(delivery)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		logging.Ctx(msgCtx).Error().Err(err).Str("routing_key", delivery.RoutingKey).Msg("couldn't decode notification, dead-lettering it")
+		// повтор не поможет: без подтверждения сообщение висело бы в очереди до перезапуска
+		if err := r.consumer.DeadLetter(ctx, delivery, rabbitconsumer.CauseMalformed, err.Error()); err != nil {
+			logging.Ctx(msgCtx).Error().Err(err).Msg("couldn't dead-letter malformed notification")
+		}
+		return
+	}
+	span.SetAttributes(attribute.String("notification.id", object.ID.String()))
+	// куча хранит уведомления дольше, чем живет спан, поэтому запоминаем только его контекст
+	object.TraceParent = tracing.TraceParent(trace.ContextWithSpan(ctx, span))
+	object.ReceivedAt = time.Now()
+	object.RequestID = delivery.CorrelationId
+	span.End()
+	logging.Ctx(msgCtx).Debug().Time("scheduled_at", object.ScheduledAt).Msg("notification received")
+	if r.ackOnReceive {
+		if err := delivery.Ack(false); err != nil {
+			logging.Ctx(msgCtx).Error().Err(err).Msg("couldn't ack notification")
+		}
+	} else {
+		// сообщение остается неподтвержденным, пока уведомление не отправлено
+		object.Delivery = deliveryAcknowledger{consumer: r.consumer, delivery: delivery}
+	}
+	r.objectsChan <- object
+}
+
+// ReceiveCancellations calls cancel with every cancellation until ctx is done or the
+// consumer is stopped.
+func (r *RabbitMQReceiver) ReceiveCancellations(ctx context.Context, cancel func(ctx context.Context, cancellation model.Cancellation)) error {
+	deliveries, err := r.consumer.ConsumeCancellations()
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("cancellations channel closed")
+			}
+			cancellation, err := wire.DecodeCancellation(delivery.ContentType, delivery.Body)
+			if err != nil {
+				logging.Ctx(ctx).Error().Err(err).Str(logging.FieldNotificationID, delivery.MessageId).Msg("couldn't decode cancellation, skipping it")
+				continue
+			}
+			cancel(tracing.ExtractAMQP(ctx, delivery.Headers), model.Cancellation{
+				ID:        cancellation.ID,
+				Channel:   cancellation.Channel,
+				Recipient: cancellation.Recipient,
+			})
+		}
+	}
+}
+
 func (r * RabbitMQReceiver) StopReceiving() error {
 	err := r.consumer.Chan.Close()
 	if err != nil {
@@ -66,9 +166,8 @@ func (r * RabbitMQReceiver) StopReceiving() error {
 	return nil
 }
 
-func (r *RabbitMQReceiver) processMessage(delivery []byte) (*model.Notification, error) {
-// определиться что будем из очереди поуолчать
-	notification, err := dto.ToModelFromSend(delivery)
+func (r *RabbitMQReceiver) processMessage(delivery amqp091.Delivery) (*model.Notification, error) {
+	notification, err := dto.ToModelFromSend(delivery.ContentType, delivery.Body)
 
 	if err != nil {
 		return nil, fmt.Errorf("bad message (could't convert to model): %w", err)
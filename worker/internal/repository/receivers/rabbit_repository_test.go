@@ -0,0 +1,74 @@
+package receivers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHandleDeliveryContinuesPublisherTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	testTracer := otel.Tracer("test")
+
+	// так публикует delayed-notifier: контекст спана публикации в заголовках
+	pubCtx, publish := testTracer.Start(context.Background(), "publish email", trace.WithSpanKind(trace.SpanKindProducer))
+	headers := tracing.InjectAMQP(pubCtx, amqp091.Table{wire.HeaderSchemaVersion: int32(wire.CurrentSchemaVersion)})
+	publish.End()
+
+	body, err := wire.EncodeNotification(wire.Notification{
+		ID:          "7f1c0e4a-8a43-4a8e-9c55-4f1f3f3f6b1e",
+		Recipient:   "user@example.com",
+		Channel:     "email",
+		Message:     "hello",
+		ScheduledAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("EncodeNotification: %v", err)
+	}
+
+	receiver := &RabbitMQReceiver{objectsChan: make(chan *model.Notification, 1)}
+	receiver.handleDelivery(context.Background(), amqp091.Delivery{
+		Headers:     headers,
+		ContentType: wire.ContentType,
+		RoutingKey:  "email",
+		Body:        body,
+	})
+	object := <-receiver.objectsChan
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	published, received := spans[0], spans[1]
+	if received.SpanKind != trace.SpanKindConsumer {
+		t.Errorf("receive span kind %s, want consumer", received.SpanKind)
+	}
+	if received.SpanContext.TraceID() != published.SpanContext.TraceID() {
+		t.Errorf("receive trace %s, want publish trace %s", received.SpanContext.TraceID(), published.SpanContext.TraceID())
+	}
+	if received.Parent.SpanID() != published.SpanContext.SpanID() {
+		t.Errorf("receive parent %s, want publish span %s", received.Parent.SpanID(), published.SpanContext.SpanID())
+	}
+
+	// отправка, начатая позже из кучи, становится ребенком спана приема
+	_, send := testTracer.Start(tracing.WithTraceParent(context.Background(), object.TraceParent), "send email")
+	send.End()
+	sent := exporter.GetSpans()[2]
+	if sent.Parent.SpanID() != received.SpanContext.SpanID() {
+		t.Errorf("send parent %s, want receive span %s", sent.Parent.SpanID(), received.SpanContext.SpanID())
+	}
+}
@@ -0,0 +1,49 @@
+package reporters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	rabbitconsumer "github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/rabbitConsumer"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/retry"
+)
+
+// RabbitReporter publishes delivery reports to the notifier exchange.
+type RabbitReporter struct {
+	consumer      *rabbitconsumer.Consumer
+	routingKey    string
+	retryStrategy retry.Strategy
+}
+
+func NewRabbitReporter(consumer *rabbitconsumer.Consumer, retryStrategy retry.Strategy) *RabbitReporter {
+	return &RabbitReporter{
+		consumer:      consumer,
+		routingKey:    consumer.Cfg.StatusRoutingKey,
+		retryStrategy: retryStrategy,
+	}
+}
+
+func (r *RabbitReporter) Report(ctx context.Context, notification *model.Notification, sendErr error) error {
+	body, err := dto.ToStatusReportFromModel(notification, sendErr, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = retry.DoContext(ctx, r.retryStrategy, func() error {
+		return r.consumer.Chan.PublishWithContext(ctx, r.consumer.Cfg.Exchange, r.routingKey, false, false, amqp091.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp091.Persistent,
+			Headers:      tracing.InjectAMQP(ctx, nil),
+			Body:         body,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't publish status report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+const apnsDefaultBaseURL = "https://api.push.apple.com"
+
+// apnsTokenLifetime is how long a provider token is reused. APNs rejects tokens older
+// than an hour and throttles ones refreshed more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsSenderConfig holds the token-based auth key from the Apple developer account.
+type APNsSenderConfig struct {
+	KeyFile string // .p8-ключ
+	KeyID   string
+	TeamID  string
+	Topic   string // bundle id приложения
+	BaseURL string // пусто — https://api.push.apple.com, для sandbox — https://api.sandbox.push.apple.com
+	Timeout time.Duration
+}
+
+// APNsSender sends through the APNs HTTP/2 provider API with a signed provider token.
+type APNsSender struct {
+	cfg    APNsSenderConfig
+	key    crypto.Signer
+	client *http.Client
+
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+func NewAPNsSender(cfg APNsSenderConfig) (*APNsSender, error) {
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, errors.New("apns key id, team id and topic are required")
+	}
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read apns key: %w", err)
+	}
+	key, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apns key: %w", err)
+	}
+	if _, ok := key.(*ecdsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("invalid apns key: expected an ECDSA P-256 key, got %T", key)
+	}
+
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = apnsDefaultBaseURL
+	}
+	return &APNsSender{
+		cfg:    cfg,
+		key:    key,
+		client: newPushClient(cfg.Timeout),
+	}, nil
+}
+
+func (s *APNsSender) send(ctx context.Context, token string, notification *model.Notification) error {
+	providerToken, err := s.providerToken()
+	if err != nil {
+		return err
+	}
+
+	alert := map[string]any{"body": notification.Message}
+	if notification.Subject != "" {
+		alert["title"] = notification.Subject
+	}
+	body, err := json.Marshal(map[string]any{
+		"aps":             map[string]any{"alert": alert},
+		"notification_id": notification.ID.String(),
+	})
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("couldn't marshal apns payload: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("invalid apns request: %w", err))
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", s.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	// тот же id при повторе: APNs и клиент видят одно уведомление
+	req.Header.Set("apns-id", notification.ID.String())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return pushRequestError("apns", err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return s.responseError(resp.StatusCode, reply)
+}
+
+func (s *APNsSender) responseError(status int, reply []byte) error {
+	var parsed struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(reply, &parsed)
+	err := fmt.Errorf("apns answered %d %s", status, parsed.Reason)
+
+	switch {
+	case status == http.StatusGone,
+		parsed.Reason == "BadDeviceToken",
+		parsed.Reason == "DeviceTokenNotForTopic":
+		// 410 Unregistered: приложение удалено или токен больше не действует
+		return apperrors.Unreachable(err)
+	case parsed.Reason == "ExpiredProviderToken":
+		s.mu.Lock()
+		s.jwt = ""
+		s.mu.Unlock()
+		return err
+	case status == http.StatusTooManyRequests || status >= 500:
+		return err
+	default:
+		return apperrors.Permanent(err)
+	}
+}
+
+// providerToken returns the current provider token, signing a new one when it gets old.
+func (s *APNsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jwt != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.jwt, nil
+	}
+
+	now := time.Now()
+	jwt, err := signJWT(
+		map[string]any{"kid": s.cfg.KeyID},
+		map[string]any{"iss": s.cfg.TeamID, "iat": now.Unix()},
+		s.key,
+	)
+	if err != nil {
+		return "", apperrors.Permanent(err)
+	}
+	s.jwt, s.issuedAt = jwt, now
+	return jwt, nil
+}
@@ -2,8 +2,8 @@ package senders
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
 )
 
@@ -13,13 +13,27 @@ func NewConsoleSender() *ConsoleSender {
 	return &ConsoleSender{}
 }
 
+// Send "delivers" the notification by writing it to the log; the correlation fields
+// and the redacted recipient come from the context logger.
 func (s *ConsoleSender) Send(ctx context.Context, notification *model.Notification) error {
-	fmt.Printf(
-		"Sending notification ID=%v to recipient=%s via channel=%s: %s\n",
-		notification.ID,
-		notification.Recipient.Val.String(),
-		notification.Channel,
-		notification.Message,
-	)
+	event := logging.Ctx(ctx).Info().
+		Str("text", notification.Message)
+	if notification.Subject != "" {
+		event = event.Str("subject", notification.Subject)
+	}
+	if notification.BodyHTML != "" {
+		event = event.Int("body_html_bytes", len(notification.BodyHTML))
+	}
+	if len(notification.Attachments) > 0 {
+		names := make([]string, len(notification.Attachments))
+		for i, attachment := range notification.Attachments {
+			names[i] = attachment.Filename
+		}
+		event = event.Strs("attachments", names)
+	}
+	if len(notification.Metadata) > 0 {
+		event = event.Interface("metadata", notification.Metadata)
+	}
+	event.Msg("console notification")
 	return nil
 }
\ No newline at end of file
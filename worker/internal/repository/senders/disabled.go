@@ -0,0 +1,29 @@
+package senders
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+// DisabledSender stands for a channel the worker serves but has no provider for. It
+// fails every notification permanently, so the notification is reported failed and
+// dead-lettered instead of being logged by the console sender and reported delivered.
+type DisabledSender struct {
+	reason string
+}
+
+func NewDisabledSender(reason string) *DisabledSender {
+	return &DisabledSender{reason: reason}
+}
+
+func (s *DisabledSender) Send(ctx context.Context, notification *model.Notification) error {
+	return apperrors.Permanent(errors.New(s.reason))
+}
+
+// Reason tells why the channel is disabled.
+func (s *DisabledSender) Reason() string {
+	return s.reason
+}
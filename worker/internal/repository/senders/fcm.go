@@ -0,0 +1,232 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+const (
+	fcmDefaultBaseURL  = "https://fcm.googleapis.com"
+	fcmDefaultTokenURL = "https://oauth2.googleapis.com/token"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMSenderConfig points the sender at a service account key and, for tests against a
+// local stand-in, at other API and token endpoints.
+type FCMSenderConfig struct {
+	CredentialsFile string // JSON-ключ service account из консоли Firebase
+	BaseURL         string // пусто — https://fcm.googleapis.com
+	TokenURL        string // пусто — token_uri из ключа
+	Timeout         time.Duration
+}
+
+// serviceAccount is the part of a Google service account key the sender needs.
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// FCMSender sends through the FCM HTTP v1 API. It trades a JWT signed with the
+// service account key for an OAuth2 access token and reuses it until shortly before
+// it expires.
+type FCMSender struct {
+	account  serviceAccount
+	key      crypto.Signer
+	baseURL  string
+	tokenURL string
+	client   *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func NewFCMSender(cfg FCMSenderConfig) (*FCMSender, error) {
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read fcm credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid fcm credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" {
+		return nil, errors.New("invalid fcm credentials: project_id and client_email are required")
+	}
+	key, err := parsePrivateKeyPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fcm credentials: %w", err)
+	}
+
+	s := &FCMSender{
+		account:  account,
+		key:      key,
+		baseURL:  strings.TrimSuffix(cfg.BaseURL, "/"),
+		tokenURL: cfg.TokenURL,
+		client:   newPushClient(cfg.Timeout),
+	}
+	if s.baseURL == "" {
+		s.baseURL = fcmDefaultBaseURL
+	}
+	if s.tokenURL == "" {
+		s.tokenURL = account.TokenURI
+	}
+	if s.tokenURL == "" {
+		s.tokenURL = fcmDefaultTokenURL
+	}
+	return s, nil
+}
+
+func (s *FCMSender) send(ctx context.Context, token string, notification *model.Notification) error {
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	content := map[string]any{"body": notification.Message}
+	if notification.Subject != "" {
+		content["title"] = notification.Subject
+	}
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token":        token,
+			"notification": content,
+			"data":         map[string]string{"notification_id": notification.ID.String()},
+		},
+	})
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("couldn't marshal fcm message: %w", err))
+	}
+	endpoint := s.baseURL + "/v1/projects/" + url.PathEscape(s.account.ProjectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("invalid fcm request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return pushRequestError("fcm", err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return s.responseError(resp.StatusCode, reply)
+}
+
+// fcmError is the error body of the v1 API.
+type fcmError struct {
+	Error struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (s *FCMSender) responseError(status int, reply []byte) error {
+	var parsed fcmError
+	_ = json.Unmarshal(reply, &parsed)
+	code := parsed.Error.Status
+	for _, detail := range parsed.Error.Details {
+		if detail.ErrorCode != "" {
+			code = detail.ErrorCode
+		}
+	}
+	err := fmt.Errorf("fcm answered %d %s: %s", status, code, parsed.Error.Message)
+
+	switch {
+	case code == "UNREGISTERED" || code == "SENDER_ID_MISMATCH":
+		// приложение удалено или токен выдан другому проекту
+		return apperrors.Unreachable(err)
+	case code == "INVALID_ARGUMENT" && strings.Contains(parsed.Error.Message, "registration token"):
+		return apperrors.Unreachable(err)
+	case status == http.StatusUnauthorized:
+		// токен доступа отозван раньше срока: получим новый при повторе
+		s.mu.Lock()
+		s.accessToken = ""
+		s.mu.Unlock()
+		return err
+	case status == http.StatusTooManyRequests || status >= 500:
+		return err
+	default:
+		return apperrors.Permanent(err)
+	}
+}
+
+// token returns a valid access token, exchanging a fresh JWT for one when needed.
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Until(s.expiresAt) > time.Minute {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]any{"kid": s.account.PrivateKeyID},
+		map[string]any{
+			"iss":   s.account.ClientEmail,
+			"scope": fcmScope,
+			"aud":   s.tokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		s.key,
+	)
+	if err != nil {
+		return "", apperrors.Permanent(err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", apperrors.Permanent(fmt.Errorf("invalid fcm token request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", pushRequestError("fcm token", err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		// неверный ключ чинится конфигурацией, сообщения пусть ждут в очереди
+		return "", fmt.Errorf("fcm token endpoint answered %d: %s", resp.StatusCode, strings.TrimSpace(string(reply)))
+	}
+
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(reply, &grant); err != nil || grant.AccessToken == "" {
+		return "", fmt.Errorf("invalid fcm token response: %s", strings.TrimSpace(string(reply)))
+	}
+	s.accessToken = grant.AccessToken
+	s.expiresAt = now.Add(time.Duration(grant.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
@@ -0,0 +1,372 @@
+package senders
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/plugin"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/dto"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wb-go/wbf/zlog"
+)
+
+var (
+	pluginCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_plugin_calls_total",
+			Help: "Calls to sender plugins by result: ok, error, permanent, timeout, unavailable",
+		},
+		[]string{"channel", "method", "result"},
+	)
+	pluginStarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_plugin_starts_total",
+			Help: "Sender plugin process starts, restarts after a crash included",
+		},
+		[]string{"channel", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pluginCalls, pluginStarts)
+}
+
+// pluginRestartBackoff limits how often a crashing plugin is started again; calls in
+// between fail as transient errors and their messages are requeued.
+const pluginRestartBackoff = time.Second
+
+var errPluginUnavailable = errors.New("sender plugin is not running")
+
+// PluginSender delivers notifications of one channel through a sender plugin, a
+// separate executable speaking the shared/plugin protocol. A crash of the plugin fails
+// only the calls in flight; the process is started again on the next call.
+type PluginSender struct {
+	channel string
+	path    string
+	timeout time.Duration
+
+	mu        sync.Mutex
+	proc      *pluginProcess
+	startedAt time.Time
+
+	nextID atomic.Uint64
+}
+
+func NewPluginSender(channel, path string, timeout time.Duration) *PluginSender {
+	return &PluginSender{
+		channel: channel,
+		path:    path,
+		timeout: timeout,
+	}
+}
+
+// Start launches the plugin ahead of the first notification, so a broken plugin shows
+// up at startup and in /readyz rather than on the first delivery.
+func (s *PluginSender) Start() error {
+	_, err := s.process()
+	return err
+}
+
+func (s *PluginSender) Send(ctx context.Context, notification *model.Notification) error {
+	req := plugin.Request{
+		Method:       plugin.MethodSend,
+		Notification: dto.ToWireFromModel(notification),
+		RequestID:    notification.RequestID,
+	}
+	return s.call(ctx, req)
+}
+
+// Check asks the plugin whether it can deliver, used by /readyz.
+func (s *PluginSender) Check(ctx context.Context) error {
+	return s.call(ctx, plugin.Request{Method: plugin.MethodHealth})
+}
+
+// Close stops the plugin: it gets EOF on stdin and time to finish the calls in flight.
+func (s *PluginSender) Close() error {
+	s.mu.Lock()
+	proc := s.proc
+	s.proc = nil
+	s.mu.Unlock()
+	if proc == nil {
+		return nil
+	}
+	return proc.stop(s.timeout)
+}
+
+func (s *PluginSender) call(ctx context.Context, req plugin.Request) error {
+	proc, err := s.process()
+	if err != nil {
+		pluginCalls.WithLabelValues(s.channel, req.Method, "unavailable").Inc()
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutMs = time.Until(deadline).Milliseconds()
+	}
+	req.ID = s.nextID.Add(1)
+
+	resp, err := proc.call(ctx, req)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		pluginCalls.WithLabelValues(s.channel, req.Method, "timeout").Inc()
+		return fmt.Errorf("plugin '%s' didn't answer in %s: %w", s.channel, s.timeout, err)
+	case err != nil:
+		pluginCalls.WithLabelValues(s.channel, req.Method, "unavailable").Inc()
+		return fmt.Errorf("plugin '%s': %w", s.channel, err)
+	case resp.Error == "":
+		pluginCalls.WithLabelValues(s.channel, req.Method, "ok").Inc()
+		return nil
+	case resp.Permanent:
+		pluginCalls.WithLabelValues(s.channel, req.Method, "permanent").Inc()
+		return apperrors.Permanent(fmt.Errorf("plugin '%s': %s", s.channel, resp.Error))
+	default:
+		pluginCalls.WithLabelValues(s.channel, req.Method, "error").Inc()
+		return fmt.Errorf("plugin '%s': %s", s.channel, resp.Error)
+	}
+}
+
+// process returns the running plugin, starting it if it isn't running.
+func (s *PluginSender) process() (*pluginProcess, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc != nil && !s.proc.exited() {
+		return s.proc, nil
+	}
+	if time.Since(s.startedAt) < pluginRestartBackoff {
+		return nil, fmt.Errorf("%w: '%s' is restarting", errPluginUnavailable, s.channel)
+	}
+
+	s.startedAt = time.Now()
+	proc, err := startPlugin(s.channel, s.path, s.timeout)
+	if err != nil {
+		pluginStarts.WithLabelValues(s.channel, "error").Inc()
+		return nil, fmt.Errorf("%w: %w", errPluginUnavailable, err)
+	}
+	pluginStarts.WithLabelValues(s.channel, "success").Inc()
+	s.proc = proc
+	return proc, nil
+}
+
+// pluginProcess is one run of the plugin executable.
+type pluginProcess struct {
+	channel string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+
+	encoder *json.Encoder
+	writes  chan pluginWrite // запросы для writeRequests, единственного пишущего в stdin
+
+	pendingMu sync.Mutex
+	pending   map[uint64]chan plugin.Response
+
+	stderrDone chan struct{} // закрывается, когда stderr дочитан: только после этого можно звать cmd.Wait
+	done       chan struct{} // закрывается, когда процесс завершился
+	waitErr    error
+}
+
+// pluginWrite is a request handed to the writer goroutine.
+type pluginWrite struct {
+	req     plugin.Request
+	written chan error
+}
+
+func startPlugin(channel, path string, handshakeTimeout time.Duration) (*pluginProcess, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open stdin of plugin '%s': %w", path, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open stdout of plugin '%s': %w", path, err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open stderr of plugin '%s': %w", path, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("couldn't start plugin '%s': %w", path, err)
+	}
+
+	p := &pluginProcess{
+		channel:    channel,
+		cmd:        cmd,
+		stdin:      stdin,
+		encoder:    json.NewEncoder(stdin),
+		writes:     make(chan pluginWrite),
+		pending:    make(map[uint64]chan plugin.Response),
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go p.logStderr(stderr)
+
+	responses := bufio.NewScanner(stdout)
+	responses.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	handshake := make(chan error, 1)
+	go func() {
+		handshake <- p.readHandshake(responses)
+	}()
+	select {
+	case err = <-handshake:
+	case <-time.After(handshakeTimeout):
+		_ = cmd.Process.Kill()
+		// чтение stdout завершится на закрытии pipe, его надо дождаться до cmd.Wait
+		<-handshake
+		err = fmt.Errorf("no handshake in %s", handshakeTimeout)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-p.stderrDone
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("plugin '%s': %w", path, err)
+	}
+
+	go p.readResponses(responses)
+	go p.writeRequests()
+	return p, nil
+}
+
+func (p *pluginProcess) readHandshake(responses *bufio.Scanner) error {
+	if !responses.Scan() {
+		return fmt.Errorf("exited before the handshake: %v", responses.Err())
+	}
+	var handshake plugin.Handshake
+	if err := json.Unmarshal(responses.Bytes(), &handshake); err != nil {
+		return fmt.Errorf("invalid handshake: %w", err)
+	}
+	if handshake.ProtocolVersion != plugin.ProtocolVersion {
+		return fmt.Errorf("protocol version %d, expected %d", handshake.ProtocolVersion, plugin.ProtocolVersion)
+	}
+	if handshake.Channel != p.channel {
+		return fmt.Errorf("handles channel '%s', but is installed as '%s'", handshake.Channel, p.channel)
+	}
+	return nil
+}
+
+// readResponses hands responses to their callers until the plugin exits, then fails
+// the calls still waiting.
+func (p *pluginProcess) readResponses(responses *bufio.Scanner) {
+	for responses.Scan() {
+		var resp plugin.Response
+		if err := json.Unmarshal(responses.Bytes(), &resp); err != nil {
+			zlog.Logger.Error().Err(err).Str("channel", p.channel).Msg("invalid response from sender plugin")
+			continue
+		}
+		p.pendingMu.Lock()
+		waiter, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.pendingMu.Unlock()
+		if ok {
+			waiter <- resp
+		}
+	}
+
+	<-p.stderrDone
+	p.waitErr = p.cmd.Wait()
+	close(p.done)
+	zlog.Logger.Warn().Err(p.waitErr).Str("channel", p.channel).Msg("sender plugin exited")
+}
+
+func (p *pluginProcess) logStderr(stderr io.Reader) {
+	defer close(p.stderrDone)
+	lines := bufio.NewScanner(stderr)
+	for lines.Scan() {
+		zlog.Logger.Info().Str("channel", p.channel).Str("plugin_output", lines.Text()).Msg("sender plugin")
+	}
+}
+
+func (p *pluginProcess) call(ctx context.Context, req plugin.Request) (plugin.Response, error) {
+	waiter := make(chan plugin.Response, 1)
+	p.pendingMu.Lock()
+	p.pending[req.ID] = waiter
+	p.pendingMu.Unlock()
+	defer func() {
+		p.pendingMu.Lock()
+		delete(p.pending, req.ID)
+		p.pendingMu.Unlock()
+	}()
+
+	// запись тоже ограничена ctx: плагин, переставший читать stdin, не держит вызывающих
+	write := pluginWrite{req: req, written: make(chan error, 1)}
+	select {
+	case p.writes <- write:
+	case <-p.done:
+		return plugin.Response{}, fmt.Errorf("%w: exited before the call: %v", errPluginUnavailable, p.waitErr)
+	case <-ctx.Done():
+		logging.Ctx(ctx).Warn().Uint64("plugin_request", req.ID).Msg("sender plugin call abandoned before it was written")
+		return plugin.Response{}, ctx.Err()
+	}
+	select {
+	case err := <-write.written:
+		if err != nil {
+			return plugin.Response{}, fmt.Errorf("couldn't write request: %w", err)
+		}
+	case <-p.done:
+		return plugin.Response{}, fmt.Errorf("%w: exited during the call: %v", errPluginUnavailable, p.waitErr)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// запрос не уместился в pipe за весь таймаут: плагин завис, следующий вызов запустит его заново
+			zlog.Logger.Error().Str("channel", p.channel).Msg("sender plugin stopped reading requests, killing it")
+			_ = p.cmd.Process.Kill()
+		}
+		return plugin.Response{}, ctx.Err()
+	}
+
+	select {
+	case resp := <-waiter:
+		return resp, nil
+	case <-p.done:
+		return plugin.Response{}, fmt.Errorf("%w: exited during the call: %v", errPluginUnavailable, p.waitErr)
+	case <-ctx.Done():
+		logging.Ctx(ctx).Warn().Uint64("plugin_request", req.ID).Msg("sender plugin call abandoned")
+		return plugin.Response{}, ctx.Err()
+	}
+}
+
+// writeRequests writes requests to the plugin's stdin one at a time until the process exits.
+func (p *pluginProcess) writeRequests() {
+	for {
+		select {
+		case write := <-p.writes:
+			write.written <- p.encoder.Encode(write.req)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *pluginProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *pluginProcess) stop(timeout time.Duration) error {
+	_ = p.stdin.Close()
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+		if err := p.cmd.Process.Kill(); err != nil {
+			return fmt.Errorf("couldn't kill plugin '%s': %w", p.channel, err)
+		}
+		<-p.done
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/plugin"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/wire"
+)
+
+// pluginModeEnv turns the test binary into a sender plugin, see runTestPlugin.
+const pluginModeEnv = "SENDERS_TEST_PLUGIN_MODE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(pluginModeEnv); mode != "" {
+		os.Exit(runTestPlugin(mode))
+	}
+	os.Exit(m.Run())
+}
+
+// testPlugin fails a send according to the mode it was started in.
+type testPlugin struct {
+	mode string
+}
+
+func (p testPlugin) Send(ctx context.Context, notification *wire.Notification) error {
+	switch p.mode {
+	case "crash":
+		os.Exit(3)
+	case "hang":
+		select {}
+	}
+	return nil
+}
+
+// runTestPlugin is the plugin side for the tests:
+//   - ok: delivers everything;
+//   - crash: exits on the first send;
+//   - hang: never answers a send;
+//   - deaf: never reads its requests;
+//   - mismatch: introduces itself as another channel.
+func runTestPlugin(mode string) int {
+	channel := "test"
+	switch mode {
+	case "mismatch":
+		channel = "other"
+	case "deaf":
+		fmt.Printf(`{"protocol_version":%d,"channel":%q}`+"\n", plugin.ProtocolVersion, channel)
+		select {}
+	}
+	if err := plugin.Serve(channel, testPlugin{mode: mode}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func newTestPluginSender(t *testing.T, mode string, timeout time.Duration) *PluginSender {
+	t.Helper()
+	t.Setenv(pluginModeEnv, mode)
+	sender := NewPluginSender("test", os.Args[0], timeout)
+	t.Cleanup(func() { _ = sender.Close() })
+	return sender
+}
+
+func TestPluginSenderDelivers(t *testing.T) {
+	sender := newTestPluginSender(t, "ok", 5*time.Second)
+	if err := sender.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := sender.Send(context.Background(), newNotification(domain.ChannelEmail, "a@example.com")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := sender.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+}
+
+func TestPluginSenderHandshakeMismatch(t *testing.T) {
+	sender := newTestPluginSender(t, "mismatch", 5*time.Second)
+	err := sender.Start()
+	if !errors.Is(err, errPluginUnavailable) || !strings.Contains(err.Error(), "installed as 'test'") {
+		t.Fatalf("Start = %v, want a channel mismatch", err)
+	}
+}
+
+func TestPluginSenderCrashDuringCall(t *testing.T) {
+	sender := newTestPluginSender(t, "crash", 5*time.Second)
+	err := sender.Send(context.Background(), newNotification(domain.ChannelEmail, "a@example.com"))
+	if !errors.Is(err, errPluginUnavailable) || !strings.Contains(err.Error(), "exited during the call") {
+		t.Fatalf("Send = %v, want the plugin exited during the call", err)
+	}
+}
+
+func TestPluginSenderCallTimeout(t *testing.T) {
+	for _, mode := range []string{"hang", "deaf"} {
+		t.Run(mode, func(t *testing.T) {
+			sender := newTestPluginSender(t, mode, 300*time.Millisecond)
+			if err := sender.Start(); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			notification := newNotification(domain.ChannelEmail, "a@example.com")
+			// больше буфера pipe: плагин, не читающий stdin, блокирует запись
+			notification.Message = strings.Repeat("a", 1<<20)
+
+			start := time.Now()
+			err := sender.Send(context.Background(), notification)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Send = %v, want a timeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("Send took %s with a 300ms timeout", elapsed)
+			}
+			if mode == "deaf" {
+				// не читающий запросы плагин убит, следующий вызов запустит новый
+				select {
+				case <-sender.proc.done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("plugin that stopped reading requests is still running")
+				}
+			}
+		})
+	}
+}
+
+func TestPluginSenderRestartBackoff(t *testing.T) {
+	sender := newTestPluginSender(t, "crash", 5*time.Second)
+	notification := newNotification(domain.ChannelEmail, "a@example.com")
+	if err := sender.Send(context.Background(), notification); !errors.Is(err, errPluginUnavailable) {
+		t.Fatalf("Send = %v, want the plugin crashed", err)
+	}
+
+	// сразу после падения плагин не перезапускается
+	t.Setenv(pluginModeEnv, "ok")
+	err := sender.Send(context.Background(), notification)
+	if !errors.Is(err, errPluginUnavailable) || !strings.Contains(err.Error(), "restarting") {
+		t.Fatalf("Send right after the crash = %v, want restarting", err)
+	}
+
+	time.Sleep(pluginRestartBackoff)
+	if err := sender.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Send after the backoff: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var pushSends = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "worker_push_sends_total",
+		Help: "Push sends by platform and result: ok, error, permanent, unreachable",
+	},
+	[]string{"platform", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(pushSends)
+}
+
+// PushSender delivers the push channel: the recipient's device token picks FCM or
+// APNs. A platform without credentials fails its notifications permanently.
+type PushSender struct {
+	fcm  *FCMSender
+	apns *APNsSender
+}
+
+func NewPushSender(fcm *FCMSender, apns *APNsSender) *PushSender {
+	return &PushSender{
+		fcm:  fcm,
+		apns: apns,
+	}
+}
+
+func (s *PushSender) Send(ctx context.Context, notification *model.Notification) error {
+	platform, token, err := domain.ParsePushToken(notification.Recipient.String())
+	if err != nil {
+		return apperrors.Unreachable(err)
+	}
+
+	switch {
+	case platform == domain.PushFCM && s.fcm != nil:
+		err = s.fcm.send(ctx, token, notification)
+	case platform == domain.PushAPNs && s.apns != nil:
+		err = s.apns.send(ctx, token, notification)
+	default:
+		err = apperrors.Permanent(fmt.Errorf("no %s credentials configured", platform))
+	}
+	pushSends.WithLabelValues(platform, pushResult(err)).Inc()
+	return err
+}
+
+func pushResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case apperrors.IsUnreachable(err):
+		return "unreachable"
+	case apperrors.IsPermanent(err):
+		return "permanent"
+	default:
+		return "error"
+	}
+}
+
+// newPushClient returns the client for a push API. Over TLS it speaks HTTP/2, which
+// APNs requires; a plain-http base URL (a local stand-in) gets HTTP/1.1.
+func newPushClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// pushRequestError strips the URL from a request error: APNs puts the device token
+// into the path.
+func pushRequestError(platform string, err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return fmt.Errorf("%s request failed: %w", platform, err)
+}
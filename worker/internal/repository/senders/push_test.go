@@ -0,0 +1,336 @@
+package senders
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/ports"
+)
+
+// verifyJWT checks the signature of a compact JWS and returns its claims.
+func verifyJWT(t *testing.T, jwt string, public crypto.PublicKey) map[string]any {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("jwt has %d parts", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("jwt signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			t.Fatalf("RS256 signature: %v", err)
+		}
+	case *ecdsa.PublicKey:
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			t.Fatal("ES256 signature doesn't verify")
+		}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("jwt claims: %v", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("jwt claims: %v", err)
+	}
+	return claims
+}
+
+// fakeFCM answers the token endpoint and the v1 send API; the device token picks the answer.
+type fakeFCM struct {
+	t            *testing.T
+	public       *rsa.PublicKey
+	tokenGrants  atomic.Int32
+	accessToken  string
+	server       *httptest.Server
+	lastPushedTo string
+}
+
+func newFakeFCM(t *testing.T, public *rsa.PublicKey) *fakeFCM {
+	f := &fakeFCM{t: t, public: public}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", r.FormValue("grant_type"))
+		}
+		claims := verifyJWT(t, r.FormValue("assertion"), f.public)
+		if claims["iss"] != "push@project.iam.gserviceaccount.com" || claims["scope"] != fcmScope {
+			t.Errorf("assertion claims %v", claims)
+		}
+		f.accessToken = "access-" + string(rune('a'+f.tokenGrants.Add(1)))
+		json.NewEncoder(w).Encode(map[string]any{"access_token": f.accessToken, "expires_in": 3600})
+	})
+	mux.HandleFunc("POST /v1/projects/demo-project/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.accessToken {
+			t.Errorf("Authorization = %q, want the granted token", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Message struct {
+				Token        string            `json:"token"`
+				Notification map[string]string `json:"notification"`
+				Data         map[string]string `json:"data"`
+			} `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode message: %v", err)
+		}
+		f.lastPushedTo = body.Message.Token
+		if body.Message.Notification["body"] != "disk is full" || body.Message.Data["notification_id"] == "" {
+			t.Errorf("message %+v", body.Message)
+		}
+		switch {
+		case strings.HasPrefix(body.Message.Token, "unregistered"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","message":"Requested entity was not found.","details":[{"errorCode":"UNREGISTERED"}]}}`))
+		case strings.HasPrefix(body.Message.Token, "malformed"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"status":"INVALID_ARGUMENT","message":"The registration token is not a valid FCM registration token"}}`))
+		case strings.HasPrefix(body.Message.Token, "unavailable"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"status":"UNAVAILABLE","message":"try later"}}`))
+		case strings.HasPrefix(body.Message.Token, "revoked"):
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"status":"UNAUTHENTICATED","message":"token revoked"}}`))
+		default:
+			w.Write([]byte(`{"name":"projects/demo-project/messages/1"}`))
+		}
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newTestFCMSender(t *testing.T) (*FCMSender, *fakeFCM) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	fake := newFakeFCM(t, &key.PublicKey)
+	account, _ := json.Marshal(serviceAccount{
+		ProjectID:    "demo-project",
+		PrivateKeyID: "key-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "push@project.iam.gserviceaccount.com",
+		TokenURI:     fake.server.URL + "/token",
+	})
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, account, 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+	sender, err := NewFCMSender(FCMSenderConfig{CredentialsFile: path, BaseURL: fake.server.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewFCMSender: %v", err)
+	}
+	return sender, fake
+}
+
+func TestFCMSend(t *testing.T) {
+	fcm, fake := newTestFCMSender(t)
+	push := NewPushSender(fcm, nil)
+	ctx := context.Background()
+
+	if err := push.Send(ctx, newNotification(domain.ChannelPush, "fcm:valid-token-0123456789")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if fake.lastPushedTo != "valid-token-0123456789" {
+		t.Errorf("pushed to %q, want the token without its prefix", fake.lastPushedTo)
+	}
+	// токен доступа переиспользуется
+	if err := push.Send(ctx, newNotification(domain.ChannelPush, "fcm:valid-token-0123456789")); err != nil {
+		t.Fatalf("second Send: %v", err)
+	}
+	if fake.tokenGrants.Load() != 1 {
+		t.Errorf("token endpoint called %d times, want 1", fake.tokenGrants.Load())
+	}
+}
+
+func TestFCMResponseClassification(t *testing.T) {
+	fcm, fake := newTestFCMSender(t)
+	push := NewPushSender(fcm, nil)
+	cases := []struct {
+		recipient   string
+		unreachable bool
+		permanent   bool
+	}{
+		{recipient: "fcm:unregistered-0123456789", unreachable: true, permanent: true},
+		{recipient: "fcm:malformed-token-0123456789", unreachable: true, permanent: true},
+		{recipient: "fcm:unavailable-0123456789"},
+		{recipient: "fcm:revoked-token-0123456789"},
+	}
+	for _, tc := range cases {
+		err := push.Send(context.Background(), newNotification(domain.ChannelPush, tc.recipient))
+		if err == nil || apperrors.IsUnreachable(err) != tc.unreachable || apperrors.IsPermanent(err) != tc.permanent {
+			t.Errorf("%s: got %v, want unreachable=%v permanent=%v", tc.recipient, err, tc.unreachable, tc.permanent)
+		}
+	}
+	// 401 сбрасывает токен доступа: следующая отправка получает новый
+	if err := push.Send(context.Background(), newNotification(domain.ChannelPush, "fcm:valid-token-0123456789")); err != nil {
+		t.Fatalf("Send after 401: %v", err)
+	}
+	if fake.tokenGrants.Load() != 2 {
+		t.Errorf("token endpoint called %d times, want 2", fake.tokenGrants.Load())
+	}
+}
+
+func newTestAPNsSender(t *testing.T, handler http.HandlerFunc) (*APNsSender, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "AuthKey.p8")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	sender, err := NewAPNsSender(APNsSenderConfig{
+		KeyFile: path,
+		KeyID:   "KEY123",
+		TeamID:  "TEAM123",
+		Topic:   "com.example.app",
+		BaseURL: server.URL,
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewAPNsSender: %v", err)
+	}
+	return sender, key
+}
+
+const apnsToken = "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+func TestAPNsSend(t *testing.T) {
+	var key *ecdsa.PrivateKey
+	var requests atomic.Int32
+	apns, key := newTestAPNsSender(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/3/device/"+apnsToken {
+			t.Errorf("path %s", r.URL.Path)
+		}
+		if r.Header.Get("apns-topic") != "com.example.app" || r.Header.Get("apns-push-type") != "alert" || r.Header.Get("apns-id") == "" {
+			t.Errorf("headers %v", r.Header)
+		}
+		claims := verifyJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "), &key.PublicKey)
+		if claims["iss"] != "TEAM123" {
+			t.Errorf("provider token claims %v", claims)
+		}
+		var payload struct {
+			APS struct {
+				Alert map[string]string `json:"alert"`
+			} `json:"aps"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.APS.Alert["body"] != "disk is full" {
+			t.Errorf("payload %+v: %v", payload, err)
+		}
+	})
+
+	push := NewPushSender(nil, apns)
+	// токен без префикса из hex-цифр — APNs
+	if err := push.Send(context.Background(), newNotification(domain.ChannelPush, apnsToken)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("apns got %d requests, want 1", requests.Load())
+	}
+}
+
+func TestAPNsResponseClassification(t *testing.T) {
+	cases := []struct {
+		status      int
+		reason      string
+		unreachable bool
+		permanent   bool
+	}{
+		{status: http.StatusGone, reason: "Unregistered", unreachable: true, permanent: true},
+		{status: http.StatusBadRequest, reason: "BadDeviceToken", unreachable: true, permanent: true},
+		{status: http.StatusBadRequest, reason: "DeviceTokenNotForTopic", unreachable: true, permanent: true},
+		{status: http.StatusBadRequest, reason: "PayloadTooLarge", permanent: true},
+		{status: http.StatusForbidden, reason: "ExpiredProviderToken"},
+		{status: http.StatusTooManyRequests, reason: "TooManyRequests"},
+		{status: http.StatusServiceUnavailable, reason: "ServiceUnavailable"},
+	}
+	for _, tc := range cases {
+		apns, _ := newTestAPNsSender(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			json.NewEncoder(w).Encode(map[string]string{"reason": tc.reason})
+		})
+		err := NewPushSender(nil, apns).Send(context.Background(), newNotification(domain.ChannelPush, "apns:"+apnsToken))
+		if err == nil || apperrors.IsUnreachable(err) != tc.unreachable || apperrors.IsPermanent(err) != tc.permanent {
+			t.Errorf("%d %s: got %v, want unreachable=%v permanent=%v", tc.status, tc.reason, err, tc.unreachable, tc.permanent)
+		}
+	}
+}
+
+func TestPushSenderInvalidTokenIsUnreachable(t *testing.T) {
+	fcm, _ := newTestFCMSender(t)
+	push := NewPushSender(fcm, nil)
+	for _, recipient := range []string{"fcm:short", "apns:not-hex", "fcm:bad token with spaces"} {
+		err := push.Send(context.Background(), newNotification(domain.ChannelPush, recipient))
+		if !apperrors.IsUnreachable(err) || !apperrors.IsPermanent(err) {
+			t.Errorf("%s: got %v, want a permanent unreachable error", recipient, err)
+		}
+	}
+}
+
+func TestPushSenderWithoutPlatformCredentials(t *testing.T) {
+	fcm, _ := newTestFCMSender(t)
+	err := NewPushSender(fcm, nil).Send(context.Background(), newNotification(domain.ChannelPush, "apns:"+apnsToken))
+	if !apperrors.IsPermanent(err) || apperrors.IsUnreachable(err) {
+		t.Fatalf("apns token without apns credentials: got %v, want permanent, not unreachable", err)
+	}
+}
+
+func TestDisabledSenderFailsPermanently(t *testing.T) {
+	// без провайдера уведомление не должно считаться доставленным
+	router := NewChannelRouter(NewConsoleSender(), map[string]ports.NotificationSender{
+		domain.SMS:  NewDisabledSender("no sms provider configured"),
+		domain.PUSH: NewDisabledSender("no push credentials configured"),
+	})
+	for _, notification := range []*model.Notification{
+		newNotification(domain.ChannelSMS, "+15551234567"),
+		newNotification(domain.ChannelPush, "fcm:valid-token-0123456789"),
+	} {
+		if err := router.Send(context.Background(), notification); !apperrors.IsPermanent(err) {
+			t.Errorf("%s: got %v, want a permanent error", notification.Channel, err)
+		}
+	}
+	if err := router.Send(context.Background(), newNotification(domain.ChannelConsole, "anyone")); err != nil {
+		t.Errorf("console: %v", err)
+	}
+}
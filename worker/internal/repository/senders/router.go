@@ -0,0 +1,29 @@
+package senders
+
+import (
+	"context"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/ports"
+)
+
+// ChannelRouter picks the sender of the notification's channel, falling back to the
+// built-in one for channels without a dedicated sender.
+type ChannelRouter struct {
+	fallback  ports.NotificationSender
+	byChannel map[string]ports.NotificationSender
+}
+
+func NewChannelRouter(fallback ports.NotificationSender, byChannel map[string]ports.NotificationSender) *ChannelRouter {
+	return &ChannelRouter{
+		fallback:  fallback,
+		byChannel: byChannel,
+	}
+}
+
+func (r *ChannelRouter) Send(ctx context.Context, notification *model.Notification) error {
+	if sender, ok := r.byChannel[notification.Channel.String()]; ok {
+		return sender.Send(ctx, notification)
+	}
+	return r.fallback.Send(ctx, notification)
+}
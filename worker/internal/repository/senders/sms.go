@@ -0,0 +1,117 @@
+package senders
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/ports"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var smsSegments = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "worker_sms_segments_total",
+		Help: "SMS segments handed to the provider, by encoding",
+	},
+	[]string{"encoding"},
+)
+
+func init() {
+	prometheus.MustRegister(smsSegments)
+}
+
+// SMS encodings.
+const (
+	EncodingGSM7 = "gsm7"
+	EncodingUCS2 = "ucs2"
+)
+
+// SMSSender counts the segments of a notification and sends it through an SMSProvider.
+// Texts longer than maxSegments are rejected rather than sent as a costly chain.
+type SMSSender struct {
+	provider    ports.SMSProvider
+	from        string
+	maxSegments int
+}
+
+func NewSMSSender(provider ports.SMSProvider, from string, maxSegments int) *SMSSender {
+	return &SMSSender{
+		provider:    provider,
+		from:        from,
+		maxSegments: maxSegments,
+	}
+}
+
+func (s *SMSSender) Send(ctx context.Context, notification *model.Notification) error {
+	encoding, segments := SMSSegments(notification.Message)
+	if segments > s.maxSegments {
+		return apperrors.Permanent(fmt.Errorf("sms takes %d %s segments, at most %d allowed", segments, encoding, s.maxSegments))
+	}
+
+	err := s.provider.SendSMS(ctx, model.SMS{
+		NotificationID: notification.ID.String(),
+		To:             notification.Recipient.String(),
+		From:           s.from,
+		Text:           notification.Message,
+		Encoding:       encoding,
+		Segments:       segments,
+	})
+	if err != nil {
+		return err
+	}
+	smsSegments.WithLabelValues(encoding).Add(float64(segments))
+	logging.Ctx(ctx).Debug().Str("encoding", encoding).Int("segments", segments).Msg("sms sent")
+	return nil
+}
+
+// Segment sizes: a single message, and a part of a concatenated one (the rest of the
+// part holds the concatenation header).
+const (
+	gsm7Single = 160
+	gsm7Part   = 153
+	ucs2Single = 70
+	ucs2Part   = 67
+)
+
+// gsm7Basic is the GSM 03.38 default alphabet, one septet per character.
+var gsm7Basic = toSet("@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà")
+
+// gsm7Extension is the extension table, two septets per character (escape + code).
+var gsm7Extension = toSet("\f^{}\\[~]|€")
+
+// SMSSegments reports the encoding text needs and how many SMS it is split into. Text
+// that fits the GSM-7 alphabet is counted in septets, anything else in UCS-2 code units.
+func SMSSegments(text string) (encoding string, segments int) {
+	septets := 0
+	for _, r := range text {
+		switch {
+		case gsm7Basic[r]:
+			septets++
+		case gsm7Extension[r]:
+			septets += 2
+		default:
+			return EncodingUCS2, countSegments(len(utf16.Encode([]rune(text))), ucs2Single, ucs2Part)
+		}
+	}
+	return EncodingGSM7, countSegments(septets, gsm7Single, gsm7Part)
+}
+
+func countSegments(units, single, part int) int {
+	if units <= single {
+		return 1
+	}
+	return (units + part - 1) / part
+}
+
+func toSet(chars string) map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range chars {
+		set[r] = true
+	}
+	return set
+}
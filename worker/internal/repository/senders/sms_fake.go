@@ -0,0 +1,40 @@
+package senders
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+// FakeSMSProvider keeps messages in memory instead of sending them. It stands in for
+// a gateway in tests only: it keeps every message, so it is not selectable in the
+// worker's configuration. Err, when set, is returned from every send.
+type FakeSMSProvider struct {
+	mu   sync.Mutex
+	sent []model.SMS
+	Err  error
+}
+
+func NewFakeSMSProvider() *FakeSMSProvider {
+	return &FakeSMSProvider{}
+}
+
+func (p *FakeSMSProvider) SendSMS(ctx context.Context, sms model.SMS) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Err != nil {
+		return p.Err
+	}
+	p.sent = append(p.sent, sms)
+	logging.Ctx(ctx).Info().Str(logging.FieldRecipient, logging.Recipient(sms.To)).Str("encoding", sms.Encoding).Int("segments", sms.Segments).Msg("fake sms provider got a message")
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (p *FakeSMSProvider) Sent() []model.SMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]model.SMS(nil), p.sent...)
+}
@@ -0,0 +1,108 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+// DefaultSMSBodyTemplate is the request body sent when no template is configured.
+const DefaultSMSBodyTemplate = `{"to":{{json .To}},"from":{{json .From}},"text":{{json .Text}}}`
+
+// HTTPSMSProviderConfig describes the gateway's API. BodyTemplate is a text/template
+// over model.SMS; the json function quotes a value, so `{{json .Text}}` is always valid
+// inside a JSON body.
+type HTTPSMSProviderConfig struct {
+	URL           string
+	Method        string
+	Authorization string // значение заголовка Authorization, например "Bearer <token>"
+	ContentType   string
+	BodyTemplate  string
+	Timeout       time.Duration
+}
+
+// HTTPSMSProvider sends SMS through a gateway with an HTTP API. Most gateways take
+// a request with the number and text; the request shape is configured, not coded.
+type HTTPSMSProvider struct {
+	cfg    HTTPSMSProviderConfig
+	body   *template.Template
+	client *http.Client
+}
+
+func NewHTTPSMSProvider(cfg HTTPSMSProviderConfig) (*HTTPSMSProvider, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("sms gateway url is empty")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+	}
+	if cfg.BodyTemplate == "" {
+		cfg.BodyTemplate = DefaultSMSBodyTemplate
+	}
+
+	body, err := template.New("sms").Funcs(template.FuncMap{"json": jsonValue}).Parse(cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sms body template: %w", err)
+	}
+	return &HTTPSMSProvider{
+		cfg:    cfg,
+		body:   body,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (p *HTTPSMSProvider) SendSMS(ctx context.Context, sms model.SMS) error {
+	var body bytes.Buffer
+	if err := p.body.Execute(&body, sms); err != nil {
+		return apperrors.Permanent(fmt.Errorf("couldn't render sms body: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, p.cfg.Method, p.cfg.URL, &body)
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("invalid sms gateway request: %w", err))
+	}
+	req.Header.Set("Content-Type", p.cfg.ContentType)
+	if p.cfg.Authorization != "" {
+		req.Header.Set("Authorization", p.cfg.Authorization)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// в адресе шлюза бывает ключ API
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("sms gateway request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("sms gateway answered %d: %s", resp.StatusCode, strings.TrimSpace(string(reply)))
+	default:
+		// неверный номер, заблокированный отправитель, неверный ключ: повтор не поможет
+		return apperrors.Permanent(fmt.Errorf("sms gateway answered %d: %s", resp.StatusCode, strings.TrimSpace(string(reply))))
+	}
+}
+
+func jsonValue(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
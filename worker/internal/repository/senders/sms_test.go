@@ -0,0 +1,204 @@
+package senders
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+func TestSMSSegments(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		encoding string
+		segments int
+	}{
+		{"empty", "", EncodingGSM7, 1},
+		{"gsm7 single", strings.Repeat("a", 160), EncodingGSM7, 1},
+		{"gsm7 concatenated", strings.Repeat("a", 161), EncodingGSM7, 2},
+		{"gsm7 two parts", strings.Repeat("a", 306), EncodingGSM7, 2},
+		{"gsm7 three parts", strings.Repeat("a", 307), EncodingGSM7, 3},
+		{"gsm7 accents", "Ça a été éèùìò", EncodingGSM7, 1},
+		// символы расширения занимают два септета: 80 × 2 = 160
+		{"gsm7 extension single", strings.Repeat("€", 80), EncodingGSM7, 1},
+		{"gsm7 extension concatenated", strings.Repeat("{", 81), EncodingGSM7, 2},
+		{"ucs2 single", strings.Repeat("я", 70), EncodingUCS2, 1},
+		{"ucs2 concatenated", strings.Repeat("я", 71), EncodingUCS2, 2},
+		{"ucs2 two parts", strings.Repeat("я", 134), EncodingUCS2, 2},
+		{"ucs2 three parts", strings.Repeat("я", 135), EncodingUCS2, 3},
+		// один символ не из GSM-7 переводит весь текст в UCS-2
+		{"ucs2 mixed", strings.Repeat("a", 69) + "я", EncodingUCS2, 1},
+		{"ucs2 mixed concatenated", strings.Repeat("a", 70) + "я", EncodingUCS2, 2},
+		// эмодзи вне BMP — суррогатная пара, две кодовые единицы: 35 × 2 = 70
+		{"ucs2 surrogate pairs single", strings.Repeat("🚀", 35), EncodingUCS2, 1},
+		{"ucs2 surrogate pairs concatenated", strings.Repeat("🚀", 36), EncodingUCS2, 2},
+	}
+	for _, tc := range cases {
+		encoding, segments := SMSSegments(tc.text)
+		if encoding != tc.encoding || segments != tc.segments {
+			t.Errorf("%s: SMSSegments = %s, %d, want %s, %d", tc.name, encoding, segments, tc.encoding, tc.segments)
+		}
+	}
+}
+
+func TestSMSSenderMaxSegments(t *testing.T) {
+	provider := NewFakeSMSProvider()
+	sender := NewSMSSender(provider, "Acme", 2)
+
+	long := newNotification(domain.ChannelSMS, "+15551234567")
+	long.Message = strings.Repeat("a", 307) // три сегмента
+	err := sender.Send(context.Background(), long)
+	if err == nil || !apperrors.IsPermanent(err) {
+		t.Fatalf("3 segments with a limit of 2: got %v, want a permanent error", err)
+	}
+	if len(provider.Sent()) != 0 {
+		t.Fatal("message over the limit reached the provider")
+	}
+
+	fits := newNotification(domain.ChannelSMS, "+15551234567")
+	fits.Message = strings.Repeat("я", 134) // два сегмента UCS-2
+	if err := sender.Send(context.Background(), fits); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	sent := provider.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("provider got %d messages, want 1", len(sent))
+	}
+	want := model.SMS{
+		NotificationID: fits.ID.String(),
+		To:             "+15551234567",
+		From:           "Acme",
+		Text:           fits.Message,
+		Encoding:       EncodingUCS2,
+		Segments:       2,
+	}
+	if sent[0] != want {
+		t.Errorf("provider got %+v, want %+v", sent[0], want)
+	}
+}
+
+// gatewayRequest is what the test gateway received.
+type gatewayRequest struct {
+	method        string
+	contentType   string
+	authorization string
+	body          string
+}
+
+func newTestGateway(t *testing.T, status int) (*httptest.Server, chan gatewayRequest) {
+	t.Helper()
+	requests := make(chan gatewayRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- gatewayRequest{
+			method:        r.Method,
+			contentType:   r.Header.Get("Content-Type"),
+			authorization: r.Header.Get("Authorization"),
+			body:          string(body),
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestHTTPSMSProviderDefaultTemplate(t *testing.T) {
+	server, requests := newTestGateway(t, http.StatusAccepted)
+	provider, err := NewHTTPSMSProvider(HTTPSMSProviderConfig{URL: server.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewHTTPSMSProvider: %v", err)
+	}
+
+	// кавычки и перевод строки в тексте не ломают JSON
+	sms := model.SMS{To: "+15551234567", From: "Acme", Text: "code \"42\"\nexpires soon"}
+	if err := provider.SendSMS(context.Background(), sms); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	got := <-requests
+	if got.method != http.MethodPost || got.contentType != "application/json" || got.authorization != "" {
+		t.Errorf("request %s %q auth %q, want POST application/json without auth", got.method, got.contentType, got.authorization)
+	}
+	var body map[string]string
+	if err := json.Unmarshal([]byte(got.body), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", got.body, err)
+	}
+	want := map[string]string{"to": sms.To, "from": sms.From, "text": sms.Text}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("body[%s] = %q, want %q", key, body[key], value)
+		}
+	}
+}
+
+func TestHTTPSMSProviderCustomTemplate(t *testing.T) {
+	server, requests := newTestGateway(t, http.StatusOK)
+	provider, err := NewHTTPSMSProvider(HTTPSMSProviderConfig{
+		URL:           server.URL,
+		Method:        http.MethodPut,
+		Authorization: "Bearer secret",
+		ContentType:   "application/vnd.gateway+json",
+		BodyTemplate:  `{"msisdn":{{json .To}},"message":{"body":{{json .Text}},"parts":{{.Segments}},"dcs":{{json .Encoding}}},"ref":{{json .NotificationID}}}`,
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPSMSProvider: %v", err)
+	}
+
+	sms := model.SMS{NotificationID: "n-1", To: "+4915112345678", Text: "привет", Encoding: EncodingUCS2, Segments: 1}
+	if err := provider.SendSMS(context.Background(), sms); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	got := <-requests
+	if got.method != http.MethodPut || got.contentType != "application/vnd.gateway+json" || got.authorization != "Bearer secret" {
+		t.Errorf("request %s %q auth %q", got.method, got.contentType, got.authorization)
+	}
+	want := `{"msisdn":"+4915112345678","message":{"body":"привет","parts":1,"dcs":"ucs2"},"ref":"n-1"}`
+	if got.body != want {
+		t.Errorf("body = %s, want %s", got.body, want)
+	}
+}
+
+func TestHTTPSMSProviderInvalidTemplate(t *testing.T) {
+	if _, err := NewHTTPSMSProvider(HTTPSMSProviderConfig{URL: "http://gateway", BodyTemplate: `{{json .To}`}); err == nil {
+		t.Fatal("unparsable template accepted")
+	}
+	provider, err := NewHTTPSMSProvider(HTTPSMSProviderConfig{URL: "http://gateway", BodyTemplate: `{{.Unknown}}`})
+	if err != nil {
+		t.Fatalf("NewHTTPSMSProvider: %v", err)
+	}
+	// поле, которого нет в model.SMS, обнаруживается только при отправке, и повтор не поможет
+	if err := provider.SendSMS(context.Background(), model.SMS{To: "+15551234567"}); !apperrors.IsPermanent(err) {
+		t.Fatalf("render error: got %v, want a permanent error", err)
+	}
+}
+
+func TestHTTPSMSProviderResponseClassification(t *testing.T) {
+	cases := map[int]struct{ wantErr, permanent bool }{
+		http.StatusOK:                  {},
+		http.StatusBadRequest:          {wantErr: true, permanent: true},
+		http.StatusUnauthorized:        {wantErr: true, permanent: true},
+		http.StatusTooManyRequests:     {wantErr: true},
+		http.StatusServiceUnavailable:  {wantErr: true},
+		http.StatusInternalServerError: {wantErr: true},
+	}
+	for status, want := range cases {
+		server, _ := newTestGateway(t, status)
+		provider, err := NewHTTPSMSProvider(HTTPSMSProviderConfig{URL: server.URL, Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("NewHTTPSMSProvider: %v", err)
+		}
+		err = provider.SendSMS(context.Background(), model.SMS{To: "+15551234567", Text: "hi"})
+		if (err != nil) != want.wantErr || apperrors.IsPermanent(err) != want.permanent {
+			t.Errorf("status %d: got %v, want error=%v permanent=%v", status, err, want.wantErr, want.permanent)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var webhookRateLimited = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "worker_webhook_rate_limited_total",
+		Help: "Incoming-webhook sends that hit or waited out a rate limit",
+	},
+	[]string{"channel", "kind"}, // rejected — ответ 429, waited — отправка ждала сброса лимита
+)
+
+func init() {
+	prometheus.MustRegister(webhookRateLimited)
+}
+
+// webhookPayload builds the request body for a notification; target is the channel
+// identifier of the recipient, empty when the recipient is a webhook URL.
+type webhookPayload func(notification *model.Notification, target string) any
+
+// WebhookSender posts notifications to Slack or Mattermost incoming webhooks. The
+// recipient is either the webhook URL itself or a channel identifier, posted through
+// the default webhook. Rate limits reported by the server pause sends to that webhook.
+//
+// The default webhook is configured by the operator and may be internal (self-hosted
+// Mattermost); webhook URLs from recipients come from API clients and go through a
+// client that enforces the egress policy.
+type WebhookSender struct {
+	channel         string
+	defaultURL      string
+	client          *http.Client // для вебхука по умолчанию
+	recipientClient *http.Client // для вебхуков из получателя, с проверкой адреса
+	payload         webhookPayload
+
+	mu           sync.Mutex
+	blockedUntil map[string]time.Time // webhook → до какого момента сервер просил не слать
+}
+
+// NewSlackSender formats notifications as Block Kit messages.
+func NewSlackSender(defaultURL string, timeout time.Duration, policy egress.Policy) *WebhookSender {
+	return newWebhookSender("slack", defaultURL, timeout, policy, slackPayload)
+}
+
+// NewMattermostSender formats notifications as message attachments.
+func NewMattermostSender(defaultURL string, timeout time.Duration, policy egress.Policy) *WebhookSender {
+	return newWebhookSender("mattermost", defaultURL, timeout, policy, mattermostPayload)
+}
+
+func newWebhookSender(channel, defaultURL string, timeout time.Duration, policy egress.Policy, payload webhookPayload) *WebhookSender {
+	return &WebhookSender{
+		channel:         channel,
+		defaultURL:      defaultURL,
+		client:          &http.Client{Timeout: timeout},
+		recipientClient: egress.NewClient(timeout, policy),
+		payload:         payload,
+		blockedUntil:    make(map[string]time.Time),
+	}
+}
+
+func (s *WebhookSender) Send(ctx context.Context, notification *model.Notification) error {
+	webhook, target, client := s.route(notification.Recipient.String())
+	if webhook == "" {
+		return apperrors.Permanent(fmt.Errorf("no %s webhook configured for channel recipient '%s'", s.channel, target))
+	}
+	if err := s.waitRateLimit(ctx, webhook); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(s.payload(notification, target))
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("couldn't marshal %s payload: %w", s.channel, err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return apperrors.Permanent(fmt.Errorf("invalid %s webhook: %w", s.channel, err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// url.Error содержит адрес вебхука вместе с токеном, в ошибку его не пишем
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		if errors.Is(err, egress.ErrBlocked) {
+			return apperrors.Permanent(fmt.Errorf("%s webhook is not allowed: %w", s.channel, err))
+		}
+		return fmt.Errorf("%s webhook request failed: %w", s.channel, err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	s.rememberRateLimit(webhook, resp)
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		webhookRateLimited.WithLabelValues(s.channel, "rejected").Inc()
+		logging.Ctx(ctx).Warn().Str("retry_after", resp.Header.Get("Retry-After")).Msg("webhook rate limited")
+		return fmt.Errorf("%s webhook rate limited: %s", s.channel, strings.TrimSpace(string(reply)))
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("%s webhook answered %d: %s", s.channel, resp.StatusCode, strings.TrimSpace(string(reply)))
+	default:
+		// неверный или удаленный вебхук, архивный канал: повтор не поможет
+		return apperrors.Permanent(fmt.Errorf("%s webhook answered %d: %s", s.channel, resp.StatusCode, strings.TrimSpace(string(reply))))
+	}
+}
+
+// route splits the recipient into the webhook to post to and the channel to post in,
+// and picks the client for that webhook.
+func (s *WebhookSender) route(recipient string) (webhook, target string, client *http.Client) {
+	if strings.HasPrefix(recipient, "http://") || strings.HasPrefix(recipient, "https://") {
+		return recipient, "", s.recipientClient
+	}
+	return s.defaultURL, recipient, s.client
+}
+
+// waitRateLimit holds the send until the webhook's rate limit resets, or fails it as a
+// transient error if ctx ends first.
+func (s *WebhookSender) waitRateLimit(ctx context.Context, webhook string) error {
+	s.mu.Lock()
+	until := s.blockedUntil[webhook]
+	s.mu.Unlock()
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+
+	webhookRateLimited.WithLabelValues(s.channel, "waited").Inc()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s webhook rate limited for %s: %w", s.channel, wait.Round(time.Second), ctx.Err())
+	}
+}
+
+// rememberRateLimit reads Retry-After (Slack, any 429) and X-Ratelimit-Remaining /
+// X-Ratelimit-Reset (Mattermost).
+func (s *WebhookSender) rememberRateLimit(webhook string, resp *http.Response) {
+	var until time.Time
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		until = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		until = time.Now().Add(time.Second)
+	}
+	if resp.Header.Get("X-Ratelimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			if resetAt := time.Unix(reset, 0); resetAt.After(until) {
+				until = resetAt
+			}
+		}
+	}
+	if until.IsZero() {
+		return
+	}
+
+	s.mu.Lock()
+	if until.After(s.blockedUntil[webhook]) {
+		s.blockedUntil[webhook] = until
+	}
+	s.mu.Unlock()
+}
+
+func slackPayload(notification *model.Notification, target string) any {
+	var blocks []any
+	if notification.Subject != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": notification.Subject},
+		})
+	}
+	blocks = append(blocks,
+		map[string]any{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": notification.Message},
+		},
+		map[string]any{
+			"type": "context",
+			"elements": []any{
+				map[string]any{"type": "mrkdwn", "text": "notification " + notification.ID.String()},
+			},
+		},
+	)
+	payload := map[string]any{
+		"text":   notification.Message, // показывается в уведомлениях клиента
+		"blocks": blocks,
+	}
+	if target != "" {
+		payload["channel"] = target
+	}
+	return payload
+}
+
+func mattermostPayload(notification *model.Notification, target string) any {
+	attachment := map[string]any{
+		"fallback": notification.Message,
+		"text":     notification.Message,
+		"footer":   "notification " + notification.ID.String(),
+	}
+	if notification.Subject != "" {
+		attachment["title"] = notification.Subject
+	}
+	payload := map[string]any{
+		"attachments": []any{attachment},
+	}
+	if target != "" {
+		payload["channel"] = strings.TrimPrefix(target, "#")
+	}
+	return payload
+}
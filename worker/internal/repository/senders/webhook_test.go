@@ -0,0 +1,116 @@
+package senders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/egress"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+func newNotification(channel domain.NotificationChannel, recipient string) *model.Notification {
+	id := types.GenerateUUID()
+	return &model.Notification{
+		ID:        &id,
+		Recipient: domain.RecipientFromString(recipient),
+		Channel:   channel,
+		Message:   "disk is full",
+	}
+}
+
+func TestWebhookRecipientURLToInternalAddressIsBlocked(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer server.Close()
+
+	sender := NewSlackSender("", time.Second, egress.Policy{})
+	err := sender.Send(context.Background(), newNotification(domain.ChannelSlack, server.URL+"/hooks/abc"))
+	if !errors.Is(err, egress.ErrBlocked) || !apperrors.IsPermanent(err) {
+		t.Fatalf("webhook on loopback: got %v, want a permanent ErrBlocked", err)
+	}
+	if hits != 0 {
+		t.Fatal("blocked webhook reached the server")
+	}
+}
+
+func TestWebhookRecipientURLOutsideAllowList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook outside the allow-list reached the server")
+	}))
+	defer server.Close()
+
+	sender := NewMattermostSender("", time.Second, egress.Policy{AllowedHosts: []string{"chat.example.com"}, AllowPrivate: true})
+	err := sender.Send(context.Background(), newNotification(domain.ChannelMattermost, server.URL+"/hooks/abc"))
+	if !errors.Is(err, egress.ErrBlocked) || !apperrors.IsPermanent(err) {
+		t.Fatalf("webhook outside the allow-list: got %v, want a permanent ErrBlocked", err)
+	}
+}
+
+func TestWebhookDefaultURLIsTrusted(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// вебхук по умолчанию задает оператор, он может быть и во внутренней сети
+	sender := NewMattermostSender(server.URL+"/hooks/default", time.Second, egress.Policy{})
+	if err := sender.Send(context.Background(), newNotification(domain.ChannelMattermost, "#ops")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if payload["channel"] != "ops" {
+		t.Errorf("channel = %v, want ops", payload["channel"])
+	}
+}
+
+func TestWebhookResponseClassification(t *testing.T) {
+	cases := []struct {
+		status    int
+		header    map[string]string
+		wantErr   bool
+		permanent bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusInternalServerError, wantErr: true},
+		{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "30"}, wantErr: true},
+		{status: http.StatusNotFound, wantErr: true, permanent: true},
+		{status: http.StatusForbidden, wantErr: true, permanent: true},
+	}
+	for _, tc := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+			}
+			for key, value := range tc.header {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(tc.status)
+		}))
+
+		sender := NewSlackSender("", time.Second, egress.Policy{AllowPrivate: true})
+		err := sender.Send(context.Background(), newNotification(domain.ChannelSlack, server.URL+"/hooks/abc"))
+		server.Close()
+		if (err != nil) != tc.wantErr || apperrors.IsPermanent(err) != tc.permanent {
+			t.Errorf("status %d: got %v, want error=%v permanent=%v", tc.status, err, tc.wantErr, tc.permanent)
+		}
+		if tc.status == http.StatusTooManyRequests {
+			// до сброса лимита следующая отправка ждет и сдается по контексту
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			err := sender.Send(ctx, newNotification(domain.ChannelSlack, server.URL+"/hooks/abc"))
+			cancel()
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("send during rate limit: got %v, want context deadline", err)
+			}
+		}
+	}
+}
@@ -1,36 +1,115 @@
 package service
 
 import (
-	"container/heap"
 	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/health"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/tracing"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/apperrors"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/dispatcher"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/logging"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
 	notificationheap "github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/notificationHeap"
 	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/ports"
-	"github.com/wb-go/wbf/zlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/service")
+
+var (
+	workerHeapDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_heap_depth",
+			Help: "Notifications waiting in the in-memory heap",
+		},
+	)
+	workerOldestDueAge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_oldest_due_age_seconds",
+			Help: "How long the next notification in the heap has been due, 0 if nothing is due",
+		},
+	)
+	workerSendDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_send_duration_seconds",
+			Help:    "Latency of sending a notification through its channel",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"channel"},
+	)
+	workerSendsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_sends_total",
+			Help: "Send attempts by channel and result (success / failure)",
+		},
+		[]string{"channel", "result"},
+	)
+	workerSettledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_deliveries_settled_total",
+			Help: "RabbitMQ deliveries settled after sending by outcome (ack / requeue / reject / duplicate / cancelled)",
+		},
+		[]string{"outcome"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(workerHeapDepth, workerOldestDueAge, workerSendDuration, workerSendsTotal, workerSettledTotal)
+}
+
+// cancelledTTL is how long a cancellation is remembered for copies of the notification
+// that reach the worker after it: still in the queue, requeued after a failure or
+// waiting for a sender.
+const cancelledTTL = time.Hour
+
 type NotificationService struct {
 	receiver         ports.NotificationReceiver
 	channelToSender  ports.NotificationSender //нужно мапу сделать
+	reporter         ports.StatusReporter
 	checkPeriod      time.Duration
 	notificationHeap *notificationheap.NotificationHeap
 	heapMutex        sync.RWMutex
+	heartbeat        *health.Heartbeat
+	dispatcher       *dispatcher.Dispatcher
+	wake             chan struct{} // будит serveHeap, когда в кучу попало более раннее уведомление
+	requeueDelay     time.Duration // пауза перед возвратом сообщения в очередь после временной ошибки
+	requeues         sync.WaitGroup
+	stopping         chan struct{}                    // закрывается при остановке: отложенные возвраты выполняются сразу
+	cancelled        map[model.Cancellation]time.Time // отмены и до каких пор их помнить, под heapMutex
+	nextPrune        time.Time                        // когда удалить из cancelled устаревшие отмены
 }
 
-func NewNotificationService(receiver ports.NotificationReceiver, channelToSender ports.NotificationSender, checkPeriod time.Duration) *NotificationService {
-	notificationHeap := &notificationheap.NotificationHeap{}
-	heap.Init(notificationHeap)
-	return &NotificationService{
+func NewNotificationService(receiver ports.NotificationReceiver, channelToSender ports.NotificationSender, reporter ports.StatusReporter, checkPeriod time.Duration, dispatchCfg config.DispatchConfig, requeueDelay time.Duration) *NotificationService {
+	s := &NotificationService{
 		receiver:         receiver,
 		channelToSender:  channelToSender,
+		reporter:         reporter,
 		checkPeriod:      checkPeriod,
-		notificationHeap: notificationHeap,
-		heapMutex:        sync.RWMutex{}}
+		notificationHeap: notificationheap.NewNotificationHeap(),
+		heapMutex:        sync.RWMutex{},
+		heartbeat:        health.NewHeartbeat(),
+		wake:             make(chan struct{}, 1),
+		stopping:         make(chan struct{}),
+		cancelled:        make(map[model.Cancellation]time.Time),
+		requeueDelay:     requeueDelay}
+	s.dispatcher = dispatcher.New(dispatchCfg, s.dispatch)
+	return s
+}
+
+// wakeUp never blocks: one pending wake-up is enough for serveHeap to re-read the heap.
+func (s *NotificationService) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
 }
 
 func (s *NotificationService) Run(ctx context.Context, rabbitCfg config.RabbitMQConfig) error {
@@ -38,98 +117,337 @@ func (s *NotificationService) Run(ctx context.Context, rabbitCfg config.RabbitMQ
 	if err != nil {
 		return fmt.Errorf("cannot start consumer: %w", err)
 	}
-	zlog.Logger.Info().
-		Str("queue", rabbitCfg.Queue).
+	logging.Ctx(ctx).Info().
+		Strs("channels", rabbitCfg.Channels).
 		Msg("notification service started receiving messages")
 	var object *model.Notification
 
-	go s.serveHeap(ctx)
+	if canceller, ok := s.receiver.(ports.CancellationReceiver); ok {
+		go func() {
+			if err := canceller.ReceiveCancellations(ctx, func(ctx context.Context, cancellation model.Cancellation) { s.Cancel(ctx, cancellation) }); err != nil && ctx.Err() == nil {
+				logging.Ctx(ctx).Error().
+					Err(err).
+					Msg("stopped receiving cancellations")
+			}
+		}()
+	}
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		s.serveHeap(ctx)
+	}()
 
 out:
 	for {
 		select {
 		case <-ctx.Done():
-			zlog.Logger.Info().
+			logging.Ctx(ctx).Info().
 				Msg("notification service: context cancelled, stopping Run loop")
 			break out
 		case object = <-objects:
 			// надо добавить провекру, что такой канал есть в мапе
 			s.heapMutex.Lock()
-			heap.Push(s.notificationHeap, object)
+			if s.isCancelled(object, time.Now()) {
+				s.heapMutex.Unlock()
+				// отмена пришла раньше этой копии: сообщение ждало в очереди или вернулось после ошибки
+				s.settle(ctx, object, "cancelled", model.Acknowledger.Ack)
+				continue
+			}
+			old, replaced := s.notificationHeap.Push(object.ID.String(), object.ScheduledAt, object)
+			root, _, _ := s.notificationHeap.Peek()
+			workerHeapDepth.Set(float64(s.notificationHeap.Len()))
 			s.heapMutex.Unlock()
+			if replaced {
+				// повторная доставка того же уведомления, старое сообщение больше не нужно
+				s.settle(ctx, old, "duplicate", model.Acknowledger.Ack)
+			}
+			if root == object {
+				// новое уведомление раньше всех ожидающих, serveHeap спит дольше, чем нужно
+				s.wakeUp()
+			}
 		}
 	}
+	// отчеты о доставке и подтверждения идут через канал consumer'а, поэтому сначала дожидаемся отправок.
+	// Сообщения, оставшиеся в куче неподтвержденными, брокер вернет в очередь при закрытии канала.
+	<-served
+	if err := s.dispatcher.Drain(); err != nil {
+		logging.Ctx(ctx).Warn().
+			Err(err).
+			Msg("not all in-flight notifications were sent before shutdown")
+	}
+	// после Drain новых возвратов нет; ожидающие возвращаем, пока канал consumer'а открыт
+	close(s.stopping)
+	s.requeues.Wait()
+
 	if err := s.receiver.StopReceiving(); err != nil {
-		zlog.Logger.Error().
+		logging.Ctx(ctx).Error().
 			Err(err).
 			Msg("error while stopping receiver")
 		return err
 	}
 
-	zlog.Logger.Info().
+	logging.Ctx(ctx).Info().
 		Msg("notification service stopped receiving messages")
 
 	return nil
 }
 
-func (s *NotificationService) serveHeap(ctx context.Context) {
-	// step 1. Peek
-	// step 2. Pop
-	// step 3. Send
+// Cancel drops a notification waiting in the heap and acknowledges its delivery, so the
+// broker doesn't hand it out again. The cancellation is remembered for cancelledTTL: a
+// copy received later, or already waiting for a sender, is dropped before it is sent.
+// A copy being sent or held by another worker is not affected; Cancel reports whether
+// the notification was found in the heap.
+func (s *NotificationService) Cancel(ctx context.Context, cancellation model.Cancellation) bool {
+	s.heapMutex.Lock()
+	s.rememberCancelled(cancellation, time.Now())
+	notification, ok := s.notificationHeap.Get(cancellation.ID)
+	if ok && cancellation.Matches(notification) {
+		s.notificationHeap.Remove(cancellation.ID)
+	} else {
+		// в куче копия для получателя, которого отмена не касается
+		ok = false
+	}
+	workerHeapDepth.Set(float64(s.notificationHeap.Len()))
+	s.heapMutex.Unlock()
+	if !ok {
+		return false
+	}
+	logging.Ctx(ctx).Info().
+		Str(logging.FieldNotificationID, cancellation.ID).
+		Time("scheduled_at", notification.ScheduledAt).
+		Msg("notification cancelled, dropped from the heap")
+	s.settle(ctx, notification, "cancelled", model.Acknowledger.Ack)
+	return true
+}
 
-	ticker := time.NewTicker(s.checkPeriod)
-	defer ticker.Stop()
+// rememberCancelled keeps the cancellation for cancelledTTL and forgets the expired
+// ones, must be called with heapMutex held.
+func (s *NotificationService) rememberCancelled(cancellation model.Cancellation, now time.Time) {
+	if now.After(s.nextPrune) {
+		for c, until := range s.cancelled {
+			if now.After(until) {
+				delete(s.cancelled, c)
+			}
+		}
+		s.nextPrune = now.Add(cancelledTTL / 10)
+	}
+	s.cancelled[cancellation] = now.Add(cancelledTTL)
+}
+
+// isCancelled reports whether a remembered cancellation applies to the notification,
+// must be called with heapMutex held.
+func (s *NotificationService) isCancelled(notification *model.Notification, now time.Time) bool {
+	id := notification.ID.String()
+	if until, ok := s.cancelled[model.Cancellation{ID: id}]; ok && now.Before(until) {
+		return true
+	}
+	addressed := model.Cancellation{ID: id, Channel: notification.Channel.String(), Recipient: notification.Recipient.String()}
+	until, ok := s.cancelled[addressed]
+	return ok && now.Before(until)
+}
+
+// CheckLoop fails when the heap serving loop is stuck. The loop wakes at least every
+// checkPeriod, but handing everything due to the dispatcher may block on full queues,
+// so the limit is generous.
+func (s *NotificationService) CheckLoop(ctx context.Context) error {
+	return s.heartbeat.Check(3*s.checkPeriod + time.Minute)(ctx)
+}
+
+// serveHeap hands notifications to the dispatcher as they become due. Then it sleeps until the
+// earliest ScheduledAt (at most checkPeriod, to keep the heartbeat fresh) and is woken
+// early by Run when a notification that is due sooner is pushed.
+func (s *NotificationService) serveHeap(ctx context.Context) {
+	timer := time.NewTimer(s.checkPeriod)
+	defer timer.Stop()
 
 	for {
+		s.heartbeat.Beat()
+		s.heapMutex.Lock()
+		for {
+			notification, ok := s.notificationHeap.PopDue(time.Now())
+			if !ok {
+				break
+			}
+			s.heapMutex.Unlock()
+			// блокируется, пока очередь канала полна: серверу не нужно брать больше, чем успевают отправить
+			if err := s.dispatcher.Submit(ctx, notification); err != nil {
+				logging.Ctx(ctx).Warn().
+					Err(err).
+					Str(logging.FieldNotificationID, notification.ID.String()).
+					Msg("notification not dispatched, worker is stopping")
+				return
+			}
+			s.heapMutex.Lock()
+		}
+
+		now := time.Now()
+		wait := s.checkPeriod
+		if _, at, ok := s.notificationHeap.Peek(); ok && at.Sub(now) < wait {
+			wait = at.Sub(now)
+		}
+		s.observeHeap(now)
+		s.heapMutex.Unlock()
+
+		timer.Reset(wait)
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			now := time.Now()
-			s.heapMutex.Lock()
-
-			for s.notificationHeap.Len() > 0 {
-				notificationToPublish := s.notificationHeap.Peek()
-				if notificationToPublish == nil {
-					break
-				}
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
 
-				if publicationTime := notificationToPublish.ScheduledAt; publicationTime.Add(s.checkPeriod).After(now) {
-					break
-				}
+// dispatch sends one due notification, reports the result and settles its RabbitMQ
+// delivery. It runs in the dispatcher's pool of the notification's channel.
+//
+// A transient failure requeues the delivery and reports nothing yet: the broker hands
+// the message out again and the next attempt decides the status. A permanent failure,
+// or any failure once the message was acknowledged on receipt, is final.
+func (s *NotificationService) dispatch(ctx context.Context, notification *model.Notification) {
+	s.heapMutex.RLock()
+	cancelled := s.isCancelled(notification, time.Now())
+	s.heapMutex.RUnlock()
+	if cancelled {
+		// отмена пришла, пока уведомление ждало отправителя
+		logging.Ctx(ctx).Info().
+			Str(logging.FieldNotificationID, notification.ID.String()).
+			Msg("notification cancelled before sending, dropped")
+		s.settle(ctx, notification, "cancelled", model.Acknowledger.Ack)
+		return
+	}
 
-				notification := heap.Pop(s.notificationHeap).(*model.Notification)
-				s.heapMutex.Unlock()
+	sendCtx := s.traceHeapWait(ctx, notification)
+	sendCtx = logging.WithFields(sendCtx,
+		logging.FieldRequestID, notification.RequestID,
+		logging.FieldNotificationID, notification.ID.String(),
+		logging.FieldChannel, notification.Channel.String(),
+		logging.FieldRecipient, logging.Recipient(notification.Recipient.Val.String()),
+	)
+	sendErr := s.sendNotification(sendCtx, notification)
+	switch {
+	case sendErr == nil:
+		logging.Ctx(sendCtx).Info().Msg("success send notification")
+		s.reportStatus(sendCtx, notification, nil)
+		s.settle(sendCtx, notification, "ack", model.Acknowledger.Ack)
+	case notification.Delivery != nil && !apperrors.IsPermanent(sendErr):
+		logging.Ctx(sendCtx).Warn().
+			Err(sendErr).
+			Dur("requeue_delay", s.requeueDelay).
+			Msg("failed to send notification, returning it to the queue")
+		s.requeueLater(sendCtx, notification)
+	default:
+		logging.Ctx(sendCtx).Error().
+			Err(sendErr).
+			Bool("permanent", apperrors.IsPermanent(sendErr)).
+			Time("scheduled_at", notification.ScheduledAt).
+			Msg("failed to send notification")
+		s.reportStatus(sendCtx, notification, sendErr)
+		reason := sendErr.Error()
+		s.settle(sendCtx, notification, "reject", func(a model.Acknowledger) error { return a.Reject(reason) })
+	}
+}
 
-				if err := s.sendNotification(ctx, notification); err != nil {
-					zlog.Logger.Error().
-						Err(err).
-						Str("id", notification.ID.String()).
-						Time("scheduled_at", notification.ScheduledAt).
-						Msg("failed to send notification")
-				} else {
-					zlog.Logger.Info().Msg("success send notification")
-				}
-				s.heapMutex.Lock()
-			}
-			s.heapMutex.Unlock()
+// requeueLater returns the delivery to the queue after requeueDelay: without the pause
+// the broker would hand the message out again at once and flood a channel that is down.
+// The wait doesn't hold a sender of the pool, so other notifications of the channel keep
+// going; the number of waiting deliveries is bounded by the prefetch. On shutdown the
+// waiting deliveries are requeued at once.
+func (s *NotificationService) requeueLater(ctx context.Context, notification *model.Notification) {
+	s.requeues.Add(1)
+	go func() {
+		defer s.requeues.Done()
+		timer := time.NewTimer(s.requeueDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.stopping:
 		}
+		s.settle(ctx, notification, "requeue", model.Acknowledger.Requeue)
+	}()
+}
+
+// settle acknowledges, requeues or rejects the delivery the notification came from.
+// It does nothing when the message was already acknowledged on receipt.
+func (s *NotificationService) settle(ctx context.Context, notification *model.Notification, outcome string, how func(model.Acknowledger) error) {
+	if notification.Delivery == nil {
+		return
 	}
+	if err := how(notification.Delivery); err != nil {
+		// канал закрыт: брокер сам вернет сообщение в очередь
+		logging.Ctx(ctx).Error().
+			Err(err).
+			Str("outcome", outcome).
+			Str(logging.FieldNotificationID, notification.ID.String()).
+			Msg("failed to settle notification delivery")
+		return
+	}
+	workerSettledTotal.WithLabelValues(outcome).Inc()
+}
 
+// observeHeap updates heap gauges, must be called with heapMutex held.
+func (s *NotificationService) observeHeap(now time.Time) {
+	workerHeapDepth.Set(float64(s.notificationHeap.Len()))
+
+	var dueAge time.Duration
+	if _, at, ok := s.notificationHeap.Peek(); ok && at.Before(now) {
+		dueAge = now.Sub(at)
+	}
+	workerOldestDueAge.Set(dueAge.Seconds())
+}
+
+// traceHeapWait records the time the notification spent in the heap as a span
+// under its receive span and returns the context the send continues in.
+func (s *NotificationService) traceHeapWait(ctx context.Context, notification *model.Notification) context.Context {
+	ctx = tracing.WithTraceParent(ctx, notification.TraceParent)
+	_, span := tracer.Start(ctx, "heap wait",
+		trace.WithTimestamp(notification.ReceivedAt),
+		trace.WithAttributes(
+			attribute.String("notification.id", notification.ID.String()),
+			attribute.String("notification.scheduled_at", notification.ScheduledAt.Format(time.RFC3339)),
+		),
+	)
+	span.End()
+	return ctx
 }
 
 func (s *NotificationService) sendNotification(ctx context.Context, notification *model.Notification) error {
 	// надо добавить провекру, что такой канал есть в мапе
+	channel := notification.Channel.String()
+	ctx, span := tracer.Start(ctx, "send "+channel,
+		trace.WithAttributes(
+			attribute.String("notification.id", notification.ID.String()),
+			attribute.String("notification.channel", channel),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
 	err := s.channelToSender.Send(ctx, notification)
+	workerSendDuration.WithLabelValues(channel).Observe(time.Since(start).Seconds())
 	if err != nil {
-		zlog.Logger.Error().
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		workerSendsTotal.WithLabelValues(channel, "failure").Inc()
+		logging.Ctx(ctx).Error().
 			Err(err).
-			Str("id", notification.ID.String()).
-			Str("channel", string(notification.Channel.String())).
 			Msg("failed to send notification via sender")
 		return fmt.Errorf("do not send %w", err)
 	}
+	workerSendsTotal.WithLabelValues(channel, "success").Inc()
 
 	return nil
 
 }
+
+func (s *NotificationService) reportStatus(ctx context.Context, notification *model.Notification, sendErr error) {
+	if s.reporter == nil {
+		return
+	}
+	if err := s.reporter.Report(ctx, notification, sendErr); err != nil {
+		logging.Ctx(ctx).Error().
+			Err(err).
+			Msg("failed to report delivery status")
+	}
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/domain"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/shared/types"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/config"
+	"github.com/Egor-Pomidor-pdf/DelayedNotifier/worker/internal/model"
+)
+
+// fakeReceiver hands out notifications and cancellations pushed by the test.
+type fakeReceiver struct {
+	objects       chan *model.Notification
+	cancellations chan model.Cancellation
+}
+
+func newFakeReceiver() *fakeReceiver {
+	return &fakeReceiver{objects: make(chan *model.Notification), cancellations: make(chan model.Cancellation)}
+}
+
+func (r *fakeReceiver) StartReceiving(ctx context.Context) (chan *model.Notification, error) {
+	return r.objects, nil
+}
+
+func (r *fakeReceiver) StopReceiving() error { return nil }
+
+func (r *fakeReceiver) ReceiveCancellations(ctx context.Context, cancel func(ctx context.Context, cancellation model.Cancellation)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case cancellation := <-r.cancellations:
+			cancel(ctx, cancellation)
+		}
+	}
+}
+
+// fakeSender counts sends and fails with err.
+type fakeSender struct {
+	sent chan *model.Notification
+	err  error
+}
+
+func (s *fakeSender) Send(ctx context.Context, notification *model.Notification) error {
+	if s.sent != nil {
+		s.sent <- notification
+	}
+	return s.err
+}
+
+// recordingAck reports how the delivery was settled.
+type recordingAck struct {
+	settled chan string
+}
+
+func (a recordingAck) Ack() error                 { a.settled <- "ack"; return nil }
+func (a recordingAck) Requeue() error             { a.settled <- "requeue"; return nil }
+func (a recordingAck) Reject(reason string) error { a.settled <- "reject"; return nil }
+
+func newTestNotification(at time.Time, ack model.Acknowledger) *model.Notification {
+	id := types.GenerateUUID()
+	return &model.Notification{
+		ID:          &id,
+		Recipient:   domain.RecipientFromString("user@example.com"),
+		Channel:     domain.ChannelConsole,
+		Message:     "hello",
+		ScheduledAt: at,
+		Delivery:    ack,
+	}
+}
+
+var testDispatch = config.DispatchConfig{Concurrency: 2, QueueSize: 10, DrainTimeout: time.Second}
+
+func TestCancelDropsNotificationFromHeap(t *testing.T) {
+	receiver := newFakeReceiver()
+	sender := &fakeSender{sent: make(chan *model.Notification, 1)}
+	s := NewNotificationService(receiver, sender, nil, time.Minute, testDispatch, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, config.RabbitMQConfig{}) }()
+
+	ack := recordingAck{settled: make(chan string, 1)}
+	notification := newTestNotification(time.Now().Add(time.Hour), ack)
+	receiver.objects <- notification
+	// Run забирает следующее уведомление, только положив предыдущее в кучу
+	receiver.objects <- newTestNotification(time.Now().Add(time.Hour), nil)
+	receiver.cancellations <- model.Cancellation{ID: notification.ID.String()}
+
+	select {
+	case outcome := <-ack.settled:
+		if outcome != "ack" {
+			t.Fatalf("cancelled delivery settled with %s, want ack", outcome)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled delivery was not settled")
+	}
+	if s.Cancel(ctx, model.Cancellation{ID: notification.ID.String()}) {
+		t.Error("notification still in the heap after cancellation")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	select {
+	case <-sender.sent:
+		t.Fatal("cancelled notification was sent")
+	default:
+	}
+}
+
+func TestCancellationBeforeCopyArrives(t *testing.T) {
+	receiver := newFakeReceiver()
+	sender := &fakeSender{sent: make(chan *model.Notification, 1)}
+	s := NewNotificationService(receiver, sender, nil, time.Minute, testDispatch, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, config.RabbitMQConfig{}) }()
+
+	// копия ждала в очереди, когда пришла отмена
+	ack := recordingAck{settled: make(chan string, 1)}
+	notification := newTestNotification(time.Now(), ack)
+	if s.Cancel(ctx, model.Cancellation{ID: notification.ID.String()}) {
+		t.Fatal("notification found in the heap before it was received")
+	}
+	receiver.objects <- notification
+
+	select {
+	case outcome := <-ack.settled:
+		if outcome != "ack" {
+			t.Fatalf("cancelled delivery settled with %s, want ack", outcome)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled delivery was not settled")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	select {
+	case <-sender.sent:
+		t.Fatal("cancelled notification was sent")
+	default:
+	}
+}
+
+func TestRetractionDropsOnlyCopyForOldRecipient(t *testing.T) {
+	receiver := newFakeReceiver()
+	sender := &fakeSender{sent: make(chan *model.Notification, 2)}
+	s := NewNotificationService(receiver, sender, nil, time.Minute, testDispatch, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, config.RabbitMQConfig{}) }()
+
+	// delayed-notifier сменил получателя: старую копию отзывает, новую публикует под тем же id
+	stale := newTestNotification(time.Now().Add(time.Hour), recordingAck{settled: make(chan string, 1)})
+	fresh := newTestNotification(time.Now(), recordingAck{settled: make(chan string, 1)})
+	fresh.ID = stale.ID
+	fresh.Recipient = domain.RecipientFromString("new@example.com")
+	retraction := model.Cancellation{ID: stale.ID.String(), Channel: stale.Channel.String(), Recipient: stale.Recipient.String()}
+
+	receiver.objects <- fresh
+	if s.Cancel(ctx, retraction) {
+		t.Fatal("retraction of the old recipient dropped the copy for the new one")
+	}
+	select {
+	case sent := <-sender.sent:
+		if sent.Recipient.String() != "new@example.com" {
+			t.Fatalf("sent to %s, want the new recipient", sent.Recipient)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("copy for the new recipient was not sent")
+	}
+
+	// старая копия, пришедшая после отзыва, не отправляется
+	receiver.objects <- stale
+	if outcome := <-stale.Delivery.(recordingAck).settled; outcome != "ack" {
+		t.Fatalf("retracted copy settled with %s, want ack", outcome)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	select {
+	case sent := <-sender.sent:
+		t.Fatalf("retracted copy was sent to %s", sent.Recipient)
+	default:
+	}
+}
+
+func TestRequeueDelayDoesNotHoldSender(t *testing.T) {
+	receiver := newFakeReceiver()
+	sender := &fakeSender{sent: make(chan *model.Notification, 2), err: errors.New("smtp is down")}
+	// один отправитель: пауза перед возвратом в пуле задержала бы второе уведомление на час
+	dispatchCfg := config.DispatchConfig{Concurrency: 1, QueueSize: 10, DrainTimeout: time.Second}
+	s := NewNotificationService(receiver, sender, nil, time.Minute, dispatchCfg, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, config.RabbitMQConfig{}) }()
+
+	ack := recordingAck{settled: make(chan string, 2)}
+	for i := 0; i < 2; i++ {
+		receiver.objects <- newTestNotification(time.Now(), ack)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sender.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %d not sent while an earlier one waits to be requeued", i+1)
+		}
+	}
+	select {
+	case outcome := <-ack.settled:
+		t.Fatalf("delivery settled with %s before the requeue delay", outcome)
+	default:
+	}
+
+	// при остановке ожидающие сообщения возвращаются сразу, пока канал открыт
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if outcome := <-ack.settled; outcome != "requeue" {
+			t.Fatalf("delivery settled with %s, want requeue", outcome)
+		}
+	}
+}
+
+const benchmarkSize = 1_000_000
+
+// countingSender closes done after n sends.
+type countingSender struct {
+	n    int64
+	sent atomic.Int64
+	done chan struct{}
+}
+
+func (s *countingSender) Send(ctx context.Context, notification *model.Notification) error {
+	if s.sent.Add(1) == s.n {
+		close(s.done)
+	}
+	return nil
+}
+
+// fillHeap puts benchmarkSize notifications into the service's heap, spread over
+// spread after start.
+func fillHeap(s *NotificationService, start time.Time, spread time.Duration) {
+	for i := 0; i < benchmarkSize; i++ {
+		notification := newTestNotification(start.Add(spread*time.Duration(i)/benchmarkSize), nil)
+		s.notificationHeap.Push(notification.ID.String(), notification.ScheduledAt, notification)
+	}
+}
+
+// BenchmarkServeHeap1M measures how long serveHeap takes to hand 1M due notifications
+// to the dispatcher and get them sent.
+func BenchmarkServeHeap1M(b *testing.B) {
+	dispatchCfg := config.DispatchConfig{Concurrency: 8, QueueSize: 1000, DrainTimeout: time.Minute}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		sender := &countingSender{n: benchmarkSize, done: make(chan struct{})}
+		s := NewNotificationService(newFakeReceiver(), sender, nil, time.Minute, dispatchCfg, 0)
+		fillHeap(s, time.Now().Add(-time.Minute), time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+		b.StartTimer()
+
+		go s.serveHeap(ctx)
+		<-sender.done
+
+		b.StopTimer()
+		cancel()
+		if err := s.dispatcher.Drain(); err != nil {
+			b.Fatalf("Drain: %v", err)
+		}
+	}
+}
+
+// BenchmarkFireLatencyAt1M measures the time from receiving a notification that is
+// already due to sending it while 1M notifications wait in the heap: serveHeap must be
+// woken by the push instead of sleeping until the earliest of them.
+func BenchmarkFireLatencyAt1M(b *testing.B) {
+	receiver := newFakeReceiver()
+	sender := &fakeSender{sent: make(chan *model.Notification, 1)}
+	s := NewNotificationService(receiver, sender, nil, time.Hour, testDispatch, 0)
+	fillHeap(s, time.Now().Add(time.Hour), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, config.RabbitMQConfig{}) }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		receiver.objects <- newTestNotification(time.Now(), nil)
+		<-sender.sent
+	}
+	b.StopTimer()
+
+	cancel()
+	if err := <-done; err != nil {
+		b.Fatalf("Run: %v", err)
+	}
+}